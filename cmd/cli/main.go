@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/accountmap"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/counterparty"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/duplicatecleaner"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/printer"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/rules"
+)
+
+const (
+	cliChatID = int64(0)
+	cliFileID = "cli"
+
+	formatText = "text"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// environment holds the services configured with environment variables.
+type environment struct {
+	storage       repo.Storage
+	firefly       processor.Firefly
+	accountMapper *accountmap.Mapper
+}
+
+func main() {
+	if err := run(context.Background(), newEnvironment, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage(out io.Writer) {
+	_, _ = fmt.Fprintln(out, `usage: importer import --source <source> [--commit] [--text <message>] [--date <rfc3339>] [--skip-duplicates] [--skip-income-error] [--format text|json|csv] [file|-]
+       importer reconcile --source <source> [--tolerance <days>] [--format text|json|csv] [file|-]`)
+}
+
+func run(
+	ctx context.Context,
+	newEnv func() (*environment, error),
+	args []string,
+	stdin io.Reader,
+	stdout io.Writer,
+) error {
+	if len(args) == 0 {
+		usage(stdout)
+		return errors.New("command is required")
+	}
+
+	switch args[0] {
+	case "import":
+		return runImport(ctx, newEnv, args[1:], stdin, stdout)
+	case "reconcile":
+		return runReconcile(ctx, newEnv, args[1:], stdin, stdout)
+	default:
+		usage(stdout)
+		return errors.Newf("unknown command %s", args[0])
+	}
+}
+
+// runImport prints the dry run and commits when requested. It fails when the command fails or any transaction
+// can not be imported, nothing is committed when the dry run has failures.
+func runImport(
+	ctx context.Context,
+	newEnv func() (*environment, error),
+	args []string,
+	stdin io.Reader,
+	stdout io.Writer,
+) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+
+	source := fs.String("source", "", "transaction source (privatbank, paribas, zen, mono, revolut, camt, mt940, ofx or a CSV_PROFILES_FILE source)")
+	commit := fs.Bool("commit", false, "commit transactions to firefly, otherwise only dry run is printed")
+	text := fs.String("text", "", "privatbank notification text, used instead of a file")
+	date := fs.String("date", "", "message date in RFC3339, defaults to now")
+	skipDuplicates := fs.Bool("skip-duplicates", false, "hide duplicates from the output")
+	skipIncomeError := fs.Bool("skip-income-error", false, "hide and acknowledge unsupported operations")
+	format := fs.String("format", formatText, "output format (text, json or csv)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *source == "" {
+		return errors.New("--source is required")
+	}
+
+	messageDate := time.Now().UTC()
+	if *date != "" {
+		parsed, err := time.Parse(time.RFC3339, *date)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse date %s", *date)
+		}
+
+		messageDate = parsed
+	}
+
+	outputPrinter, err := newPrinter(*format)
+	if err != nil {
+		return err
+	}
+
+	env, err := newEnv()
+	if err != nil {
+		return err
+	}
+
+	notifier := newLocalNotifier(stdout)
+	txSource := database.TransactionSource(*source)
+
+	processorSvc, err := newProcessor(env, notifier, txSource, outputPrinter)
+	if err != nil {
+		return err
+	}
+
+	message := processor.Message{
+		ID:                cliFileID,
+		Date:              messageDate,
+		OriginalDate:      messageDate,
+		ChatID:            cliChatID,
+		TransactionSource: txSource,
+		Configuration: common.ChatConfiguration{
+			Source:          txSource,
+			SkipDuplicates:  *skipDuplicates,
+			SkipIncomeError: *skipIncomeError,
+		},
+	}
+
+	if err = addStatement(ctx, processorSvc, notifier, message, *text, fs.Arg(0), stdin); err != nil {
+		return err
+	}
+
+	message.FileID = ""
+
+	transactions, errArr, err := processorSvc.PendingTransactions(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	if err = notifier.SendMessage(ctx, cliChatID, outputPrinter.Dry(ctx, transactions, errArr)); err != nil {
+		return err
+	}
+
+	if err = importFailures(processorSvc, message, transactions, errArr); err != nil || !*commit {
+		return err
+	}
+
+	transactions, errArr, err = processorSvc.CommitPending(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	if err = notifier.SendMessage(ctx, cliChatID,
+		processorSvc.CommitSummary(ctx, message.Configuration, transactions, errArr)); err != nil {
+		return err
+	}
+
+	return importFailures(processorSvc, message, transactions, errArr)
+}
+
+// importFailures returns an error when messages failed to parse or transactions were not committed
+// and are not acknowledged, e.g. mapping errors or probable duplicates.
+func importFailures(
+	processorSvc *processor.Processor,
+	message processor.Message,
+	transactions []*firefly.MappedTransaction,
+	errArr []error,
+) error {
+	failed := lo.CountBy(transactions, func(tx *firefly.MappedTransaction) bool {
+		return tx.Error != nil && !processorSvc.IsAcknowledged(tx, message.Configuration)
+	})
+
+	if failed == 0 && len(errArr) == 0 {
+		return nil
+	}
+
+	return errors.Newf("%d transactions and %d messages can not be imported", failed, len(errArr))
+}
+
+func runReconcile(
+	ctx context.Context,
+	newEnv func() (*environment, error),
+	args []string,
+	stdin io.Reader,
+	stdout io.Writer,
+) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+
+	source := fs.String("source", "", "transaction source of the statement")
+	tolerance := fs.Int("tolerance", -1, "date tolerance in days, defaults to 3")
+	format := fs.String("format", formatText, "output format (text, json or csv)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *source == "" {
+		return errors.New("--source is required")
+	}
+
+	outputPrinter, err := newPrinter(*format)
+	if err != nil {
+		return err
+	}
+
+	env, err := newEnv()
+	if err != nil {
+		return err
+	}
+
+	notifier := newLocalNotifier(stdout)
+	txSource := database.TransactionSource(*source)
+
+	processorSvc, err := newProcessor(env, notifier, txSource, outputPrinter)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	message := processor.Message{
+		ID:                cliFileID,
+		Date:              now,
+		OriginalDate:      now,
+		ChatID:            cliChatID,
+		TransactionSource: txSource,
+		Configuration: common.ChatConfiguration{
+			Source: txSource,
+		},
+	}
+
+	if err = addStatement(ctx, processorSvc, notifier, message, "", fs.Arg(0), stdin); err != nil {
+		return err
+	}
+
+	command := "/reconcile"
+	if *tolerance >= 0 {
+		command = fmt.Sprintf("%s %d", command, *tolerance)
+	}
+
+	message.Content = command // same path as a telegram command
+
+	return processorSvc.ProcessMessage(ctx, message)
+}
+
+// addStatement stores the text or the statement file as a pending message.
+func addStatement(
+	ctx context.Context,
+	processorSvc *processor.Processor,
+	notifier *localNotifier,
+	message processor.Message,
+	text string,
+	path string,
+	stdin io.Reader,
+) error {
+	if text != "" {
+		message.Content = text
+	} else {
+		data, err := readInput(path, stdin)
+		if err != nil {
+			return err
+		}
+
+		if message.TransactionSource == database.PrivatBank { // notifications are plain text
+			message.Content = string(data)
+		} else {
+			notifier.AddFile(cliFileID, data)
+			message.FileID = cliFileID
+		}
+	}
+
+	return processorSvc.AddMessage(ctx, message)
+}
+
+func readInput(path string, stdin io.Reader) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(stdin)
+	}
+
+	return os.ReadFile(path)
+}
+
+func newPrinter(format string) (processor.Printer, error) {
+	switch format {
+	case formatText:
+		return printer.NewPrinter(), nil
+	case formatJSON:
+		return printer.NewJSON(), nil
+	case formatCSV:
+		return printer.NewCSV(), nil
+	default:
+		return nil, errors.Newf("unknown format %s", format)
+	}
+}
+
+// newEnvironment creates the firefly client and the storage, pending messages and commit batches are kept
+// in the storage so /undo and /history of the server see cli imports.
+func newEnvironment() (*environment, error) {
+	var fireflyAdditionalHeaders map[string]string
+	if v, ok := os.LookupEnv("FIREFLY_ADDITIONAL_HEADERS"); ok {
+		if err := json.Unmarshal([]byte(v), &fireflyAdditionalHeaders); err != nil {
+			return nil, err
+		}
+	}
+
+	fireflyClient := firefly.NewFirefly(
+		os.Getenv("FIREFLY_TOKEN"),
+		os.Getenv("FIREFLY_URL"),
+		req.DefaultClient(),
+		fireflyAdditionalHeaders,
+	)
+
+	storage, err := repo.NewStorageFromEnv(repo.StorageMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	if path, ok := os.LookupEnv("COUNTERPARTY_ALIASES_FILE"); ok && path != "" {
+		normalizer, normalizerErr := counterparty.LoadFile(path)
+		if normalizerErr != nil {
+			return nil, normalizerErr
+		}
+
+		fireflyClient.SetCounterpartyNormalizer(normalizer)
+	}
+
+	var staticMappings []*database.AccountMapping
+	if path, ok := os.LookupEnv("ACCOUNT_MAPPING_FILE"); ok && path != "" {
+		staticMappings, err = accountmap.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accountMapper := accountmap.NewMapper(storage, staticMappings)
+	fireflyClient.SetAccountMapper(accountMapper)
+
+	return &environment{
+		storage:       storage,
+		firefly:       fireflyClient,
+		accountMapper: accountMapper,
+	}, nil
+}
+
+func newProcessor(
+	env *environment,
+	notifier processor.NotificationSvc,
+	source database.TransactionSource,
+	outputPrinter processor.Printer,
+) (*processor.Processor, error) {
+	cfg := &processor.Config{
+		Repo:             env.storage,
+		Parsers:          map[database.TransactionSource]processor.Parser{},
+		NotificationSvc:  notifier,
+		FireflySvc:       env.firefly,
+		DuplicateCleaner: duplicatecleaner.NewDuplicateCleaner(env.storage),
+		Printer:          outputPrinter,
+	}
+
+	if env.accountMapper != nil {
+		cfg.AccountMapper = env.accountMapper
+	}
+
+	for _, p := range []processor.Parser{
+		parser.NewParser(),
+		parser.NewParibas(),
+		parser.NewZen(),
+		parser.NewMono(),
+		parser.NewRevolut(),
+		parser.NewCamt(),
+		parser.NewMT940(),
+		parser.NewOfx(),
+	} {
+		cfg.Parsers[p.Type()] = p
+	}
+
+	if path, ok := os.LookupEnv("CSV_PROFILES_FILE"); ok && path != "" {
+		profileParsers, profileErr := parser.LoadCsvProfiles(path)
+		if profileErr != nil {
+			return nil, profileErr
+		}
+
+		for _, p := range profileParsers {
+			if _, exists := cfg.Parsers[p.Type()]; exists {
+				return nil, errors.Newf("parser for source %v is already registered", p.Type())
+			}
+
+			cfg.Parsers[p.Type()] = p
+		}
+	}
+
+	if _, ok := cfg.Parsers[source]; !ok {
+		return nil, errors.Newf("parser for source %v not found", source)
+	}
+
+	if path, ok := os.LookupEnv("RULES_FILE"); ok && path != "" {
+		rulesEngine, rulesErr := rules.LoadFile(path)
+		if rulesErr != nil {
+			return nil, rulesErr
+		}
+
+		cfg.Rules = rulesEngine
+	}
+
+	if val, ok := os.LookupEnv("FUZZY_DUPLICATE_DAYS"); ok && val != "" {
+		days, daysErr := strconv.Atoi(val)
+		if daysErr != nil {
+			return nil, errors.Wrapf(daysErr, "failed to parse FUZZY_DUPLICATE_DAYS")
+		}
+
+		cfg.DuplicateWindow = time.Duration(days) * 24 * time.Hour
+	}
+
+	return processor.NewProcessor(cfg), nil
+}
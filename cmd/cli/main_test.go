@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+)
+
+const revolutStatement = "Type,Product,Started Date,Completed Date,Description,Amount,Fee,Currency,State,Balance\n" +
+	"TRANSFER,Current,2024-09-02 10:31:35,,To XXYYZZ,-21.31,0,USD,COMPLETED,\n"
+
+type fakeFirefly struct {
+	created []*firefly.Transaction
+}
+
+func (f *fakeFirefly) ListAccounts(_ context.Context) ([]*firefly.Account, error) {
+	return nil, nil
+}
+
+func (f *fakeFirefly) ListCategories(_ context.Context) ([]*firefly.Category, error) {
+	return nil, nil
+}
+
+func (f *fakeFirefly) MapTransactions(
+	_ context.Context,
+	transactions []*database.Transaction,
+) ([]*firefly.MappedTransaction, error) {
+	var mapped []*firefly.MappedTransaction
+
+	for _, tx := range transactions {
+		mapped = append(mapped, &firefly.MappedTransaction{
+			Original:    tx,
+			Transaction: &firefly.Transaction{Description: tx.Description},
+			Error:       tx.ParsingError,
+		})
+	}
+
+	return mapped, nil
+}
+
+func (f *fakeFirefly) CreateTransactions(
+	_ context.Context,
+	tx *firefly.Transaction,
+	_ bool,
+) (*firefly.TransactionGroup, error) {
+	f.created = append(f.created, tx)
+
+	return &firefly.TransactionGroup{Id: "42"}, nil
+}
+
+func (f *fakeFirefly) DeleteTransaction(_ context.Context, _ string) error {
+	return nil
+}
+
+func (f *fakeFirefly) CheckBalances(
+	_ context.Context,
+	_ []*database.Balance,
+) ([]*firefly.BalanceCheck, error) {
+	return nil, nil
+}
+
+func (f *fakeFirefly) ListAccountTransactions(
+	_ context.Context,
+	_ string,
+	_ time.Time,
+	_ time.Time,
+) ([]*firefly.TransactionGroup, error) {
+	return nil, nil
+}
+
+func newTestEnvironment() (*environment, *fakeFirefly) {
+	fireflySvc := &fakeFirefly{}
+	env := &environment{
+		storage: repo.NewMemory(),
+		firefly: fireflySvc,
+	}
+
+	return env, fireflySvc
+}
+
+func runTestImport(env *environment, args []string, input string) (string, error) {
+	var out bytes.Buffer
+
+	err := run(context.Background(), func() (*environment, error) {
+		return env, nil
+	}, append([]string{"import"}, args...), strings.NewReader(input), &out)
+
+	return out.String(), err
+}
+
+func TestImportDryRun(t *testing.T) {
+	env, fireflySvc := newTestEnvironment()
+
+	out, err := runTestImport(env, []string{"--source", "revolut"}, revolutStatement)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "To XXYYZZ")
+	assert.Empty(t, fireflySvc.created)
+
+	pending, err := env.storage.GetLatestMessages(context.Background(), database.Revolut)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
+
+func TestImportCommit(t *testing.T) {
+	env, fireflySvc := newTestEnvironment()
+
+	_, err := runTestImport(env, []string{"--source", "revolut", "--commit"}, revolutStatement)
+	assert.NoError(t, err)
+
+	if assert.Len(t, fireflySvc.created, 1) {
+		assert.Equal(t, "TRANSFER.To XXYYZZ", fireflySvc.created[0].Description)
+	}
+
+	pending, err := env.storage.GetLatestMessages(context.Background(), database.Revolut)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	batches, err := env.storage.GetCommitBatches(context.Background(), database.Revolut, 10)
+	assert.NoError(t, err)
+	assert.Len(t, batches, 1)
+}
+
+func TestImportParseFailure(t *testing.T) {
+	env, fireflySvc := newTestEnvironment()
+
+	out, err := runTestImport(env, []string{"--source", "revolut", "--commit"}, "not,a,statement\n1,2,3\n")
+	assert.ErrorContains(t, err, "1 transactions and 0 messages can not be imported")
+	assert.Contains(t, out, "expected len > 8")
+	assert.Empty(t, fireflySvc.created)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+)
+
+// localNotifier replaces telegram for the cli: replies go to the output and files are served from memory.
+type localNotifier struct {
+	out   io.Writer
+	files map[string][]byte
+}
+
+func newLocalNotifier(out io.Writer) *localNotifier {
+	return &localNotifier{
+		out:   out,
+		files: map[string][]byte{},
+	}
+}
+
+func (l *localNotifier) AddFile(fileID string, data []byte) {
+	l.files[fileID] = data
+}
+
+func (l *localNotifier) React(
+	_ context.Context,
+	_ int64,
+	_ int64,
+	_ string,
+) error {
+	return nil
+}
+
+func (l *localNotifier) SendMessage(
+	_ context.Context,
+	_ int64,
+	text string,
+) error {
+	_, err := fmt.Fprintln(l.out, text)
+
+	return err
+}
+
+// SendKeyboard prints the text only, there is nobody to press the buttons.
+func (l *localNotifier) SendKeyboard(
+	ctx context.Context,
+	chatID int64,
+	text string,
+	_ notifications.Keyboard,
+) error {
+	return l.SendMessage(ctx, chatID, text)
+}
+
+func (l *localNotifier) EditMessage(
+	ctx context.Context,
+	chatID int64,
+	_ int64,
+	text string,
+	_ notifications.Keyboard,
+) error {
+	return l.SendMessage(ctx, chatID, text)
+}
+
+func (l *localNotifier) AnswerCallback(
+	_ context.Context,
+	_ string,
+	_ string,
+) error {
+	return nil
+}
+
+// SendDocument prints the caption followed by the document content.
+func (l *localNotifier) SendDocument(
+	ctx context.Context,
+	chatID int64,
+	document notifications.Document,
+	caption string,
+) error {
+	if err := l.SendMessage(ctx, chatID, caption); err != nil {
+		return err
+	}
+
+	_, err := l.out.Write(document.Data)
+
+	return err
+}
+
+func (l *localNotifier) GetFile(_ context.Context, fileID string) ([]byte, error) {
+	data, ok := l.files[fileID]
+	if !ok {
+		return nil, errors.Newf("file %s not found", fileID)
+	}
+
+	return data, nil
+}
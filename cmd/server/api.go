@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+)
+
+const (
+	apiMaxMemory     = 32 << 20
+	apiMaxUploadSize = 64 << 20 // whole request body, larger uploads are rejected
+)
+
+// ApiHandler exposes the processor as a json rest api under /api/v1/sources/{source}.
+type ApiHandler struct {
+	svc     ImportService
+	chatMap map[string]common.ChatConfiguration
+}
+
+func NewApiHandler(
+	svc ImportService,
+	chatMap map[string]common.ChatConfiguration,
+) *ApiHandler {
+	return &ApiHandler{
+		svc:     svc,
+		chatMap: chatMap,
+	}
+}
+
+// ApiTransaction is a mapped transaction with the error as text.
+type ApiTransaction struct {
+	*firefly.MappedTransaction
+	Error string `json:"Error,omitempty"`
+}
+
+type ApiTransactionsResponse struct {
+	Transactions []ApiTransaction `json:"transactions"`
+	Errors       []string         `json:"errors"`
+}
+
+type ApiPendingResponse struct {
+	Messages []*database.Message `json:"messages"`
+}
+
+type ApiUploadResponse struct {
+	Messages int `json:"messages"`
+}
+
+type ApiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *ApiHandler) Register(r *mux.Router) {
+	sub := r.PathPrefix("/api/v1/sources/{source}").Subrouter()
+	sub.Use(h.middleware)
+
+	sub.HandleFunc("/files", h.upload).Methods(http.MethodPost)
+	sub.HandleFunc("/pending", h.pending).Methods(http.MethodGet)
+	sub.HandleFunc("/pending", h.clear).Methods(http.MethodDelete)
+	sub.HandleFunc("/dry", h.dry).Methods(http.MethodGet)
+	sub.HandleFunc("/commit", h.commit).Methods(http.MethodPost)
+	sub.HandleFunc("/duplicates", h.listDuplicates).Methods(http.MethodGet)
+	sub.HandleFunc("/errors", h.listErrors).Methods(http.MethodGet)
+}
+
+func (h *ApiHandler) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validAPIKey(r) {
+			writeJSON(w, http.StatusUnauthorized, ApiErrorResponse{Error: "unauthorized"})
+			return
+		}
+
+		if !h.svc.SupportsSource(h.source(r)) {
+			writeJSON(w, http.StatusNotFound, ApiErrorResponse{Error: "unknown source"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *ApiHandler) source(r *http.Request) database.TransactionSource {
+	return database.TransactionSource(mux.Vars(r)["source"])
+}
+
+// message builds the processor message of the source with the configuration of its first chat,
+// sources which are not used by any chat get the default configuration.
+func (h *ApiHandler) message(r *http.Request) processor.Message {
+	source := h.source(r)
+	now := time.Now().UTC()
+
+	message := processor.Message{
+		Date:              now,
+		OriginalDate:      now,
+		TransactionSource: source,
+	}
+
+	chatIDs := lo.Keys(h.chatMap)
+	sort.Strings(chatIDs)
+
+	for _, chatID := range chatIDs {
+		if cfg := h.chatMap[chatID]; cfg.Source == source {
+			message.Configuration = cfg
+			break
+		}
+	}
+
+	return message
+}
+
+// upload stores every "file" part as a statement and every "text" value as a text message.
+func (h *ApiHandler) upload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, apiMaxUploadSize)
+
+	if err := r.ParseMultipartForm(apiMaxMemory); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, ApiErrorResponse{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusBadRequest, ApiErrorResponse{Error: err.Error()})
+		return
+	}
+
+	files := r.MultipartForm.File["file"]
+	texts := r.MultipartForm.Value["text"]
+
+	if len(files) == 0 && len(texts) == 0 {
+		writeJSON(w, http.StatusBadRequest, ApiErrorResponse{Error: "file or text is required"})
+		return
+	}
+
+	var messages []database.Message // every part is split before anything is stored
+
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ApiErrorResponse{Error: err.Error()})
+			return
+		}
+
+		data, err := io.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ApiErrorResponse{Error: err.Error()})
+			return
+		}
+
+		message := h.message(r)
+		message.FileID = header.Filename
+
+		fileMessages, err := h.svc.NewMessages(r.Context(), message, data)
+		if err != nil {
+			writeJSON(w, http.StatusUnprocessableEntity,
+				ApiErrorResponse{Error: errors.Wrapf(err, "file %s", header.Filename).Error()})
+			return
+		}
+
+		messages = append(messages, fileMessages...)
+	}
+
+	for _, text := range texts {
+		message := h.message(r)
+		message.Content = text
+
+		textMessages, err := h.svc.NewMessages(r.Context(), message, nil)
+		if err != nil {
+			writeJSON(w, http.StatusUnprocessableEntity, ApiErrorResponse{Error: err.Error()})
+			return
+		}
+
+		messages = append(messages, textMessages...)
+	}
+
+	if err := h.svc.StoreMessages(r.Context(), messages); err != nil {
+		writeJSON(w, http.StatusInternalServerError, ApiErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ApiUploadResponse{Messages: len(messages)})
+}
+
+func (h *ApiHandler) pending(w http.ResponseWriter, r *http.Request) {
+	messages, err := h.svc.PendingMessages(r.Context(), h.source(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ApiErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ApiPendingResponse{Messages: messages})
+}
+
+func (h *ApiHandler) clear(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.Clear(r.Context(), h.message(r)); err != nil {
+		writeJSON(w, http.StatusInternalServerError, ApiErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ApiHandler) dry(w http.ResponseWriter, r *http.Request) {
+	h.transactions(w, r, func(_ *firefly.MappedTransaction) bool {
+		return true
+	})
+}
+
+func (h *ApiHandler) listDuplicates(w http.ResponseWriter, r *http.Request) {
+	h.transactions(w, r, func(tx *firefly.MappedTransaction) bool {
+		return errors.Is(tx.Error, common.ErrDuplicate) || errors.Is(tx.Error, common.ErrProbableDuplicate)
+	})
+}
+
+func (h *ApiHandler) listErrors(w http.ResponseWriter, r *http.Request) {
+	h.transactions(w, r, func(tx *firefly.MappedTransaction) bool {
+		return tx.Error != nil && !errors.Is(tx.Error, common.ErrDuplicate) &&
+			!errors.Is(tx.Error, common.ErrProbableDuplicate) && !errors.Is(tx.Error, common.ErrSkipped)
+	})
+}
+
+func (h *ApiHandler) transactions(
+	w http.ResponseWriter,
+	r *http.Request,
+	filter func(tx *firefly.MappedTransaction) bool,
+) {
+	message := h.message(r)
+	message.Configuration.SkipDuplicates = false // filtered by the endpoint
+
+	transactions, errArr, err := h.svc.PendingTransactions(r.Context(), message)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ApiErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newApiTransactionsResponse(lo.Filter(transactions,
+		func(tx *firefly.MappedTransaction, _ int) bool {
+			return filter(tx)
+		}), errArr))
+}
+
+func (h *ApiHandler) commit(w http.ResponseWriter, r *http.Request) {
+	transactions, errArr, err := h.svc.CommitPending(r.Context(), h.message(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ApiErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newApiTransactionsResponse(transactions, errArr))
+}
+
+func newApiTransactionsResponse(
+	transactions []*firefly.MappedTransaction,
+	errArr []error,
+) ApiTransactionsResponse {
+	resp := ApiTransactionsResponse{
+		Transactions: []ApiTransaction{},
+		Errors:       []string{},
+	}
+
+	for _, tx := range transactions {
+		apiTx := ApiTransaction{MappedTransaction: tx}
+		if tx.Error != nil {
+			apiTx.Error = tx.Error.Error()
+		}
+
+		resp.Transactions = append(resp.Transactions, apiTx)
+	}
+
+	for _, err := range errArr {
+		resp.Errors = append(resp.Errors, err.Error())
+	}
+
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
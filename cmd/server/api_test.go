@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+)
+
+type fakeImportService struct {
+	prepared     []processor.Message
+	preparedData [][]byte
+	stored       []database.Message
+	transactions []*firefly.MappedTransaction
+	errArr       []error
+	committed    []processor.Message
+}
+
+func (f *fakeImportService) SupportsSource(source database.TransactionSource) bool {
+	return source == database.Revolut
+}
+
+func (f *fakeImportService) NewMessages(
+	_ context.Context,
+	message processor.Message,
+	fileData []byte,
+) ([]database.Message, error) {
+	if message.FileID == "broken.csv" {
+		return nil, errors.New("unexpected header")
+	}
+
+	f.prepared = append(f.prepared, message)
+	f.preparedData = append(f.preparedData, fileData)
+
+	return []database.Message{{ID: message.FileID, Content: message.Content}}, nil
+}
+
+func (f *fakeImportService) StoreMessages(_ context.Context, messages []database.Message) error {
+	f.stored = append(f.stored, messages...)
+
+	return nil
+}
+
+func (f *fakeImportService) PendingMessages(
+	_ context.Context,
+	_ database.TransactionSource,
+) ([]*database.Message, error) {
+	return []*database.Message{{ID: "1"}}, nil
+}
+
+func (f *fakeImportService) PendingTransactions(
+	_ context.Context,
+	_ processor.Message,
+) ([]*firefly.MappedTransaction, []error, error) {
+	return f.transactions, f.errArr, nil
+}
+
+func (f *fakeImportService) CommitPending(
+	_ context.Context,
+	message processor.Message,
+) ([]*firefly.MappedTransaction, []error, error) {
+	f.committed = append(f.committed, message)
+
+	return f.transactions, f.errArr, nil
+}
+
+func (f *fakeImportService) Clear(_ context.Context, _ processor.Message) error {
+	return nil
+}
+
+func withAPIKey(t *testing.T, key string) {
+	prev := apiKey
+	apiKey = key
+
+	t.Cleanup(func() {
+		apiKey = prev
+	})
+}
+
+func newApiRouter(svc *fakeImportService) *mux.Router {
+	r := mux.NewRouter()
+	NewApiHandler(svc, map[string]common.ChatConfiguration{
+		"2": {Source: database.Revolut, SkipDuplicates: true},
+		"1": {Source: database.Paribas},
+	}).Register(r)
+
+	return r
+}
+
+func serveApi(r http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestApiAuth(t *testing.T) {
+	withAPIKey(t, "secret")
+	r := newApiRouter(&fakeImportService{})
+
+	for _, target := range []string{
+		"/api/v1/sources/revolut/pending",
+		"/api/v1/sources/revolut/pending?api_key=wrong",
+	} {
+		rec := serveApi(r, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, target)
+	}
+
+	rec := serveApi(r, httptest.NewRequest(http.MethodGet, "/api/v1/sources/revolut/pending?api_key=secret", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp ApiPendingResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Messages, 1)
+}
+
+func TestApiEmptyKey(t *testing.T) {
+	withAPIKey(t, "")
+	r := newApiRouter(&fakeImportService{})
+
+	rec := serveApi(r, httptest.NewRequest(http.MethodGet, "/api/v1/sources/revolut/pending?api_key=", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestApiUnknownSource(t *testing.T) {
+	withAPIKey(t, "secret")
+	r := newApiRouter(&fakeImportService{})
+
+	rec := serveApi(r, httptest.NewRequest(http.MethodGet, "/api/v1/sources/unknown/dry?api_key=secret", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"unknown source"}`, rec.Body.String())
+}
+
+func TestApiUpload(t *testing.T) {
+	withAPIKey(t, "secret")
+	svc := &fakeImportService{}
+	r := newApiRouter(svc)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	part, err := form.CreateFormFile("file", "statement.csv")
+	assert.NoError(t, err)
+	_, _ = part.Write([]byte("a,b,c"))
+	assert.NoError(t, form.WriteField("text", "some text"))
+	assert.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sources/revolut/files?api_key=secret", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	rec := serveApi(r, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"messages":2}`, rec.Body.String())
+
+	assert.Len(t, svc.stored, 2)
+
+	if assert.Len(t, svc.prepared, 2) {
+		assert.Equal(t, "statement.csv", svc.prepared[0].FileID)
+		assert.Equal(t, "a,b,c", string(svc.preparedData[0]))
+		assert.Equal(t, database.Revolut, svc.prepared[0].Configuration.Source)
+		assert.Equal(t, "some text", svc.prepared[1].Content)
+		assert.Nil(t, svc.preparedData[1])
+	}
+}
+
+func TestApiUploadInvalidFile(t *testing.T) {
+	withAPIKey(t, "secret")
+	svc := &fakeImportService{}
+	r := newApiRouter(svc)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	for _, name := range []string{"statement.csv", "broken.csv"} {
+		part, err := form.CreateFormFile("file", name)
+		assert.NoError(t, err)
+		_, _ = part.Write([]byte("a,b,c"))
+	}
+
+	assert.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sources/revolut/files?api_key=secret", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	rec := serveApi(r, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.JSONEq(t, `{"error":"file broken.csv: unexpected header"}`, rec.Body.String())
+	assert.Len(t, svc.prepared, 1)
+	assert.Empty(t, svc.stored) // the valid file is not stored either
+}
+
+func TestApiUploadEmpty(t *testing.T) {
+	withAPIKey(t, "secret")
+	r := newApiRouter(&fakeImportService{})
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	assert.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sources/revolut/files?api_key=secret", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	rec := serveApi(r, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error":"file or text is required"}`, rec.Body.String())
+}
+
+func TestApiUploadTooLarge(t *testing.T) {
+	withAPIKey(t, "secret")
+	svc := &fakeImportService{}
+	r := newApiRouter(svc)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	part, err := form.CreateFormFile("file", "statement.csv")
+	assert.NoError(t, err)
+	_, _ = part.Write(bytes.Repeat([]byte("a"), apiMaxUploadSize+1))
+	assert.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sources/revolut/files?api_key=secret", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	rec := serveApi(r, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Empty(t, svc.stored)
+}
+
+func TestApiFilters(t *testing.T) {
+	withAPIKey(t, "secret")
+	svc := &fakeImportService{
+		transactions: []*firefly.MappedTransaction{
+			{Original: &database.Transaction{ID: "ok"}},
+			{Original: &database.Transaction{ID: "duplicate"}, Error: common.ErrDuplicate},
+			{Original: &database.Transaction{ID: "probable"}, Error: common.ErrProbableDuplicate},
+			{Original: &database.Transaction{ID: "skipped"}, Error: common.ErrSkipped},
+			{Original: &database.Transaction{ID: "failed"}, Error: errors.New("account not found")},
+		},
+		errArr: []error{errors.New("parse error")},
+	}
+	r := newApiRouter(svc)
+
+	ids := func(rec *httptest.ResponseRecorder) []string {
+		var resp ApiTransactionsResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, []string{"parse error"}, resp.Errors)
+
+		var result []string
+		for _, tx := range resp.Transactions {
+			result = append(result, tx.Original.ID)
+		}
+
+		return result
+	}
+
+	for target, expected := range map[string][]string{
+		"dry":        {"ok", "duplicate", "probable", "skipped", "failed"},
+		"duplicates": {"duplicate", "probable"},
+		"errors":     {"failed"},
+	} {
+		rec := serveApi(r, httptest.NewRequest(http.MethodGet,
+			"/api/v1/sources/revolut/"+target+"?api_key=secret", nil))
+		assert.Equal(t, http.StatusOK, rec.Code, target)
+		assert.Equal(t, expected, ids(rec), target)
+	}
+
+	rec := serveApi(r, httptest.NewRequest(http.MethodGet, "/api/v1/sources/revolut/errors?api_key=secret", nil))
+	assert.Contains(t, rec.Body.String(), `"Error":"account not found"`)
+}
+
+func TestApiCommit(t *testing.T) {
+	withAPIKey(t, "secret")
+	svc := &fakeImportService{
+		transactions: []*firefly.MappedTransaction{
+			{Original: &database.Transaction{ID: "ok"}, IsCommitted: true, FireflyID: "42"},
+		},
+	}
+	r := newApiRouter(svc)
+
+	rec := serveApi(r, httptest.NewRequest(http.MethodGet, "/api/v1/sources/revolut/commit?api_key=secret", nil))
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+	assert.Empty(t, svc.committed)
+
+	rec = serveApi(r, httptest.NewRequest(http.MethodPost, "/api/v1/sources/revolut/commit?api_key=secret", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp ApiTransactionsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Transactions, 1)
+	assert.True(t, resp.Transactions[0].IsCommitted)
+	assert.Equal(t, "42", resp.Transactions[0].FireflyID)
+	assert.Empty(t, resp.Errors)
+
+	if assert.Len(t, svc.committed, 1) {
+		assert.Equal(t, database.Revolut, svc.committed[0].TransactionSource)
+		assert.True(t, svc.committed[0].Configuration.SkipDuplicates)
+	}
+}
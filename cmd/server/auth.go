@@ -0,0 +1,28 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+const telegramSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+var (
+	apiKey              string // API_KEY, required by every endpoint as api_key query parameter
+	telegramSecretToken string // TELEGRAM_WEBHOOK_SECRET, secret_token passed to setWebhook
+)
+
+func secretEquals(expected string, actual string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
+
+// validAPIKey rejects every request when API_KEY is not configured.
+func validAPIKey(r *http.Request) bool {
+	return secretEquals(apiKey, r.URL.Query().Get("api_key"))
+}
+
+// validTelegramRequest accepts the secret token header telegram sends with every update,
+// webhooks registered with ?api_key= keep working.
+func validTelegramRequest(r *http.Request) bool {
+	return secretEquals(telegramSecretToken, r.Header.Get(telegramSecretTokenHeader)) || validAPIKey(r)
+}
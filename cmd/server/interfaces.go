@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
 )
 
@@ -11,4 +13,43 @@ type MessageProcessor interface {
 		ctx context.Context,
 		message processor.Message,
 	) error
+
+	ProcessCallback(
+		ctx context.Context,
+		callback processor.Callback,
+	) error
+}
+
+type MessageStore interface {
+	AddMessage(ctx context.Context, messages []database.Message) error
+	GetMessages(ctx context.Context, source database.TransactionSource, ids []string) ([]*database.Message, error)
+}
+
+type ImportService interface {
+	SupportsSource(source database.TransactionSource) bool
+
+	NewMessages(
+		ctx context.Context,
+		message processor.Message,
+		fileData []byte,
+	) ([]database.Message, error)
+
+	StoreMessages(ctx context.Context, messages []database.Message) error
+
+	PendingMessages(
+		ctx context.Context,
+		source database.TransactionSource,
+	) ([]*database.Message, error)
+
+	PendingTransactions(
+		ctx context.Context,
+		message processor.Message,
+	) ([]*firefly.MappedTransaction, []error, error)
+
+	CommitPending(
+		ctx context.Context,
+		message processor.Message,
+	) ([]*firefly.MappedTransaction, []error, error)
+
+	Clear(ctx context.Context, message processor.Message) error
 }
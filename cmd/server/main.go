@@ -4,12 +4,19 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/cockroachdb/errors"
 	"github.com/gorilla/mux"
 	"github.com/imroc/req/v3"
+	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
 
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/accountmap"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/counterparty"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/duplicatecleaner"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
@@ -18,19 +25,20 @@ import (
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/printer"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/rules"
 )
 
-var apiKey string
-
 func main() {
-	client, err := azcosmos.NewClientFromConnectionString(os.Getenv("COSMO_DB_CONNECTION_STRING"), nil)
-	if err != nil {
-		panic(err)
+	apiKey = os.Getenv("API_KEY")
+	telegramSecretToken = os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+
+	if apiKey == "" && telegramSecretToken == "" {
+		panic(errors.New("API_KEY or TELEGRAM_WEBHOOK_SECRET is required"))
 	}
 
 	var fireflyAdditionalHeaders map[string]string
 	if v, ok := os.LookupEnv("FIREFLY_ADDITIONAL_HEADERS"); ok {
-		if err = json.Unmarshal([]byte(v), &fireflyAdditionalHeaders); err != nil {
+		if err := json.Unmarshal([]byte(v), &fireflyAdditionalHeaders); err != nil {
 			panic(err)
 		}
 	}
@@ -43,29 +51,101 @@ func main() {
 		fireflyAdditionalHeaders,
 	)
 
-	chatMap := map[string]database.TransactionSource{}
-	if err = json.Unmarshal([]byte(os.Getenv("CHAT_MAP")), &chatMap); err != nil {
+	chatMap, err := loadChatMap()
+	if err != nil {
 		panic(err)
 	}
 
-	dataRepo, err := repo.NewCosmo(client, os.Getenv("COSMO_DB_NAME"))
+	dataRepo, err := repo.NewStorageFromEnv(repo.StorageCosmo)
 	if err != nil {
 		panic(err)
 	}
 
 	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler { // handlers log with zerolog.Ctx
+		return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+			next.ServeHTTP(w, request.WithContext(log.Logger.WithContext(request.Context())))
+		})
+	})
 
 	tgNotifier := notifications.NewTelegram(
 		os.Getenv("TELEGRAM_BOT_TOKEN"),
 		httpClient,
 	)
 
+	notifier := notifications.NewRouter(tgNotifier)
+
+	slackChats, err := transportChats(chatMap, notifications.TransportSlack)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(slackChats) > 0 {
+		if os.Getenv("SLACK_SIGNING_SECRET") == "" {
+			panic(errors.New("SLACK_SIGNING_SECRET is required for slack chats"))
+		}
+
+		slackURL := "https://slack.com/api"
+		if val, ok := os.LookupEnv("SLACK_API_URL"); ok && val != "" {
+			slackURL = val
+		}
+
+		notifier.Register(notifications.TransportSlack, notifications.NewSlack(
+			slackURL,
+			os.Getenv("SLACK_BOT_TOKEN"),
+			httpClient,
+			slackChats,
+		), lo.Keys(slackChats)...)
+	}
+
+	matrixChats, err := transportChats(chatMap, notifications.TransportMatrix)
+	if err != nil {
+		panic(err)
+	}
+
+	matrixNotifier := notifications.NewMatrix(
+		os.Getenv("MATRIX_HOMESERVER_URL"),
+		os.Getenv("MATRIX_ACCESS_TOKEN"),
+		httpClient,
+		matrixChats,
+	)
+	if len(matrixChats) > 0 {
+		if os.Getenv("MATRIX_HS_TOKEN") == "" {
+			panic(errors.New("MATRIX_HS_TOKEN is required for matrix chats"))
+		}
+
+		notifier.Register(notifications.TransportMatrix, matrixNotifier, lo.Keys(matrixChats)...)
+	}
+
+	webhookChats, err := transportChats(chatMap, notifications.TransportWebhook)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(webhookChats) > 0 {
+		if apiKey == "" {
+			panic(errors.New("API_KEY is required for webhook chats"))
+		}
+
+		webhookNotifier := notifications.NewWebhook(
+			os.Getenv("WEBHOOK_CALLBACK_URL"),
+			httpClient,
+			nil,
+		)
+
+		if val, ok := os.LookupEnv("WEBHOOK_FILE_HOSTS"); ok && val != "" {
+			webhookNotifier.SetFileHosts(strings.Split(val, ",")...)
+		}
+
+		notifier.Register(notifications.TransportWebhook, webhookNotifier, lo.Keys(webhookChats)...)
+	}
+
 	dedup := duplicatecleaner.NewDuplicateCleaner(dataRepo)
 
 	parserConfig := &processor.Config{
 		Repo:             dataRepo,
 		Parsers:          map[database.TransactionSource]processor.Parser{},
-		NotificationSvc:  tgNotifier,
+		NotificationSvc:  notifier,
 		FireflySvc:       fireflyClient,
 		DuplicateCleaner: dedup,
 		Printer:          printer.NewPrinter(),
@@ -77,13 +157,135 @@ func main() {
 		parser.NewZen(),
 		parser.NewMono(),
 		parser.NewRevolut(),
+		parser.NewCamt(),
+		parser.NewMT940(),
+		parser.NewOfx(),
 	} {
 		parserConfig.Parsers[p.Type()] = p
 	}
 
+	if path, ok := os.LookupEnv("CSV_PROFILES_FILE"); ok && path != "" {
+		profileParsers, profileErr := parser.LoadCsvProfiles(path)
+		if profileErr != nil {
+			panic(profileErr)
+		}
+
+		for _, p := range profileParsers {
+			if _, exists := parserConfig.Parsers[p.Type()]; exists {
+				panic(errors.Newf("parser for source %v is already registered", p.Type()))
+			}
+
+			parserConfig.Parsers[p.Type()] = p
+		}
+	}
+
+	if path, ok := os.LookupEnv("RULES_FILE"); ok && path != "" {
+		rulesEngine, rulesErr := rules.LoadFile(path)
+		if rulesErr != nil {
+			panic(rulesErr)
+		}
+
+		parserConfig.Rules = rulesEngine
+	}
+
+	if path, ok := os.LookupEnv("COUNTERPARTY_ALIASES_FILE"); ok && path != "" {
+		normalizer, normalizerErr := counterparty.LoadFile(path)
+		if normalizerErr != nil {
+			panic(normalizerErr)
+		}
+
+		fireflyClient.SetCounterpartyNormalizer(normalizer)
+	}
+
+	var staticMappings []*database.AccountMapping
+	if path, ok := os.LookupEnv("ACCOUNT_MAPPING_FILE"); ok && path != "" {
+		staticMappings, err = accountmap.LoadFile(path)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	accountMapper := accountmap.NewMapper(dataRepo, staticMappings)
+	fireflyClient.SetAccountMapper(accountMapper)
+	parserConfig.AccountMapper = accountMapper
+
+	if val, ok := os.LookupEnv("PRINTER_FORMAT"); ok && val != "" {
+		switch val {
+		case "text": // default printer
+		case "html": // telegram only, other transports would show the tags
+			parserConfig.Printer = printer.NewHTML()
+			tgNotifier.SetParseMode(notifications.ParseModeHTML)
+		default:
+			panic(errors.Newf("unknown PRINTER_FORMAT %s", val))
+		}
+	}
+
+	if val, ok := os.LookupEnv("DRY_DOCUMENT_FORMAT"); ok && val != "" {
+		switch val {
+		case "csv":
+			parserConfig.Document = printer.NewCSV()
+		case "xlsx":
+			parserConfig.Document = printer.NewXLSX()
+		default:
+			panic(errors.Newf("unknown DRY_DOCUMENT_FORMAT %s", val))
+		}
+
+		parserConfig.DocumentThreshold = 30
+
+		if threshold, thresholdOk := os.LookupEnv("DRY_DOCUMENT_THRESHOLD"); thresholdOk && threshold != "" {
+			parserConfig.DocumentThreshold, err = strconv.Atoi(threshold)
+			if err != nil {
+				panic(errors.Wrapf(err, "failed to parse DRY_DOCUMENT_THRESHOLD"))
+			}
+		}
+	}
+
+	if val, ok := os.LookupEnv("FUZZY_DUPLICATE_DAYS"); ok && val != "" {
+		days, daysErr := strconv.Atoi(val)
+		if daysErr != nil {
+			panic(errors.Wrapf(daysErr, "failed to parse FUZZY_DUPLICATE_DAYS"))
+		}
+
+		parserConfig.DuplicateWindow = time.Duration(days) * 24 * time.Hour
+	}
+
 	processorSvc := processor.NewProcessor(parserConfig)
 	handle := NewHandler(processorSvc, chatMap)
 	r.Handle("/api/github/webhook", handle)
+	NewApiHandler(processorSvc, chatMap).Register(r)
+
+	monoHandler, err := newMonoHandlerFromEnv(dataRepo, chatMap)
+	if err != nil {
+		panic(err)
+	}
+
+	if monoHandler != nil {
+		r.Handle("/api/mono/webhook", monoHandler)
+	}
+
+	if len(slackChats) > 0 {
+		r.Handle("/api/slack/events", NewSlackHandler(
+			processorSvc,
+			os.Getenv("SLACK_SIGNING_SECRET"),
+			slackChats,
+			chatMap,
+		))
+	}
+
+	if len(matrixChats) > 0 {
+		r.Handle("/_matrix/app/v1/transactions/{txnId}", NewMatrixHandler(
+			processorSvc,
+			matrixNotifier,
+			os.Getenv("MATRIX_HS_TOKEN"),
+			os.Getenv("MATRIX_USER_ID"),
+			matrixChats,
+			chatMap,
+		)).Methods(http.MethodPut)
+	}
+
+	if len(webhookChats) > 0 {
+		r.Handle("/api/generic/webhook", NewGenericWebhookHandler(processorSvc, chatMap))
+	}
 
 	listenAddr := ":8080"
 	if val, ok := os.LookupEnv("FUNCTIONS_CUSTOMHANDLER_PORT"); ok {
@@ -99,3 +301,109 @@ func main() {
 
 	panic(srv.ListenAndServe())
 }
+
+// newMonoHandlerFromEnv stores mono webhook items for the chat configured with the mono source, MONO_CHAT_ID
+// selects it when there are several. MONO_ACCOUNTS maps mono account ids to their currency (chat currency or UAH).
+// The handler is nil without a mono chat or API_KEY, mono webhooks are authenticated with the api key only.
+func newMonoHandlerFromEnv(
+	store MessageStore,
+	chatMap map[string]common.ChatConfiguration,
+) (*MonoHandler, error) {
+	if apiKey == "" {
+		return nil, nil
+	}
+
+	accountCurrencies := map[string]string{}
+	if v, ok := os.LookupEnv("MONO_ACCOUNTS"); ok && v != "" {
+		if err := json.Unmarshal([]byte(v), &accountCurrencies); err != nil {
+			return nil, errors.Wrap(err, "failed to parse MONO_ACCOUNTS")
+		}
+	}
+
+	chatID, err := monoChatID(chatMap, os.Getenv("MONO_CHAT_ID"))
+	if err != nil || chatID == 0 {
+		return nil, err
+	}
+
+	return NewMonoHandler(store, chatID, accountCurrencies), nil
+}
+
+// monoChatID returns the chat receiving mono webhook items, 0 when no chat has the mono source.
+func monoChatID(
+	chatMap map[string]common.ChatConfiguration,
+	configured string,
+) (int64, error) {
+	if configured != "" {
+		cfg, ok := chatMap[configured]
+		if !ok || cfg.Source != database.Mono {
+			return 0, errors.Newf("MONO_CHAT_ID %s is not a chat with the mono source", configured)
+		}
+
+		return strconv.ParseInt(configured, 10, 64)
+	}
+
+	var ids []string
+	for id, cfg := range chatMap {
+		if cfg.Source == database.Mono {
+			ids = append(ids, id)
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return 0, nil
+	case 1:
+		chatID, err := strconv.ParseInt(ids[0], 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid chat id %s", ids[0])
+		}
+
+		return chatID, nil
+	default:
+		return 0, errors.New("several chats have the mono source, set MONO_CHAT_ID")
+	}
+}
+
+// transportChats returns chat id => native channel of the chats configured with the transport.
+func transportChats(
+	chatMap map[string]common.ChatConfiguration,
+	transport string,
+) (map[int64]string, error) {
+	chats := map[int64]string{}
+
+	for id, cfg := range chatMap {
+		if cfg.Transport != transport {
+			continue
+		}
+
+		chatID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid chat id %s", id)
+		}
+
+		chats[chatID] = cfg.Channel
+	}
+
+	return chats, nil
+}
+
+// loadChatMap reads chat configuration from CHAT_MAP_FILE when set, otherwise from CHAT_MAP.
+func loadChatMap() (map[string]common.ChatConfiguration, error) {
+	raw := []byte(os.Getenv("CHAT_MAP"))
+
+	if path, ok := os.LookupEnv("CHAT_MAP_FILE"); ok && path != "" {
+		fileData, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		raw = fileData
+	}
+
+	chatMap := map[string]common.ChatConfiguration{}
+	if err := json.Unmarshal(raw, &chatMap); err != nil {
+		return nil, err
+	}
+
+	return chatMap, nil
+}
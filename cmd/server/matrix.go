@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+)
+
+// MatrixHandler receives room events pushed by the homeserver to the importer registered as an appservice.
+type MatrixHandler struct {
+	processor MessageProcessor
+	matrix    *notifications.Matrix
+	hsToken   string
+	userID    string
+	chats     map[string]int64 // room id => chat id
+	chatMap   map[string]common.ChatConfiguration
+}
+
+func NewMatrixHandler(
+	processor MessageProcessor,
+	matrix *notifications.Matrix,
+	hsToken string,
+	userID string,
+	chats map[int64]string,
+	chatMap map[string]common.ChatConfiguration,
+) *MatrixHandler {
+	return &MatrixHandler{
+		processor: processor,
+		matrix:    matrix,
+		hsToken:   hsToken,
+		userID:    userID,
+		chats:     lo.Invert(chats),
+		chatMap:   chatMap,
+	}
+}
+
+type matrixTransaction struct {
+	Events []matrixEvent `json:"events"`
+}
+
+type matrixEvent struct {
+	Type           string `json:"type"`
+	EventID        string `json:"event_id"`
+	RoomID         string `json:"room_id"`
+	Sender         string `json:"sender"`
+	OriginServerTs int64  `json:"origin_server_ts"`
+	Content        struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+		URL     string `json:"url"`
+	} `json:"content"`
+}
+
+func (h *MatrixHandler) ServeHTTP(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("access_token") // legacy appservice auth
+	}
+
+	if !secretEquals(h.hsToken, token) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errcode":"M_FORBIDDEN"}`))
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	var txn matrixTransaction
+	if err = json.Unmarshal(b, &txn); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	for _, event := range txn.Events {
+		if event.Type != "m.room.message" || event.Sender == h.userID {
+			continue
+		}
+
+		chatID, ok := h.chats[event.RoomID]
+		if !ok {
+			continue
+		}
+
+		messageID, isNew := h.matrix.MessageID(event.EventID)
+		if !isNew { // redelivered transaction, e.g. the response got lost
+			continue
+		}
+
+		message := processor.Message{
+			ID:           event.EventID,
+			Date:         time.UnixMilli(event.OriginServerTs),
+			OriginalDate: time.UnixMilli(event.OriginServerTs),
+			ChatID:       chatID,
+			Content:      event.Content.Body,
+			MessageID:    messageID,
+			UserID:       event.Sender,
+		}
+
+		if event.Content.MsgType == "m.file" { // body is the file name
+			message.Content = ""
+			message.FileID = notifications.FileID(notifications.TransportMatrix, event.Content.URL)
+		}
+
+		chatCfg := h.chatMap[strconv.FormatInt(chatID, 10)]
+		message.TransactionSource = chatCfg.Source
+		message.Configuration = chatCfg
+
+		_ = h.processor.ProcessMessage(notifications.WithMatrixEvent(r.Context(), event.EventID), message)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("{}"))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+)
+
+const matrixTransactionBody = `{"events":[` +
+	`{"type":"m.room.message","event_id":"$1","room_id":"!room:example.org","sender":"@alice:example.org",` +
+	`"origin_server_ts":1712345678000,"content":{"msgtype":"m.text","body":"/dry"}},` +
+	`{"type":"m.room.message","event_id":"$2","room_id":"!room:example.org","sender":"@importer:example.org",` +
+	`"origin_server_ts":1712345678000,"content":{"msgtype":"m.text","body":"reply"}}]}`
+
+func newMatrixHandler(hsToken string) (*MatrixHandler, *fakeMessageProcessor) {
+	proc := &fakeMessageProcessor{}
+	rooms := map[int64]string{1002: "!room:example.org"}
+
+	return NewMatrixHandler(
+		proc,
+		notifications.NewMatrix("https://matrix.example.org", "as_token", req.C(), rooms),
+		hsToken,
+		"@importer:example.org",
+		rooms,
+		map[string]common.ChatConfiguration{
+			"1002": {Source: database.Paribas, Transport: "matrix", Channel: "!room:example.org"},
+		},
+	), proc
+}
+
+func matrixRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/_matrix/app/v1/transactions/1", strings.NewReader(matrixTransactionBody))
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return r
+}
+
+func TestMatrixSender(t *testing.T) {
+	handler, proc := newMatrixHandler("hs_token")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, matrixRequest("hs_token"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, proc.messages, 1) {
+		assert.Equal(t, "@alice:example.org", proc.messages[0].UserID)
+		assert.EqualValues(t, 1002, proc.messages[0].ChatID)
+		assert.Equal(t, database.Paribas, proc.messages[0].TransactionSource)
+	}
+}
+
+func TestMatrixToken(t *testing.T) {
+	for _, tc := range []struct {
+		configured string
+		sent       string
+	}{
+		{"hs_token", "wrong"},
+		{"", ""},
+	} {
+		handler, proc := newMatrixHandler(tc.configured)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, matrixRequest(tc.sent))
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, proc.messages)
+	}
+}
+
+func TestMatrixRedelivery(t *testing.T) {
+	handler, proc := newMatrixHandler("hs_token")
+
+	for i := 0; i < 2; i++ { // the homeserver retries when the response is lost
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, matrixRequest("hs_token"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	if assert.Len(t, proc.messages, 1) {
+		assert.Equal(t, "$1", proc.messages[0].ID)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+)
+
+const monoMaxBodySize = 1 << 20
+
+// MonoHandler receives statement items pushed by the mono personal api webhook
+// and stores them as pending messages of the mono source.
+type MonoHandler struct {
+	store             MessageStore
+	chatID            int64
+	accountCurrencies map[string]string
+}
+
+func NewMonoHandler(
+	store MessageStore,
+	chatID int64,
+	accountCurrencies map[string]string,
+) *MonoHandler {
+	return &MonoHandler{
+		store:             store,
+		chatID:            chatID,
+		accountCurrencies: accountCurrencies,
+	}
+}
+
+func (h *MonoHandler) ServeHTTP(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	if !validAPIKey(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("unauthorized"))
+		return
+	}
+
+	if r.Method == http.MethodGet { // mono validates the webhook url with GET
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, monoMaxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	var webhook parser.MonoWebhook
+	if err = json.Unmarshal(b, &webhook); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	if webhook.Type != parser.MonoWebhookStatementItem {
+		zerolog.Ctx(r.Context()).Warn().Msgf("skipping mono webhook type %s", webhook.Type)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if webhook.Data.StatementItem.ID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("statement item id is missing"))
+		return
+	}
+
+	// mono retries deliveries, the message id is derived from the item so a redelivery is stored once
+	messageID := "mono_" + hex.EncodeToString([]byte(webhook.Data.StatementItem.ID))
+
+	existing, err := h.store.GetMessages(r.Context(), database.Mono, []string{messageID})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	if len(existing) > 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	webhook.Data.Currency = h.accountCurrencies[webhook.Data.Account]
+
+	content, err := json.Marshal(webhook)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err = h.store.AddMessage(r.Context(), []database.Message{
+		{
+			ID:                messageID,
+			CreatedAt:         time.Unix(webhook.Data.StatementItem.Time, 0),
+			Content:           hex.EncodeToString(content),
+			ChatID:            h.chatID,
+			TransactionSource: database.Mono,
+		},
+	}); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+)
+
+const monoStatementItem = `{"type":"StatementItem","data":{"account":"acc-usd","statementItem":` +
+	`{"id":"ZuHWzqkKGVo=","time":1760000000,"description":"Сільпо","mcc":5411,"hold":true,` +
+	`"amount":-4525,"operationAmount":-4525,"currencyCode":840,"balance":125475}}}`
+
+func newMonoTestHandler(t *testing.T) (*MonoHandler, *repo.Memory) {
+	withAPIKey(t, "secret")
+
+	store := repo.NewMemory()
+
+	return NewMonoHandler(store, 1003, map[string]string{"acc-usd": "USD"}), store
+}
+
+func serveMono(handler http.Handler, method string, target string, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	return rec
+}
+
+func pendingMono(t *testing.T, store *repo.Memory) []*database.Message {
+	messages, err := store.GetLatestMessages(context.TODO(), database.Mono)
+	assert.NoError(t, err)
+
+	return messages
+}
+
+func TestMonoUnauthorized(t *testing.T) {
+	handler, store := newMonoTestHandler(t)
+
+	for _, target := range []string{"/api/mono/webhook", "/api/mono/webhook?api_key=wrong"} {
+		rec := serveMono(handler, http.MethodPost, target, monoStatementItem)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, target)
+	}
+
+	assert.Empty(t, pendingMono(t, store))
+}
+
+func TestMonoValidation(t *testing.T) {
+	handler, store := newMonoTestHandler(t)
+
+	rec := serveMono(handler, http.MethodGet, "/api/mono/webhook?api_key=secret", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, pendingMono(t, store))
+}
+
+func TestMonoSkipsOtherTypes(t *testing.T) {
+	handler, store := newMonoTestHandler(t)
+
+	rec := serveMono(handler, http.MethodPost, "/api/mono/webhook?api_key=secret", `{"type":"Other","data":{}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, pendingMono(t, store))
+}
+
+func TestMonoMalformedBody(t *testing.T) {
+	handler, store := newMonoTestHandler(t)
+
+	for _, body := range []string{
+		`{"type":`,
+		`{"type":"StatementItem","data":{"statementItem":{"time":1760000000}}}`,
+	} {
+		rec := serveMono(handler, http.MethodPost, "/api/mono/webhook?api_key=secret", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	rec := serveMono(handler, http.MethodPost, "/api/mono/webhook?api_key=secret",
+		`{"type":"StatementItem","data":{"account":"`+strings.Repeat("a", monoMaxBodySize)+`"}}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	assert.Empty(t, pendingMono(t, store))
+}
+
+func TestMonoStoresItemOnce(t *testing.T) {
+	handler, store := newMonoTestHandler(t)
+
+	for i := 0; i < 2; i++ { // redelivery
+		rec := serveMono(handler, http.MethodPost, "/api/mono/webhook?api_key=secret", monoStatementItem)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	messages := pendingMono(t, store)
+	if !assert.Len(t, messages, 1) {
+		return
+	}
+
+	assert.EqualValues(t, 1003, messages[0].ChatID)
+	assert.Equal(t, database.Mono, messages[0].TransactionSource)
+
+	content, err := hex.DecodeString(messages[0].Content)
+	assert.NoError(t, err)
+
+	var webhook parser.MonoWebhook
+	assert.NoError(t, json.Unmarshal(content, &webhook))
+	assert.Equal(t, "USD", webhook.Data.Currency)
+	assert.Equal(t, "ZuHWzqkKGVo=", webhook.Data.StatementItem.ID)
+}
@@ -9,16 +9,18 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/rs/zerolog"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
 )
 
 type Handler struct {
 	processor MessageProcessor
-	chatMap   map[string]database.TransactionSource
+	chatMap   map[string]common.ChatConfiguration
 }
 
-func NewHandler(processor MessageProcessor, chatMap map[string]database.TransactionSource) *Handler {
+func NewHandler(processor MessageProcessor, chatMap map[string]common.ChatConfiguration) *Handler {
 	return &Handler{
 		processor: processor,
 		chatMap:   chatMap,
@@ -29,6 +31,34 @@ func (h *Handler) ProcessWebhook(
 	ctx context.Context,
 	webhook Webhook,
 ) error {
+	if webhook.CallbackQuery != nil {
+		chatID := webhook.CallbackQuery.Message.Chat.Id
+
+		chatCfg, ok := h.chatMap[fmt.Sprint(chatID)]
+		if !ok {
+			auditUnknownChat(ctx, webhook.UpdateId, chatID, webhook.CallbackQuery.From)
+			return nil
+		}
+
+		_ = h.processor.ProcessCallback(ctx, processor.Callback{
+			ID:                webhook.CallbackQuery.ID,
+			ChatID:            chatID,
+			MessageID:         webhook.CallbackQuery.Message.MessageID,
+			UserID:            strconv.FormatInt(webhook.CallbackQuery.From.Id, 10),
+			Data:              webhook.CallbackQuery.Data,
+			TransactionSource: chatCfg.Source,
+			Configuration:     chatCfg,
+		})
+
+		return nil
+	}
+
+	chatCfg, ok := h.chatMap[fmt.Sprint(webhook.Message.Chat.Id)]
+	if !ok {
+		auditUnknownChat(ctx, webhook.UpdateId, webhook.Message.Chat.Id, webhook.Message.From)
+		return nil
+	}
+
 	date := time.Unix(webhook.Message.Date, 0)
 	originalDate := date
 	forwardedFrom := ""
@@ -38,8 +68,6 @@ func (h *Handler) ProcessWebhook(
 		forwardedFrom = webhook.Message.ForwardOrigin.SenderUser.UserName
 	}
 
-	source := h.chatMap[fmt.Sprint(webhook.Message.Chat.Id)]
-
 	_ = h.processor.ProcessMessage(ctx, processor.Message{
 		ID:                strconv.FormatInt(webhook.UpdateId, 10),
 		Date:              date,
@@ -48,18 +76,31 @@ func (h *Handler) ProcessWebhook(
 		Content:           webhook.Message.Text,
 		ForwardedFrom:     forwardedFrom,
 		MessageID:         webhook.Message.MessageID,
+		UserID:            strconv.FormatInt(webhook.Message.From.Id, 10),
 		FileID:            webhook.Message.Document.FileID,
-		TransactionSource: source,
+		TransactionSource: chatCfg.Source,
+		Configuration:     chatCfg,
 	})
 
 	return nil
 }
 
+// auditUnknownChat logs updates from chats missing in CHAT_MAP, they are acknowledged to telegram and dropped.
+func auditUnknownChat(ctx context.Context, updateID int64, chatID int64, from SenderUser) {
+	zerolog.Ctx(ctx).Warn().
+		Str("audit", "unknown_chat").
+		Int64("update_id", updateID).
+		Int64("chat_id", chatID).
+		Int64("user_id", from.Id).
+		Str("username", from.UserName).
+		Msg("rejected telegram update from unknown chat")
+}
+
 func (h *Handler) ServeHTTP(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	if apiKey != r.URL.Query().Get("api_key") {
+	if !validTelegramRequest(r) {
 		w.WriteHeader(http.StatusUnauthorized)
 		_, _ = w.Write([]byte("unauthorized"))
 		return
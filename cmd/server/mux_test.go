@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+)
+
+type fakeMessageProcessor struct {
+	messages  []processor.Message
+	callbacks []processor.Callback
+}
+
+func (f *fakeMessageProcessor) ProcessMessage(_ context.Context, message processor.Message) error {
+	f.messages = append(f.messages, message)
+
+	return nil
+}
+
+func (f *fakeMessageProcessor) ProcessCallback(_ context.Context, callback processor.Callback) error {
+	f.callbacks = append(f.callbacks, callback)
+
+	return nil
+}
+
+func withTelegramSecret(t *testing.T, secret string) {
+	prev := telegramSecretToken
+	telegramSecretToken = secret
+
+	t.Cleanup(func() {
+		telegramSecretToken = prev
+	})
+}
+
+func newTelegramHandler() (*Handler, *fakeMessageProcessor) {
+	proc := &fakeMessageProcessor{}
+
+	return NewHandler(proc, map[string]common.ChatConfiguration{
+		"123": {Source: database.Revolut},
+	}), proc
+}
+
+func telegramRequest(target string, body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+}
+
+const telegramUpdate = `{"update_id":5,"message":{"message_id":7,"date":1712345678,"text":"/dry",` +
+	`"chat":{"id":123},"from":{"id":42,"username":"user"}}}`
+
+func TestTelegramAPIKey(t *testing.T) {
+	withAPIKey(t, "secret")
+	withTelegramSecret(t, "")
+
+	for _, target := range []string{"/api/github/webhook", "/api/github/webhook?api_key=wrong"} {
+		handler, proc := newTelegramHandler()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, telegramRequest(target, telegramUpdate))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, target)
+		assert.Empty(t, proc.messages, target)
+	}
+
+	handler, proc := newTelegramHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, telegramRequest("/api/github/webhook?api_key=secret", telegramUpdate))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, proc.messages, 1) {
+		assert.Equal(t, "5", proc.messages[0].ID)
+		assert.EqualValues(t, 123, proc.messages[0].ChatID)
+		assert.Equal(t, "42", proc.messages[0].UserID)
+		assert.Equal(t, database.Revolut, proc.messages[0].TransactionSource)
+	}
+}
+
+func TestTelegramSecretToken(t *testing.T) {
+	withAPIKey(t, "")
+	withTelegramSecret(t, "tg-secret")
+
+	handler, proc := newTelegramHandler()
+
+	req := telegramRequest("/api/github/webhook", telegramUpdate)
+	req.Header.Set(telegramSecretTokenHeader, "tg-secret")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, proc.messages, 1)
+
+	req = telegramRequest("/api/github/webhook", telegramUpdate)
+	req.Header.Set(telegramSecretTokenHeader, "wrong")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Len(t, proc.messages, 1)
+}
+
+func TestTelegramEmptySecret(t *testing.T) {
+	withAPIKey(t, "")
+	withTelegramSecret(t, "")
+
+	handler, proc := newTelegramHandler()
+
+	req := telegramRequest("/api/github/webhook?api_key=", telegramUpdate)
+	req.Header.Set(telegramSecretTokenHeader, "")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, proc.messages)
+}
+
+func TestTelegramUnknownChat(t *testing.T) {
+	withAPIKey(t, "secret")
+
+	handler, proc := newTelegramHandler()
+
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	req := telegramRequest("/api/github/webhook?api_key=secret",
+		`{"update_id":6,"message":{"message_id":8,"text":"/commit","chat":{"id":999},"from":{"id":13,"username":"other"}}}`)
+	req = req.WithContext(logger.WithContext(req.Context()))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, proc.messages)
+	assert.Contains(t, logs.String(), `"audit":"unknown_chat"`)
+	assert.Contains(t, logs.String(), `"chat_id":999`)
+	assert.Contains(t, logs.String(), `"user_id":13`)
+
+	req = telegramRequest("/api/github/webhook?api_key=secret",
+		`{"update_id":7,"callback_query":{"id":"cb","data":"x","from":{"id":13},"message":{"chat":{"id":999}}}}`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, proc.callbacks)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+)
+
+const slackMaxRequestAge = 5 * time.Minute
+
+// SlackHandler receives channel messages from the slack events api.
+type SlackHandler struct {
+	processor     MessageProcessor
+	signingSecret string
+	chats         map[string]int64 // slack channel id => chat id
+	chatMap       map[string]common.ChatConfiguration
+}
+
+func NewSlackHandler(
+	processor MessageProcessor,
+	signingSecret string,
+	chats map[int64]string,
+	chatMap map[string]common.ChatConfiguration,
+) *SlackHandler {
+	return &SlackHandler{
+		processor:     processor,
+		signingSecret: signingSecret,
+		chats:         lo.Invert(chats),
+		chatMap:       chatMap,
+	}
+}
+
+type slackEnvelope struct {
+	Type      string     `json:"type"`
+	Challenge string     `json:"challenge"`
+	EventID   string     `json:"event_id"`
+	Event     slackEvent `json:"event"`
+}
+
+type slackEvent struct {
+	Type    string      `json:"type"`
+	Subtype string      `json:"subtype"`
+	Channel string      `json:"channel"`
+	User    string      `json:"user"`
+	Text    string      `json:"text"`
+	Ts      string      `json:"ts"`
+	BotID   string      `json:"bot_id"`
+	Files   []slackFile `json:"files"`
+}
+
+type slackFile struct {
+	URLPrivateDownload string `json:"url_private_download"`
+}
+
+// verify checks the v0 request signature, see https://api.slack.com/authentication/verifying-requests-from-slack
+func (h *SlackHandler) verify(r *http.Request, body []byte) bool {
+	if h.signingSecret == "" {
+		return false
+	}
+
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || math.Abs(time.Since(time.Unix(sec, 0)).Seconds()) > slackMaxRequestAge.Seconds() {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(h.signingSecret))
+	_, _ = mac.Write([]byte("v0:" + timestamp + ":"))
+	_, _ = mac.Write(body)
+
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature")))
+}
+
+func (h *SlackHandler) ServeHTTP(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	if !h.verify(r, b) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("unauthorized"))
+		return
+	}
+
+	var envelope slackEnvelope
+	if err = json.Unmarshal(b, &envelope); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	if envelope.Type == "url_verification" {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(envelope.Challenge))
+		return
+	}
+
+	event := envelope.Event
+
+	// bot messages include our own replies, retries are already being processed
+	if envelope.Type != "event_callback" || event.Type != "message" || event.BotID != "" ||
+		(event.Subtype != "" && event.Subtype != "file_share") || r.Header.Get("X-Slack-Retry-Num") != "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	chatID, ok := h.chats[event.Channel]
+	if !ok {
+		zerolog.Ctx(r.Context()).Warn().Msgf("skipping message from unknown slack channel %s", event.Channel)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	messageID, err := notifications.SlackMessageID(event.Ts)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	fileID := ""
+	if len(event.Files) > 0 {
+		fileID = notifications.FileID(notifications.TransportSlack, event.Files[0].URLPrivateDownload)
+	}
+
+	date := time.UnixMicro(messageID)
+	chatCfg := h.chatMap[strconv.FormatInt(chatID, 10)]
+
+	_ = h.processor.ProcessMessage(r.Context(), processor.Message{
+		ID:                envelope.EventID,
+		Date:              date,
+		OriginalDate:      date,
+		ChatID:            chatID,
+		Content:           event.Text,
+		MessageID:         messageID,
+		UserID:            event.User,
+		FileID:            fileID,
+		TransactionSource: chatCfg.Source,
+		Configuration:     chatCfg,
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
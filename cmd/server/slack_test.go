@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+func slackRequest(secret string, body string) *http.Request {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/slack/events", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+	return req
+}
+
+const slackMessage = `{"type":"event_callback","event_id":"Ev1","event":{"type":"message","channel":"C123",` +
+	`"user":"U012AB3CD","text":"/dry","ts":"1712345678.000100"}}`
+
+func TestSlackSender(t *testing.T) {
+	proc := &fakeMessageProcessor{}
+	handler := NewSlackHandler(
+		proc,
+		"signing",
+		map[int64]string{1001: "C123"},
+		map[string]common.ChatConfiguration{
+			"1001": {Source: database.Revolut, Transport: "slack", Channel: "C123"},
+		},
+	)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, slackRequest("signing", slackMessage))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, proc.messages, 1) {
+		assert.Equal(t, "U012AB3CD", proc.messages[0].UserID)
+		assert.EqualValues(t, 1001, proc.messages[0].ChatID)
+		assert.Equal(t, "/dry", proc.messages[0].Content)
+	}
+}
+
+func TestSlackEmptySecret(t *testing.T) {
+	proc := &fakeMessageProcessor{}
+	handler := NewSlackHandler(proc, "", map[int64]string{1001: "C123"}, map[string]common.ChatConfiguration{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, slackRequest("", slackMessage))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, proc.messages)
+}
@@ -1,8 +1,16 @@
 package main
 
 type Webhook struct {
-	Message  Message `json:"message"`
-	UpdateId int64   `json:"update_id"`
+	Message       Message        `json:"message"`
+	CallbackQuery *CallbackQuery `json:"callback_query"`
+	UpdateId      int64          `json:"update_id"`
+}
+
+type CallbackQuery struct {
+	ID      string     `json:"id"`
+	Data    string     `json:"data"`
+	From    SenderUser `json:"from"`
+	Message Message    `json:"message"`
 }
 
 type Message struct {
@@ -11,6 +19,7 @@ type Message struct {
 	Document      Document       `json:"document"`
 	Text          string         `json:"text"`
 	Chat          Chat           `json:"chat"`
+	From          SenderUser     `json:"from"`
 	MessageID     int64          `json:"message_id"`
 }
 
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+)
+
+// GenericMessage is posted by any chat platform bridged with the generic webhook transport.
+type GenericMessage struct {
+	ChatID    int64  `json:"chatId"`
+	MessageID int64  `json:"messageId"`
+	UserID    int64  `json:"userId"`
+	Text      string `json:"text"`
+	FileURL   string `json:"fileUrl"`
+	Date      int64  `json:"date"`
+}
+
+// GenericWebhookHandler receives messages of chats configured with the webhook transport.
+type GenericWebhookHandler struct {
+	processor MessageProcessor
+	chatMap   map[string]common.ChatConfiguration
+}
+
+func NewGenericWebhookHandler(
+	processor MessageProcessor,
+	chatMap map[string]common.ChatConfiguration,
+) *GenericWebhookHandler {
+	return &GenericWebhookHandler{
+		processor: processor,
+		chatMap:   chatMap,
+	}
+}
+
+func (h *GenericWebhookHandler) ServeHTTP(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	if !validAPIKey(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("unauthorized"))
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	var msg GenericMessage
+	if err = json.Unmarshal(b, &msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	chatCfg, ok := h.chatMap[strconv.FormatInt(msg.ChatID, 10)]
+	if !ok || chatCfg.Transport != notifications.TransportWebhook {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("unknown chat"))
+		return
+	}
+
+	date := time.Now().UTC()
+	if msg.Date > 0 {
+		date = time.Unix(msg.Date, 0)
+	}
+
+	userID := ""
+	if msg.UserID != 0 {
+		userID = strconv.FormatInt(msg.UserID, 10)
+	}
+
+	fileID := ""
+	if msg.FileURL != "" {
+		fileID = notifications.FileID(notifications.TransportWebhook, msg.FileURL)
+	}
+
+	_ = h.processor.ProcessMessage(r.Context(), processor.Message{
+		ID:                uuid.NewString(),
+		Date:              date,
+		OriginalDate:      date,
+		ChatID:            msg.ChatID,
+		Content:           msg.Text,
+		MessageID:         msg.MessageID,
+		UserID:            userID,
+		FileID:            fileID,
+		TransactionSource: chatCfg.Source,
+		Configuration:     chatCfg,
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,133 @@
+package accountmap
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+// Mapper combines mappings managed with /map, which take precedence, with the ones from the config file.
+type Mapper struct {
+	repo   Repo
+	static []*database.AccountMapping
+}
+
+func NewMapper(
+	repo Repo,
+	static []*database.AccountMapping,
+) *Mapper {
+	return &Mapper{
+		repo:   repo,
+		static: static,
+	}
+}
+
+// LoadFile reads mappings from a json file, any other extension is parsed as yaml.
+func LoadFile(path string) ([]*database.AccountMapping, error) {
+	var file File
+
+	if err := common.UnmarshalFile(path, "account mapping", &file); err != nil {
+		return nil, err
+	}
+
+	for _, mapping := range file.Mappings {
+		if mapping.Pattern == "" || mapping.FireflyAccountID == "" {
+			return nil, errors.Newf("pattern and account are required in account mapping file %s", path)
+		}
+	}
+
+	return file.Mappings, nil
+}
+
+func (m *Mapper) Mappings(ctx context.Context) ([]*database.AccountMapping, error) {
+	var mappings []*database.AccountMapping
+
+	if m.repo != nil {
+		stored, err := m.repo.GetAccountMappings(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		mappings = append(mappings, stored...)
+	}
+
+	return append(mappings, m.static...), nil
+}
+
+func (m *Mapper) Set(ctx context.Context, pattern string, fireflyAccountID string) error {
+	if m.repo == nil {
+		return errors.New("account mapping storage is not configured")
+	}
+
+	return m.repo.SaveAccountMapping(ctx, &database.AccountMapping{
+		Pattern:          pattern,
+		FireflyAccountID: fireflyAccountID,
+		CreatedAt:        time.Now().UTC(),
+	})
+}
+
+func (m *Mapper) Delete(ctx context.Context, pattern string) error {
+	if m.repo == nil {
+		return errors.New("account mapping storage is not configured")
+	}
+
+	return m.repo.DeleteAccountMapping(ctx, pattern)
+}
+
+type wildcardMapping struct {
+	mapping *database.AccountMapping
+	regex   *regexp.Regexp
+}
+
+// Matcher resolves raw accounts with mappings, wildcard patterns are compiled once.
+type Matcher struct {
+	exact    map[string]*database.AccountMapping
+	wildcard []*wildcardMapping
+}
+
+func NewMatcher(mappings []*database.AccountMapping) *Matcher {
+	m := &Matcher{
+		exact: map[string]*database.AccountMapping{},
+	}
+
+	for _, mapping := range mappings {
+		if _, ok := m.exact[mapping.Pattern]; !ok {
+			m.exact[mapping.Pattern] = mapping
+		}
+
+		if strings.ContainsAny(mapping.Pattern, "*?") {
+			m.wildcard = append(m.wildcard, &wildcardMapping{
+				mapping: mapping,
+				regex:   common.WildcardRegex(mapping.Pattern, false),
+			})
+		}
+	}
+
+	return m
+}
+
+// Match returns the first mapping equal to raw, otherwise the first wildcard mapping matching it.
+// A nil matcher has no mappings.
+func (m *Matcher) Match(raw string) (*database.AccountMapping, bool) {
+	if m == nil || raw == "" {
+		return nil, false
+	}
+
+	if mapping, ok := m.exact[raw]; ok {
+		return mapping, true
+	}
+
+	for _, item := range m.wildcard {
+		if item.regex.MatchString(raw) {
+			return item.mapping, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,88 @@
+package accountmap_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/accountmap"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+)
+
+func TestLoadFile(t *testing.T) {
+	for _, path := range []string{"testdata/mappings.yaml", "testdata/mappings.json"} {
+		t.Run(path, func(t *testing.T) {
+			mappings, err := accountmap.LoadFile(path)
+			assert.NoError(t, err)
+
+			if assert.Len(t, mappings, 2) {
+				assert.Equal(t, "5168**1234", mappings[0].Pattern)
+				assert.Equal(t, "12", mappings[0].FireflyAccountID)
+				assert.Equal(t, "revolut_*", mappings[1].Pattern)
+				assert.Equal(t, "15", mappings[1].FireflyAccountID)
+			}
+		})
+	}
+
+	_, err := accountmap.LoadFile("testdata/invalid.yaml")
+	assert.ErrorContains(t, err, "pattern and account are required")
+}
+
+func TestMatch(t *testing.T) {
+	mappings := []*database.AccountMapping{
+		{Pattern: "5168*", FireflyAccountID: "1"},
+		{Pattern: "5168**1234", FireflyAccountID: "2"},
+		{Pattern: "revolut_???", FireflyAccountID: "3"},
+		{Pattern: "a.b", FireflyAccountID: "4"},
+	}
+
+	matcher := accountmap.NewMatcher(mappings)
+
+	for raw, expected := range map[string]string{
+		"5168**1234":  "2", // exact wins over the earlier wildcard
+		"5168**9999":  "1",
+		"revolut_EUR": "3",
+		"a.b":         "4",
+	} {
+		mapping, ok := matcher.Match(raw)
+		if assert.True(t, ok, raw) {
+			assert.Equal(t, expected, mapping.FireflyAccountID, raw)
+		}
+	}
+
+	for _, raw := range []string{"", "revolut_EURO", "axb", "4168**1234"} {
+		_, ok := matcher.Match(raw)
+		assert.False(t, ok, raw)
+	}
+
+	_, ok := (*accountmap.Matcher)(nil).Match("revolut_EUR")
+	assert.False(t, ok)
+}
+
+func TestMapper(t *testing.T) {
+	ctx := context.TODO()
+
+	mapper := accountmap.NewMapper(repo.NewMemory(), []*database.AccountMapping{
+		{Pattern: "revolut_*", FireflyAccountID: "15"},
+	})
+
+	assert.NoError(t, mapper.Set(ctx, "revolut_*", "20"))
+
+	mappings, err := mapper.Mappings(ctx)
+	assert.NoError(t, err)
+
+	mapping, ok := accountmap.NewMatcher(mappings).Match("revolut_EUR")
+	if assert.True(t, ok) {
+		assert.Equal(t, "20", mapping.FireflyAccountID) // stored mappings take precedence over the file
+	}
+
+	assert.NoError(t, mapper.Delete(ctx, "revolut_*"))
+
+	mappings, err = mapper.Mappings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, mappings, 1)
+
+	assert.Error(t, accountmap.NewMapper(nil, nil).Set(ctx, "revolut_*", "20"))
+}
@@ -0,0 +1,13 @@
+package accountmap
+
+import (
+	"context"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+type Repo interface {
+	SaveAccountMapping(ctx context.Context, mapping *database.AccountMapping) error
+	GetAccountMappings(ctx context.Context) ([]*database.AccountMapping, error)
+	DeleteAccountMapping(ctx context.Context, pattern string) error
+}
@@ -0,0 +1,9 @@
+package accountmap
+
+import (
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+type File struct {
+	Mappings []*database.AccountMapping `json:"mappings" yaml:"mappings"`
+}
@@ -5,4 +5,7 @@ import "github.com/cockroachdb/errors"
 var (
 	ErrDuplicate             = errors.New("duplicate transaction")
 	ErrOperationNotSupported = errors.New("income transactions are not supported")
+	ErrProbableDuplicate     = errors.New("probable duplicate of existing firefly transaction")
+	ErrSkipped               = errors.New("skipped by user")
+	ErrPermissionDenied      = errors.New("permission denied")
 )
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalFile reads a json file into target, any other extension is parsed as yaml.
+// Kind names the file in parse errors, e.g. "rules".
+func UnmarshalFile(path string, kind string, target any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, target)
+	} else {
+		err = yaml.Unmarshal(data, target)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s file %s", kind, path)
+	}
+
+	return nil
+}
+
+// WildcardRegex compiles a pattern where * matches any text and ? a single character, the whole input has to match.
+func WildcardRegex(pattern string, ignoreCase bool) *regexp.Regexp {
+	var sb strings.Builder
+
+	if ignoreCase {
+		sb.WriteString("(?i)")
+	}
+
+	sb.WriteString("^")
+
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	sb.WriteString("$")
+
+	return regexp.MustCompile(sb.String())
+}
@@ -0,0 +1,47 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+)
+
+func TestUnmarshalFile(t *testing.T) {
+	type file struct {
+		Name string `json:"name" yaml:"name"`
+	}
+
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"config.json": `{"name": "json"}`,
+		"config.yaml": "name: yaml\n",
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		var target file
+		assert.NoError(t, common.UnmarshalFile(path, "test", &target))
+		assert.NotEmpty(t, target.Name, name)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	assert.NoError(t, os.WriteFile(invalid, []byte("{"), 0o600))
+
+	var target file
+	assert.ErrorContains(t, common.UnmarshalFile(invalid, "test", &target), "failed to parse test file")
+	assert.Error(t, common.UnmarshalFile(filepath.Join(dir, "missing.yaml"), "test", &target))
+}
+
+func TestWildcardRegex(t *testing.T) {
+	assert.True(t, common.WildcardRegex("revolut_???", false).MatchString("revolut_EUR"))
+	assert.False(t, common.WildcardRegex("revolut_???", false).MatchString("revolut_EURO"))
+	assert.True(t, common.WildcardRegex("a.b*", false).MatchString("a.bc"))
+	assert.False(t, common.WildcardRegex("a.b*", false).MatchString("axb"))
+	assert.False(t, common.WildcardRegex("silpo*", false).MatchString("SILPO 12"))
+	assert.True(t, common.WildcardRegex("silpo*", true).MatchString("SILPO 12"))
+}
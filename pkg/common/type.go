@@ -1,9 +1,84 @@
 package common
 
-import "github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+import (
+	"encoding/json"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+// Role grants access to bot commands, every role includes the permissions of the previous ones.
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // /stat, /dry, /errors, /duplicates, /history, /reconcile, listing mappings
+	RoleImporter Role = "importer" // uploads, /commit and interactive decisions
+	RoleAdmin    Role = "admin"    // /clear, /undo, mapping changes
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleImporter: 2,
+	RoleAdmin:    3,
+}
+
+// Allows reports whether the role includes the required one, empty and unknown roles allow nothing.
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] > 0 && roleLevels[r] >= roleLevels[required]
+}
 
 type ChatConfiguration struct {
-	Source          database.TransactionSource
-	SkipDuplicates  bool
-	SkipIncomeError bool
+	Source          database.TransactionSource `json:"source"`
+	SkipDuplicates  bool                       `json:"skipDuplicates"`
+	SkipIncomeError bool                       `json:"skipIncomeError"`
+	// Interactive makes /dry send every pending transaction with inline buttons.
+	Interactive bool `json:"interactive"`
+	// Transport is the chat platform, telegram when empty. Channel is the slack channel or matrix room id.
+	Transport string `json:"transport"`
+	Channel   string `json:"channel"`
+	// Currency of the account, used by sources whose statements do not carry it.
+	Currency string `json:"currency"`
+	// Users maps sender ids of the chat transport to roles, e.g. telegram user ids, slack user ids or
+	// matrix user ids. Users missing in the map can not run any command, every member of a chat
+	// without users has the admin role.
+	Users map[string]Role `json:"users"`
+}
+
+// UserRole returns the role of the sender.
+func (c ChatConfiguration) UserRole(userID string) Role {
+	if len(c.Users) == 0 {
+		return RoleAdmin
+	}
+
+	return c.Users[userID]
+}
+
+// UnmarshalJSON also accepts a plain source string, which is the legacy CHAT_MAP format.
+func (c *ChatConfiguration) UnmarshalJSON(data []byte) error {
+	var source string
+	if err := json.Unmarshal(data, &source); err == nil {
+		*c = ChatConfiguration{
+			Source: database.TransactionSource(source),
+		}
+
+		return nil
+	}
+
+	type plain ChatConfiguration
+
+	var cfg plain
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+
+	for userID, role := range cfg.Users {
+		if _, ok := roleLevels[role]; !ok {
+			return errors.Newf("unknown role %s of user %s", role, userID)
+		}
+	}
+
+	*c = ChatConfiguration(cfg)
+
+	return nil
 }
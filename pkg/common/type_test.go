@@ -0,0 +1,70 @@
+package common_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+func TestChatConfigurationUnmarshal(t *testing.T) {
+	var chatMap map[string]common.ChatConfiguration
+
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"111": "privatbank",
+		"222": {"source": "revolut", "skipDuplicates": true, "skipIncomeError": true},
+		"333": {"source": "paribas", "transport": "slack", "channel": "C123"},
+		"444": {"source": "mono", "users": {"1": "admin", "2": "viewer"}},
+		"555": {"source": "revolut", "transport": "slack", "channel": "C123", "users": {"U012AB3CD": "importer"}}
+	}`), &chatMap))
+
+	assert.Equal(t, common.ChatConfiguration{
+		Source: database.PrivatBank,
+	}, chatMap["111"])
+
+	assert.Equal(t, common.ChatConfiguration{
+		Source:          database.Revolut,
+		SkipDuplicates:  true,
+		SkipIncomeError: true,
+	}, chatMap["222"])
+
+	assert.Equal(t, common.ChatConfiguration{
+		Source:    database.Paribas,
+		Transport: "slack",
+		Channel:   "C123",
+	}, chatMap["333"])
+
+	assert.Equal(t, common.ChatConfiguration{
+		Source: database.Mono,
+		Users: map[string]common.Role{
+			"1": common.RoleAdmin,
+			"2": common.RoleViewer,
+		},
+	}, chatMap["444"])
+
+	assert.Equal(t, common.RoleImporter, chatMap["555"].UserRole("U012AB3CD"))
+}
+
+func TestChatConfigurationUnmarshalInvalid(t *testing.T) {
+	var cfg common.ChatConfiguration
+
+	assert.Error(t, json.Unmarshal([]byte(`123`), &cfg))
+	assert.Error(t, json.Unmarshal([]byte(`{"source": "mono", "users": {"1": "owner"}}`), &cfg))
+}
+
+func TestChatConfigurationUserRole(t *testing.T) {
+	open := common.ChatConfiguration{}
+	assert.Equal(t, common.RoleAdmin, open.UserRole("5"))
+
+	restricted := common.ChatConfiguration{
+		Users: map[string]common.Role{"1": common.RoleImporter},
+	}
+
+	assert.True(t, restricted.UserRole("1").Allows(common.RoleViewer))
+	assert.True(t, restricted.UserRole("1").Allows(common.RoleImporter))
+	assert.False(t, restricted.UserRole("1").Allows(common.RoleAdmin))
+	assert.False(t, restricted.UserRole("2").Allows(common.RoleViewer))
+}
@@ -0,0 +1,95 @@
+package counterparty
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+)
+
+var (
+	spacesRegex      = regexp.MustCompile(`\s+`)
+	storeNumberRegex = regexp.MustCompile(`[\s\-_#№/]+\d+$`) // "SILPO 123", "Silpo-45", "Zabka #12"
+)
+
+type compiledAlias struct {
+	name     string
+	patterns []*regexp.Regexp
+}
+
+// Normalizer turns raw counterparties into firefly expense/revenue account names.
+type Normalizer struct {
+	aliases []*compiledAlias
+}
+
+func NewNormalizer(aliases []*Alias) (*Normalizer, error) {
+	n := &Normalizer{}
+
+	for _, alias := range aliases {
+		if alias.Name == "" || len(alias.Patterns) == 0 {
+			return nil, errors.New("name and patterns are required for counterparty alias")
+		}
+
+		compiled := &compiledAlias{
+			name: alias.Name,
+		}
+
+		for _, pattern := range alias.Patterns {
+			compiled.patterns = append(compiled.patterns, common.WildcardRegex(pattern, true))
+		}
+
+		n.aliases = append(n.aliases, compiled)
+	}
+
+	return n, nil
+}
+
+// LoadFile reads aliases from a json file, any other extension is parsed as yaml.
+func LoadFile(path string) (*Normalizer, error) {
+	var file File
+
+	if err := common.UnmarshalFile(path, "counterparty alias", &file); err != nil {
+		return nil, err
+	}
+
+	normalizer, err := NewNormalizer(file.Aliases)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid counterparty alias file %s", path)
+	}
+
+	return normalizer, nil
+}
+
+// Normalize returns the name of the first alias matching the raw or the cleaned counterparty,
+// otherwise the cleaned one.
+func (n *Normalizer) Normalize(raw string) string {
+	cleaned := Clean(raw)
+	if cleaned == "" {
+		return ""
+	}
+
+	raw = strings.TrimSpace(spacesRegex.ReplaceAllString(raw, " "))
+
+	for _, alias := range n.aliases {
+		for _, pattern := range alias.patterns {
+			if pattern.MatchString(cleaned) || pattern.MatchString(raw) {
+				return alias.name
+			}
+		}
+	}
+
+	return cleaned
+}
+
+// Clean collapses whitespace and drops trailing store numbers, names consisting of digits only are kept.
+func Clean(raw string) string {
+	cleaned := strings.TrimSpace(spacesRegex.ReplaceAllString(raw, " "))
+
+	if stripped := storeNumberRegex.ReplaceAllString(cleaned, ""); stripped != "" {
+		cleaned = stripped
+	}
+
+	return strings.TrimRight(cleaned, " ,-")
+}
@@ -0,0 +1,46 @@
+package counterparty_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/counterparty"
+)
+
+func TestClean(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"SILPO 123":          "SILPO",
+		"Silpo-45":           "Silpo",
+		"  Zabka   #12 ":     "Zabka",
+		"ALLEGRO SP. Z O.O.": "ALLEGRO SP. Z O.O.",
+		"Pyszne.pl, Wroclaw": "Pyszne.pl, Wroclaw",
+		"Імя Фамілія\nПоБатькові": "Імя Фамілія ПоБатькові",
+		"12345": "12345",
+		"":      "",
+	} {
+		assert.Equal(t, expected, counterparty.Clean(raw), raw)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	for _, path := range []string{"testdata/aliases.yaml", "testdata/aliases.json"} {
+		t.Run(path, func(t *testing.T) {
+			normalizer, err := counterparty.LoadFile(path)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "Silpo", normalizer.Normalize("SILPO 123"))
+			assert.Equal(t, "Silpo", normalizer.Normalize("Silpo-45"))
+			assert.Equal(t, "Silpo", normalizer.Normalize("СІЛЬПО Київ"))
+			assert.Equal(t, "Bolt", normalizer.Normalize("Bolt.eu/o/2405"))
+			assert.Equal(t, "Bolt", normalizer.Normalize("BOLT"))
+			assert.Equal(t, "Steam", normalizer.Normalize("Steam"))
+			assert.Equal(t, "", normalizer.Normalize(" "))
+		})
+	}
+}
+
+func TestNewNormalizer(t *testing.T) {
+	_, err := counterparty.NewNormalizer([]*counterparty.Alias{{Name: "Silpo"}})
+	assert.ErrorContains(t, err, "name and patterns are required")
+}
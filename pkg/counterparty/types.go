@@ -0,0 +1,18 @@
+package counterparty
+
+// File is the alias configuration, e.g.
+//
+//	aliases:
+//	  - name: Silpo
+//	    patterns: ["silpo*", "сільпо*"]
+type File struct {
+	Aliases []*Alias `json:"aliases" yaml:"aliases"`
+}
+
+// Alias collapses merchant variants into one firefly account.
+type Alias struct {
+	Name string `json:"name" yaml:"name"`
+	// Patterns are matched case-insensitively against the raw and the cleaned counterparty,
+	// `*` matches any sequence and `?` a single character.
+	Patterns []string `json:"patterns" yaml:"patterns"`
+}
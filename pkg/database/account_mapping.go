@@ -0,0 +1,11 @@
+package database
+
+import "time"
+
+// AccountMapping maps a raw parser account, e.g. a masked card number, to a firefly account.
+type AccountMapping struct {
+	// Pattern is the raw account, `*` matches any sequence and `?` a single character.
+	Pattern          string    `json:"pattern" yaml:"pattern"`
+	FireflyAccountID string    `json:"fireflyAccountId" yaml:"account"`
+	CreatedAt        time.Time `json:"createdAt" yaml:"-"`
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type CommitStatus string
+
+const (
+	CommitStatusCommitted    = CommitStatus("committed")
+	CommitStatusFailed       = CommitStatus("failed")
+	CommitStatusAcknowledged = CommitStatus("acknowledged") // marked processed without sending to firefly
+	CommitStatusUndone       = CommitStatus("undone")
+)
+
+type CommitBatch struct {
+	ID                string                    `json:"id"`
+	TransactionSource TransactionSource         `json:"transactionSource"`
+	ChatID            int64                     `json:"chatId"`
+	CreatedAt         time.Time                 `json:"createdAt"`
+	UndoneAt          *time.Time                `json:"undoneAt"`
+	Transactions      []*CommitBatchTransaction `json:"transactions"`
+}
+
+type CommitBatchTransaction struct {
+	// FireflyID is empty for messages which were only acknowledged, e.g. duplicates.
+	FireflyID     string   `json:"fireflyId"`
+	MessageIDs    []string `json:"messageIds"`
+	DuplicateKeys []string `json:"duplicateKeys"`
+
+	Status         CommitStatus    `json:"status"`
+	Error          string          `json:"error,omitempty"`
+	Transaction    *Transaction    `json:"transaction,omitempty"`
+	FireflyPayload json.RawMessage `json:"fireflyPayload,omitempty"`
+}
@@ -0,0 +1,19 @@
+package database
+
+import "time"
+
+type DecisionAction string
+
+const (
+	DecisionActionNone      = DecisionAction("")
+	DecisionActionCommit    = DecisionAction("commit") // commit even when flagged as a probable duplicate
+	DecisionActionSkip      = DecisionAction("skip")
+	DecisionActionDuplicate = DecisionAction("duplicate")
+)
+
+// Decision is a per-transaction choice of the user, respected by /commit.
+type Decision struct {
+	Action       DecisionAction `json:"action,omitempty"`
+	CategoryName string         `json:"categoryName,omitempty"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
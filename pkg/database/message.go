@@ -17,6 +17,10 @@ type Message struct {
 	MessageID   int64      `json:"messageId"`
 
 	TransactionSource TransactionSource `json:"transactionSource"`
+	// Decisions made with telegram inline buttons, keyed by the transaction decision key.
+	Decisions map[string]*Decision `json:"decisions,omitempty"`
+	// Parsed is the stored parse result, commands re-parse only when it is missing or out of date.
+	Parsed *ParsedMessage `json:"parsed,omitempty"`
 }
 
 type Transaction struct {
@@ -44,9 +48,19 @@ type Transaction struct {
 
 	OriginalTxType      string
 	OriginalNadawcaName string
+	Counterparty        string // merchant or person on the other side, name of the firefly expense/revenue account
+	MCC                 string
+	Balance             *Balance
 	ParsingError        error `json:"-"`
 }
 
+// Balance is the account balance reported by the bank right after the transaction.
+type Balance struct {
+	Account  string
+	Amount   decimal.Decimal
+	Currency string
+}
+
 type TransactionType int32
 
 const (
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ParsedMessage is the stored outcome of parsing and mapping a pending message together with the other
+// pending messages of the source. It is reused by commands until Fingerprint changes or it expires.
+type ParsedMessage struct {
+	Fingerprint  string               `json:"fingerprint"`
+	ParsedAt     time.Time            `json:"parsedAt"`
+	Errors       []string             `json:"errors,omitempty"`
+	Transactions []*ParsedTransaction `json:"transactions,omitempty"`
+}
+
+// ParsedTransaction is a mapped transaction which originates from the message.
+type ParsedTransaction struct {
+	Position    int          `json:"position"` // among the transactions of all pending messages
+	Transaction *Transaction `json:"transaction"`
+	// DuplicateMessageIDs are the messages of Transaction.DuplicateTransactions, in the same order.
+	DuplicateMessageIDs []string        `json:"duplicateMessageIds,omitempty"`
+	FireflyPayload      json.RawMessage `json:"fireflyPayload,omitempty"`
+	Error               string          `json:"error,omitempty"`
+	// ErrorKinds name the well known errors matched by Error, e.g. an unsupported operation.
+	ErrorKinds        []string        `json:"errorKinds,omitempty"`
+	MatchedRules      []string        `json:"matchedRules,omitempty"`
+	ProbableDuplicate json.RawMessage `json:"probableDuplicate,omitempty"`
+}
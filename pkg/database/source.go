@@ -8,4 +8,7 @@ const (
 	Revolut    = TransactionSource("revolut")
 	Zen        = TransactionSource("zen")
 	Mono       = TransactionSource("mono")
+	Camt       = TransactionSource("camt")
+	MT940      = TransactionSource("mt940")
+	Ofx        = TransactionSource("ofx")
 )
@@ -66,6 +66,28 @@ func (d *DuplicateCleaner) AddDuplicateKey(
 	return d.repo.AddDuplicateKey(ctx, key, txSource)
 }
 
+func (d *DuplicateCleaner) RemoveDuplicateKeys(
+	ctx context.Context,
+	keys []string,
+	txSource database.TransactionSource,
+) error {
+	var hashedKeys []string
+
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		hashedKeys = append(hashedKeys, d.HashKey(key))
+	}
+
+	if len(hashedKeys) == 0 {
+		return nil
+	}
+
+	return d.repo.DeleteDuplicateKeys(ctx, hashedKeys, txSource)
+}
+
 func (d *DuplicateCleaner) HashKey(bv string) string {
 	shaImpl := sha512.New()
 	shaImpl.Write([]byte(bv))
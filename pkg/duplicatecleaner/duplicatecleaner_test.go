@@ -108,3 +108,28 @@ func TestAddDuplicateKey_RepoError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "repo error", err.Error())
 }
+
+func TestRemoveDuplicateKeys_Hashed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := NewMockRepo(ctrl)
+	duplicateCleaner := duplicatecleaner.NewDuplicateCleaner(mockRepo)
+
+	mockRepo.EXPECT().DeleteDuplicateKeys(gomock.Any(), []string{duplicateCleaner.HashKey("test-key")}, database.Zen).
+		Return(nil)
+
+	err := duplicateCleaner.RemoveDuplicateKeys(context.Background(), []string{"test-key", ""}, database.Zen)
+	assert.NoError(t, err)
+}
+
+func TestRemoveDuplicateKeys_Empty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := NewMockRepo(ctrl)
+	duplicateCleaner := duplicatecleaner.NewDuplicateCleaner(mockRepo)
+
+	err := duplicateCleaner.RemoveDuplicateKeys(context.Background(), []string{""}, database.Zen)
+	assert.NoError(t, err)
+}
@@ -11,4 +11,5 @@ import (
 type Repo interface {
 	GetDuplicates(ctx context.Context, key []string, source database.TransactionSource) ([]string, error)
 	AddDuplicateKey(ctx context.Context, key string, source database.TransactionSource) error
+	DeleteDuplicateKeys(ctx context.Context, keys []string, source database.TransactionSource) error
 }
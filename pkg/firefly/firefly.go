@@ -2,6 +2,8 @@ package firefly
 
 import (
 	"context"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -9,6 +11,8 @@ import (
 	"github.com/imroc/req/v3"
 	"github.com/shopspring/decimal"
 
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/accountmap"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/counterparty"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 )
 
@@ -17,6 +21,8 @@ type Firefly struct {
 	apiKey            string
 	fireflyURL        string
 	additionalHeaders map[string]string
+	accountMapper     AccountMapper
+	counterparties    CounterpartyNormalizer
 }
 
 func NewFirefly(
@@ -33,6 +39,25 @@ func NewFirefly(
 	}
 }
 
+// SetAccountMapper makes mappings take precedence over the account_number of firefly accounts.
+func (f *Firefly) SetAccountMapper(mapper AccountMapper) {
+	f.accountMapper = mapper
+}
+
+// SetCounterpartyNormalizer replaces the default clean up of counterparty names, e.g. with aliases.
+func (f *Firefly) SetCounterpartyNormalizer(normalizer CounterpartyNormalizer) {
+	f.counterparties = normalizer
+}
+
+// counterpartyName is the expense/revenue account firefly finds or creates for a counterparty.
+func (f *Firefly) counterpartyName(raw string) string {
+	if f.counterparties == nil {
+		return counterparty.Clean(raw)
+	}
+
+	return f.counterparties.Normalize(raw)
+}
+
 func (f *Firefly) getBaseRequest(ctx context.Context) *req.Request {
 	baseReq := f.cl.R().
 		SetContext(ctx).
@@ -64,16 +89,40 @@ func (f *Firefly) ListAccounts(ctx context.Context) ([]*Account, error) {
 	return apiResp.Data, nil
 }
 
-func (f *Firefly) MapTransactions(
-	ctx context.Context,
-	transactions []*database.Transaction,
-) ([]*MappedTransaction, error) {
+func (f *Firefly) ListCategories(ctx context.Context) ([]*Category, error) {
+	var apiResp GenericApiResponse[[]*Category]
+
+	resp, err := f.getBaseRequest(ctx).
+		SetSuccessResult(&apiResp).
+		SetHeader("Accept", "application/json").
+		SetQueryParam("limit", "100500").
+		Get(f.fireflyURL + "/api/v1/categories")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsErrorState() {
+		return nil, errors.Newf("got error response: %s", resp.String())
+	}
+
+	return apiResp.Data, nil
+}
+
+// accountsByNumber indexes accounts by every comma separated value of their account number.
+func (f *Firefly) accountsByNumber(ctx context.Context) (*accountIndex, error) {
 	accounts, err := f.ListAccounts(ctx)
 	if err != nil {
 		return nil, err
 	}
-	accountByAccountNumber := map[string]*Account{}
+
+	index := &accountIndex{
+		byNumber: map[string]*Account{},
+		byID:     map[string]*Account{},
+	}
+
 	for _, acc := range accounts {
+		index.byID[acc.Id] = acc
+
 		sp := strings.Split(acc.Attributes.AccountNumber, ",")
 		if len(sp) == 0 {
 			continue
@@ -85,15 +134,98 @@ func (f *Firefly) MapTransactions(
 				continue
 			}
 
-			_, ok := accountByAccountNumber[s]
+			_, ok := index.byNumber[s]
 			if ok {
 				return nil, errors.Newf("duplicate account number %s", s)
 			}
 
-			accountByAccountNumber[s] = acc
+			index.byNumber[s] = acc
 		}
 	}
 
+	if f.accountMapper != nil {
+		mappings, mappingsErr := f.accountMapper.Mappings(ctx)
+		if mappingsErr != nil {
+			return nil, errors.Wrapf(mappingsErr, "failed to get account mappings")
+		}
+
+		index.mappings = accountmap.NewMatcher(mappings)
+	}
+
+	return index, nil
+}
+
+type accountIndex struct {
+	byNumber map[string]*Account
+	byID     map[string]*Account
+	mappings *accountmap.Matcher
+}
+
+// find resolves a raw parser account, mappings take precedence over account numbers.
+func (i *accountIndex) find(raw string) (*Account, bool) {
+	if mapping, ok := i.mappings.Match(raw); ok {
+		acc, found := i.byID[mapping.FireflyAccountID]
+
+		return acc, found
+	}
+
+	acc, ok := i.byNumber[raw]
+
+	return acc, ok
+}
+
+// CheckBalances compares balances reported by the bank with the current balance of the matching firefly accounts.
+func (f *Firefly) CheckBalances(
+	ctx context.Context,
+	balances []*database.Balance,
+) ([]*BalanceCheck, error) {
+	accountIdx, err := f.accountsByNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []*BalanceCheck
+
+	for _, balance := range balances {
+		check := &BalanceCheck{
+			Balance: balance,
+		}
+		checks = append(checks, check)
+
+		acc, ok := accountIdx.find(balance.Account)
+		if !ok {
+			check.Error = errors.Newf("account with IBAN %s not found", balance.Account)
+			continue
+		}
+
+		check.AccountName = acc.Attributes.Name
+
+		if acc.Attributes.CurrencyCode != "" && acc.Attributes.CurrencyCode != balance.Currency {
+			check.Error = errors.Newf("currency mismatch: %s != %s", acc.Attributes.CurrencyCode, balance.Currency)
+			continue
+		}
+
+		fireflyBalance, parseErr := decimal.NewFromString(acc.Attributes.CurrentBalance)
+		if parseErr != nil {
+			check.Error = errors.Wrapf(parseErr, "failed to parse firefly balance %s", acc.Attributes.CurrentBalance)
+			continue
+		}
+
+		check.FireflyBalance = fireflyBalance
+	}
+
+	return checks, nil
+}
+
+func (f *Firefly) MapTransactions(
+	ctx context.Context,
+	transactions []*database.Transaction,
+) ([]*MappedTransaction, error) {
+	accountIdx, err := f.accountsByNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var finalTransactions []*MappedTransaction
 	for _, tx := range transactions {
 		mapped := &MappedTransaction{
@@ -110,7 +242,7 @@ func (f *Firefly) MapTransactions(
 		case database.TransactionTypeRemoteTransfer:
 			fallthrough
 		case database.TransactionTypeExpense:
-			acc, ok := accountByAccountNumber[tx.SourceAccount]
+			acc, ok := accountIdx.find(tx.SourceAccount)
 			if !ok {
 				mapped.Error = errors.Newf("account with IBAN %s not found", tx.SourceAccount)
 				continue
@@ -133,22 +265,26 @@ func (f *Firefly) MapTransactions(
 			}
 
 			if tx.DestinationAccount != "" {
-				if dst, dstOk := accountByAccountNumber[tx.DestinationAccount]; dstOk {
+				if dst, dstOk := accountIdx.find(tx.DestinationAccount); dstOk {
 					mapped.Transaction.DestinationID = dst.Id
 					mapped.Transaction.DestinationName = dst.Attributes.Name
 				}
 			}
+
+			if mapped.Transaction.DestinationID == "" {
+				mapped.Transaction.DestinationName = f.counterpartyName(tx.Counterparty)
+			}
 		case database.TransactionTypeInternalTransfer:
 			sourceID := tx.SourceAccount
 			destinationID := tx.DestinationAccount
 
-			accSource, ok := accountByAccountNumber[sourceID]
+			accSource, ok := accountIdx.find(sourceID)
 			if !ok {
 				mapped.Error = errors.Newf("source account with IBAN %s not found", sourceID)
 				continue
 			}
 
-			accDestination, ok := accountByAccountNumber[destinationID]
+			accDestination, ok := accountIdx.find(destinationID)
 			if !ok {
 				mapped.Error = errors.Newf("destination account with IBAN %s not found", destinationID)
 				continue
@@ -169,7 +305,7 @@ func (f *Firefly) MapTransactions(
 				ForeignCurrencyCode: tx.DestinationCurrency,
 			}
 		case database.TransactionTypeIncome:
-			acc, ok := accountByAccountNumber[tx.DestinationAccount]
+			acc, ok := accountIdx.find(tx.DestinationAccount)
 			if !ok {
 				mapped.Error = errors.Newf("account with IBAN %s not found", tx.DestinationAccount)
 				continue
@@ -187,13 +323,17 @@ func (f *Firefly) MapTransactions(
 			}
 
 			if tx.SourceAccount != "" {
-				if src, sourceOk := accountByAccountNumber[tx.SourceAccount]; sourceOk {
+				if src, sourceOk := accountIdx.find(tx.SourceAccount); sourceOk {
 					mapped.Transaction.SourceID = src.Id
 					mapped.Transaction.SourceName = src.Attributes.Name
 					mapped.Transaction.ForeignCurrencyCode = tx.SourceCurrency
 					mapped.Transaction.ForeignAmount = tx.SourceAmount.StringFixed(2)
 				}
 			}
+
+			if mapped.Transaction.SourceID == "" {
+				mapped.Transaction.SourceName = f.counterpartyName(tx.Counterparty)
+			}
 		default:
 			mapped.Error = errors.Newf("unknown transaction type %d", tx.Type)
 		}
@@ -206,8 +346,8 @@ func (f *Firefly) CreateTransactions(
 	ctx context.Context,
 	tx *Transaction,
 	errorOnDuplicate bool,
-) (*Transaction, error) {
-	var apiResp GenericApiResponse[Transaction]
+) (*TransactionGroup, error) {
+	var apiResp GenericApiResponse[TransactionGroup]
 
 	resp, err := f.getBaseRequest(ctx).
 		SetSuccessResult(&apiResp).
@@ -229,3 +369,63 @@ func (f *Firefly) CreateTransactions(
 
 	return &apiResp.Data, nil
 }
+
+func (f *Firefly) DeleteTransaction(
+	ctx context.Context,
+	id string,
+) error {
+	resp, err := f.getBaseRequest(ctx).
+		SetHeader("Accept", "application/json").
+		Delete(f.fireflyURL + "/api/v1/transactions/" + id)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound { // already removed in firefly
+		return nil
+	}
+
+	if resp.IsErrorState() {
+		return errors.Newf("got error response: %s", resp.String())
+	}
+
+	return nil
+}
+
+// ListAccountTransactions returns transaction groups of the account booked between start and end, both inclusive.
+func (f *Firefly) ListAccountTransactions(
+	ctx context.Context,
+	accountID string,
+	start time.Time,
+	end time.Time,
+) ([]*TransactionGroup, error) {
+	var groups []*TransactionGroup
+
+	for page := 1; ; page++ {
+		var apiResp PaginatedApiResponse[[]*TransactionGroup]
+
+		resp, err := f.getBaseRequest(ctx).
+			SetSuccessResult(&apiResp).
+			SetHeader("Accept", "application/json").
+			SetQueryParams(map[string]string{
+				"start": start.Format(time.DateOnly),
+				"end":   end.Format(time.DateOnly),
+				"limit": "500",
+				"page":  strconv.Itoa(page),
+			}).
+			Get(f.fireflyURL + "/api/v1/accounts/" + accountID + "/transactions")
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.IsErrorState() {
+			return nil, errors.Newf("got error response: %s", resp.String())
+		}
+
+		groups = append(groups, apiResp.Data...)
+
+		if page >= apiResp.Meta.Pagination.TotalPages {
+			return groups, nil
+		}
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/imroc/req/v3"
 	"github.com/jarcoal/httpmock"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/counterparty"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
 )
 
@@ -62,3 +66,288 @@ func TestFirefly(t *testing.T) {
 	assert.Equal(t, "2", resp[1].Id)
 	assert.Equal(t, "test-account-2", resp[1].Attributes.Name)
 }
+
+func TestCreateTransactions(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	ff := firefly.NewFirefly("test-api-key", "https://example.com", cl, nil)
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://example.com/api/v1/transactions",
+		httpmock.NewStringResponder(200, `{"data":{"type":"transactions","id":"42","attributes":{"transactions":[{"description":"test"}]}}}`),
+	)
+
+	resp, err := ff.CreateTransactions(context.TODO(), &firefly.Transaction{Description: "test"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", resp.Id)
+
+	if assert.Len(t, resp.Attributes.Transactions, 1) {
+		assert.Equal(t, "test", resp.Attributes.Transactions[0].Description)
+	}
+}
+
+func TestDeleteTransaction(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	ff := firefly.NewFirefly("test-api-key", "https://example.com", cl, nil)
+
+	httpmock.RegisterResponder(
+		"DELETE",
+		"https://example.com/api/v1/transactions/42",
+		httpmock.NewStringResponder(204, ""),
+	)
+	httpmock.RegisterResponder(
+		"DELETE",
+		"https://example.com/api/v1/transactions/43",
+		httpmock.NewStringResponder(404, `{"message":"Resource not found"}`),
+	)
+	httpmock.RegisterResponder(
+		"DELETE",
+		"https://example.com/api/v1/transactions/44",
+		httpmock.NewStringResponder(500, `{"message":"boom"}`),
+	)
+
+	assert.NoError(t, ff.DeleteTransaction(context.TODO(), "42"))
+	assert.NoError(t, ff.DeleteTransaction(context.TODO(), "43"))
+	assert.Error(t, ff.DeleteTransaction(context.TODO(), "44"))
+}
+
+func TestCheckBalances(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	ff := firefly.NewFirefly("test-api-key", "https://example.com", cl, nil)
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://example.com/api/v1/accounts",
+		httpmock.NewStringResponder(200, `{"data":[
+			{"id":"1","attributes":{"name":"Privat USD","currency_code":"USD","account_number":"4*67, 4*68","current_balance":"10.50"}},
+			{"id":"2","attributes":{"name":"Mono","currency_code":"UAH","account_number":"mono_UAH","current_balance":"100.00"}}
+		]}`),
+	)
+
+	resp, err := ff.CheckBalances(context.TODO(), []*database.Balance{
+		{Account: "4*68", Amount: decimal.RequireFromString("10.50"), Currency: "USD"},
+		{Account: "mono_UAH", Amount: decimal.RequireFromString("120.00"), Currency: "UAH"},
+		{Account: "mono_USD", Amount: decimal.RequireFromString("1"), Currency: "USD"},
+		{Account: "4*67", Amount: decimal.RequireFromString("1"), Currency: "EUR"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 4)
+
+	assert.NoError(t, resp[0].Error)
+	assert.Equal(t, "Privat USD", resp[0].AccountName)
+	assert.True(t, resp[0].Drift().IsZero())
+
+	assert.NoError(t, resp[1].Error)
+	assert.Equal(t, "-20.00", resp[1].Drift().StringFixed(2))
+
+	assert.ErrorContains(t, resp[2].Error, "account with IBAN mono_USD not found")
+	assert.ErrorContains(t, resp[3].Error, "currency mismatch: USD != EUR")
+}
+
+type staticMapper []*database.AccountMapping
+
+func (s staticMapper) Mappings(_ context.Context) ([]*database.AccountMapping, error) {
+	return s, nil
+}
+
+func TestMapTransactionsWithAccountMapper(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	ff := firefly.NewFirefly("test-api-key", "https://example.com", cl, nil)
+	ff.SetAccountMapper(staticMapper{
+		{Pattern: "5168**1234", FireflyAccountID: "1"},
+		{Pattern: "revolut_*", FireflyAccountID: "2"},
+		{Pattern: "mono_UAH", FireflyAccountID: "404"},
+	})
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://example.com/api/v1/accounts",
+		httpmock.NewStringResponder(200, `{"data":[
+			{"id":"1","attributes":{"name":"Privat card","account_number":"UA123"}},
+			{"id":"2","attributes":{"name":"Revolut"}},
+			{"id":"3","attributes":{"name":"Mono","account_number":"mono_UAH"}}
+		]}`),
+	)
+
+	resp, err := ff.MapTransactions(context.TODO(), []*database.Transaction{
+		{
+			Type:               database.TransactionTypeInternalTransfer,
+			SourceAccount:      "5168**1234",
+			DestinationAccount: "revolut_EUR",
+			SourceAmount:       decimal.RequireFromString("10"),
+			DestinationAmount:  decimal.RequireFromString("10"),
+		},
+		{
+			Type:          database.TransactionTypeExpense,
+			SourceAccount: "UA123",
+			SourceAmount:  decimal.RequireFromString("5"),
+		},
+		{
+			Type:          database.TransactionTypeExpense,
+			SourceAccount: "mono_UAH", // mapping wins over the account number
+			SourceAmount:  decimal.RequireFromString("5"),
+		},
+	})
+	assert.NoError(t, err)
+
+	if assert.Len(t, resp, 3) {
+		assert.NoError(t, resp[0].Error)
+		assert.Equal(t, "1", resp[0].Transaction.SourceID)
+		assert.Equal(t, "2", resp[0].Transaction.DestinationID)
+
+		assert.NoError(t, resp[1].Error)
+		assert.Equal(t, "1", resp[1].Transaction.SourceID)
+
+		assert.ErrorContains(t, resp[2].Error, "account with IBAN mono_UAH not found")
+	}
+}
+
+func TestMapTransactionsCounterparty(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	ff := firefly.NewFirefly("test-api-key", "https://example.com", cl, nil)
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://example.com/api/v1/accounts",
+		httpmock.NewStringResponder(200, `{"data":[
+			{"id":"1","attributes":{"name":"Privat","account_number":"4*71"}},
+			{"id":"2","attributes":{"name":"Savings","account_number":"UA2"}}
+		]}`),
+	)
+
+	transactions := []*database.Transaction{
+		{
+			Type:          database.TransactionTypeExpense,
+			SourceAccount: "4*71",
+			SourceAmount:  decimal.RequireFromString("10"),
+			Counterparty:  "SILPO 123",
+		},
+		{
+			Type:               database.TransactionTypeRemoteTransfer,
+			SourceAccount:      "4*71",
+			DestinationAccount: "UA2", // own account wins over the counterparty
+			SourceAmount:       decimal.RequireFromString("10"),
+			Counterparty:       "Me",
+		},
+		{
+			Type:               database.TransactionTypeIncome,
+			DestinationAccount: "4*71",
+			DestinationAmount:  decimal.RequireFromString("10"),
+			Counterparty:       "Somecompany SLU",
+		},
+	}
+
+	resp, err := ff.MapTransactions(context.TODO(), transactions)
+	assert.NoError(t, err)
+
+	if assert.Len(t, resp, 3) {
+		assert.Equal(t, "SILPO", resp[0].Transaction.DestinationName)
+		assert.Empty(t, resp[0].Transaction.DestinationID)
+
+		assert.Equal(t, "Savings", resp[1].Transaction.DestinationName)
+		assert.Equal(t, "2", resp[1].Transaction.DestinationID)
+
+		assert.Equal(t, "Somecompany SLU", resp[2].Transaction.SourceName)
+		assert.Empty(t, resp[2].Transaction.SourceID)
+	}
+
+	normalizer, err := counterparty.NewNormalizer([]*counterparty.Alias{
+		{Name: "Silpo", Patterns: []string{"silpo*"}},
+	})
+	assert.NoError(t, err)
+
+	ff.SetCounterpartyNormalizer(normalizer)
+
+	resp, err = ff.MapTransactions(context.TODO(), transactions[:1])
+	assert.NoError(t, err)
+
+	if assert.Len(t, resp, 1) {
+		assert.Equal(t, "Silpo", resp[0].Transaction.DestinationName)
+	}
+}
+
+func TestListAccountTransactions(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	ff := firefly.NewFirefly("test-api-key", "https://example.com", cl, nil)
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://example.com/api/v1/accounts/1/transactions",
+		func(request *http.Request) (*http.Response, error) {
+			assert.Equal(t, "2024-05-01", request.URL.Query().Get("start"))
+			assert.Equal(t, "2024-05-31", request.URL.Query().Get("end"))
+
+			if request.URL.Query().Get("page") == "1" {
+				return httpmock.NewStringResponse(200, `{"data":[
+					{"id":"10","attributes":{"transactions":[{"transaction_journal_id":"20","description":"Coffee","amount":"3.50"}]}}
+				],"meta":{"pagination":{"current_page":1,"total_pages":2}}}`), nil
+			}
+
+			return httpmock.NewStringResponse(200, `{"data":[
+				{"id":"11","attributes":{"transactions":[{"transaction_journal_id":"21","description":"Rent","amount":"800.00"}]}}
+			],"meta":{"pagination":{"current_page":2,"total_pages":2}}}`), nil
+		},
+	)
+	httpmock.RegisterResponder(
+		"GET",
+		"https://example.com/api/v1/accounts/2/transactions",
+		httpmock.NewStringResponder(404, `{"message":"Resource not found"}`),
+	)
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+
+	resp, err := ff.ListAccountTransactions(context.TODO(), "1", start, end)
+	assert.NoError(t, err)
+
+	if assert.Len(t, resp, 2) {
+		assert.Equal(t, "10", resp[0].Id)
+		assert.Equal(t, "20", resp[0].Attributes.Transactions[0].JournalID)
+		assert.Equal(t, "11", resp[1].Id)
+		assert.Equal(t, "Rent", resp[1].Attributes.Transactions[0].Description)
+	}
+
+	_, err = ff.ListAccountTransactions(context.TODO(), "2", start, end)
+	assert.Error(t, err)
+}
+
+func TestListCategories(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	ff := firefly.NewFirefly("test-api-key", "https://example.com", cl, nil)
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://example.com/api/v1/categories",
+		httpmock.NewStringResponder(200, `{"data":[{"id":"1","attributes":{"name":"Groceries"}},{"id":"2","attributes":{"name":"Travel"}}]}`),
+	)
+
+	resp, err := ff.ListCategories(context.TODO())
+	assert.NoError(t, err)
+
+	if assert.Len(t, resp, 2) {
+		assert.Equal(t, "1", resp[0].Id)
+		assert.Equal(t, "Groceries", resp[0].Attributes.Name)
+		assert.Equal(t, "Travel", resp[1].Attributes.Name)
+	}
+}
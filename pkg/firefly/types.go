@@ -1,44 +1,125 @@
 package firefly
 
-import "github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
 
 type GenericApiResponse[T any] struct {
 	Data T `json:"data"`
 }
 
+type PaginatedApiResponse[T any] struct {
+	Data T    `json:"data"`
+	Meta Meta `json:"meta"`
+}
+
+type Meta struct {
+	Pagination Pagination `json:"pagination"`
+}
+
+type Pagination struct {
+	CurrentPage int `json:"current_page"`
+	TotalPages  int `json:"total_pages"`
+}
+
+type Category struct {
+	Id         string             `json:"id"`
+	Attributes CategoryAttributes `json:"attributes"`
+}
+
+type CategoryAttributes struct {
+	Name string `json:"name"`
+}
+
 type Account struct {
 	Id         string            `json:"id"`
 	Attributes AccountAttributes `json:"attributes"`
 }
 
 type AccountAttributes struct {
-	Name          string `json:"name"`
-	Type          string `json:"type"`
-	CurrencyCode  string `json:"currency_code"`
-	Iban          string `json:"iban"`
-	Bic           string `json:"bic"`
-	AccountNumber string `json:"account_number"`
-	Active        bool   `json:"active"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	CurrencyCode   string `json:"currency_code"`
+	Iban           string `json:"iban"`
+	Bic            string `json:"bic"`
+	AccountNumber  string `json:"account_number"`
+	Active         bool   `json:"active"`
+	CurrentBalance string `json:"current_balance"`
+}
+
+// BalanceCheck is a bank reported balance next to the firefly balance of the same account.
+type BalanceCheck struct {
+	Balance        *database.Balance
+	AccountName    string
+	FireflyBalance decimal.Decimal
+	Error          error
+}
+
+// Drift is the amount firefly is off by, positive when firefly shows more than the bank.
+func (b *BalanceCheck) Drift() decimal.Decimal {
+	return b.FireflyBalance.Sub(b.Balance.Amount)
 }
 
 type MappedTransaction struct {
-	Original    *database.Transaction
-	Transaction *Transaction
-	Error       error
-	IsCommitted bool
+	Original     *database.Transaction
+	Transaction  *Transaction
+	Error        error
+	IsCommitted  bool
+	MatchedRules []string
+	FireflyID    string
+	// ProbableDuplicate is an existing firefly transaction which looks like this one.
+	ProbableDuplicate *ProbableDuplicate
+	// DecisionKey identifies the transaction in inline button callbacks.
+	DecisionKey string
+	Decision    *database.Decision
+}
+
+type ProbableDuplicate struct {
+	FireflyID   string
+	Date        time.Time
+	Amount      string
+	Description string
+	Confidence  float64
+}
+
+type TransactionGroup struct {
+	Id         string                     `json:"id"`
+	Attributes TransactionGroupAttributes `json:"attributes"`
+}
+
+type TransactionGroupAttributes struct {
+	Transactions []*Transaction `json:"transactions"`
 }
 
 type Transaction struct {
+	JournalID           string `json:"transaction_journal_id,omitempty"`
 	Type                string `json:"type"`
 	Date                string `json:"date"`
 	Amount              string `json:"amount"`
 	Description         string `json:"description"`
 	CurrencyCode        string `json:"currency_code"`
 	SourceID            string `json:"source_id"`
-	SourceName          string `json:"-"`
+	SourceName          string `json:"source_name,omitempty"`
 	DestinationID       string `json:"destination_id,omitempty"`
-	DestinationName     string `json:"-"`
+	DestinationName     string `json:"destination_name,omitempty"`
 	Notes               string `json:"notes"`
 	ForeignAmount       string `json:"foreign_amount,omitempty"`
 	ForeignCurrencyCode string `json:"foreign_currency_code,omitempty"`
+
+	CategoryName string   `json:"category_name,omitempty"`
+	BudgetName   string   `json:"budget_name,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+type AccountMapper interface {
+	Mappings(ctx context.Context) ([]*database.AccountMapping, error)
+}
+
+type CounterpartyNormalizer interface {
+	Normalize(raw string) string
 }
@@ -0,0 +1,232 @@
+package notifications
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"github.com/imroc/req/v3"
+)
+
+// matrixMaxEvents bounds the remembered events, reactions to older messages fail and their redeliveries
+// are processed again.
+const matrixMaxEvents = 10000
+
+type matrixReplyKey struct{}
+
+// matrixReply numbers the events sent while a received event is processed.
+type matrixReply struct {
+	eventID string
+	sent    atomic.Int64
+}
+
+// WithMatrixEvent marks events sent with the context as replies to the received event. Their transaction ids
+// are derived from the event, the homeserver drops replies sent again for a redelivered event.
+func WithMatrixEvent(ctx context.Context, eventID string) context.Context {
+	return context.WithValue(ctx, matrixReplyKey{}, &matrixReply{eventID: eventID})
+}
+
+// Matrix sends replies with the client-server api, chats are mapped to room ids.
+type Matrix struct {
+	client        *req.Client
+	homeserverURL string
+	accessToken   string
+	rooms         map[int64]string
+
+	mut         sync.Mutex
+	events      map[int64]string // message id => event id of received messages, needed for reactions
+	eventsOrder []int64          // message ids in the order they were received, oldest are evicted first
+}
+
+func NewMatrix(
+	homeserverURL string,
+	accessToken string,
+	cl *req.Client,
+	rooms map[int64]string,
+) *Matrix {
+	return &Matrix{
+		client:        cl,
+		homeserverURL: strings.TrimSuffix(homeserverURL, "/"),
+		accessToken:   accessToken,
+		rooms:         rooms,
+		events:        map[int64]string{},
+	}
+}
+
+// MessageID remembers a received event and returns the message id used by the processor, isNew is false
+// when the event was received before. Event ids are kept in memory only, reactions to messages received
+// before a restart or more than matrixMaxEvents messages ago fail.
+func (m *Matrix) MessageID(eventID string) (int64, bool) {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(eventID))
+
+	id := int64(hash.Sum64() & math.MaxInt64)
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if _, ok := m.events[id]; ok {
+		return id, false
+	}
+
+	m.events[id] = eventID
+	m.eventsOrder = append(m.eventsOrder, id)
+
+	if len(m.eventsOrder) > matrixMaxEvents {
+		delete(m.events, m.eventsOrder[0])
+		m.eventsOrder = m.eventsOrder[1:]
+	}
+
+	return id, true
+}
+
+// txnID returns the transaction id of a sent event, replies to a received event get the same ids when
+// the event is processed again.
+func (m *Matrix) txnID(ctx context.Context) string {
+	reply, ok := ctx.Value(matrixReplyKey{}).(*matrixReply)
+	if !ok {
+		return uuid.NewString()
+	}
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", reply.eventID, reply.sent.Add(1))))
+
+	return hex.EncodeToString(hash[:16])
+}
+
+func (m *Matrix) room(chatID int64) (string, error) {
+	room, ok := m.rooms[chatID]
+	if !ok {
+		return "", errors.Newf("matrix room for chat %d is not configured", chatID)
+	}
+
+	return room, nil
+}
+
+func (m *Matrix) send(ctx context.Context, room string, eventType string, content map[string]interface{}) error {
+	resp, err := m.client.R().
+		SetContext(ctx).
+		SetBearerAuthToken(m.accessToken).
+		SetBody(content).
+		Put(fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/%s/%s",
+			m.homeserverURL, url.PathEscape(room), eventType, m.txnID(ctx)))
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return nil
+}
+
+func (m *Matrix) SendMessage(ctx context.Context, chatID int64, raw string) error {
+	room, err := m.room(chatID)
+	if err != nil {
+		return err
+	}
+
+	for _, text := range splitText(raw, 16000, false) { // events are limited to 64KiB
+		if err = m.send(ctx, room, "m.room.message", map[string]interface{}{
+			"msgtype": "m.text",
+			"body":    text,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Matrix) React(ctx context.Context, chatID int64, messageID int64, reaction string) error {
+	room, err := m.room(chatID)
+	if err != nil {
+		return err
+	}
+
+	m.mut.Lock()
+	eventID, ok := m.events[messageID]
+	m.mut.Unlock()
+
+	if !ok {
+		return errors.Newf("matrix event of message %d is unknown", messageID)
+	}
+
+	return m.send(ctx, room, "m.reaction", map[string]interface{}{
+		"m.relates_to": map[string]interface{}{
+			"rel_type": "m.annotation",
+			"event_id": eventID,
+			"key":      reaction,
+		},
+	})
+}
+
+// SendDocument uploads the document to the media repository and sends it as a file with the caption.
+func (m *Matrix) SendDocument(ctx context.Context, chatID int64, document Document, caption string) error {
+	room, err := m.room(chatID)
+	if err != nil {
+		return err
+	}
+
+	var upload struct {
+		ContentURI string `json:"content_uri"`
+	}
+
+	resp, err := m.client.R().
+		SetContext(ctx).
+		SetBearerAuthToken(m.accessToken).
+		SetContentType(document.MimeType).
+		SetQueryParam("filename", document.Name).
+		SetBodyBytes(document.Data).
+		SetSuccessResult(&upload).
+		Post(m.homeserverURL + "/_matrix/media/v3/upload")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return m.send(ctx, room, "m.room.message", map[string]interface{}{
+		"msgtype":  "m.file",
+		"body":     caption,
+		"filename": document.Name,
+		"url":      upload.ContentURI,
+		"info": map[string]interface{}{
+			"mimetype": document.MimeType,
+			"size":     len(document.Data),
+		},
+	})
+}
+
+// GetFile downloads a mxc://<server>/<media id> content uri with authenticated media.
+func (m *Matrix) GetFile(ctx context.Context, fileID string) ([]byte, error) {
+	media, ok := strings.CutPrefix(fileID, "mxc://")
+	if !ok {
+		return nil, errors.Newf("invalid matrix content uri %s", fileID)
+	}
+
+	resp, err := m.client.R().
+		SetContext(ctx).
+		SetBearerAuthToken(m.accessToken).
+		Get(m.homeserverURL + "/_matrix/client/v1/media/download/" + media)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsErrorState() {
+		return nil, fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return resp.Bytes(), nil
+}
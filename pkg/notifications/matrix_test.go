@@ -0,0 +1,127 @@
+package notifications_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+)
+
+func TestMatrixSendMessageAndReact(t *testing.T) {
+	var events []map[string]interface{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "Bearer syt_123", r.Header.Get("Authorization"))
+		assert.True(t, strings.HasPrefix(r.URL.EscapedPath(), "/_matrix/client/v3/rooms/%21room:example.org/send/"))
+
+		var content map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&content))
+		events = append(events, content)
+
+		_, _ = w.Write([]byte(`{"event_id":"$reply"}`))
+	}))
+	defer srv.Close()
+
+	matrix := notifications.NewMatrix(srv.URL, "syt_123", req.C(), map[int64]string{1: "!room:example.org"})
+
+	assert.NoError(t, matrix.SendMessage(context.TODO(), 1, "test"))
+
+	messageID, isNew := matrix.MessageID("$event")
+	assert.Positive(t, messageID)
+	assert.True(t, isNew)
+
+	again, isNew := matrix.MessageID("$event")
+	assert.Equal(t, messageID, again)
+	assert.False(t, isNew)
+
+	assert.NoError(t, matrix.React(context.TODO(), 1, messageID, "🤝"))
+	assert.ErrorContains(t, matrix.React(context.TODO(), 1, 42, "🤝"), "matrix event of message 42 is unknown")
+
+	assert.Equal(t, []map[string]interface{}{
+		{"msgtype": "m.text", "body": "test"},
+		{"m.relates_to": map[string]interface{}{"rel_type": "m.annotation", "event_id": "$event", "key": "🤝"}},
+	}, events)
+}
+
+func TestMatrixGetFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_matrix/client/v1/media/download/example.org/abc", r.URL.Path)
+
+		_, _ = w.Write([]byte(`file content`))
+	}))
+	defer srv.Close()
+
+	matrix := notifications.NewMatrix(srv.URL, "syt_123", req.C(), nil)
+
+	resp, err := matrix.GetFile(context.TODO(), "mxc://example.org/abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "file content", string(resp))
+
+	_, err = matrix.GetFile(context.TODO(), "https://example.org/abc")
+	assert.ErrorContains(t, err, "invalid matrix content uri")
+}
+
+func TestMatrixEventsBounded(t *testing.T) {
+	var reactions int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reactions++
+		_, _ = w.Write([]byte(`{"event_id":"$reply"}`))
+	}))
+	defer srv.Close()
+
+	matrix := notifications.NewMatrix(srv.URL, "syt_123", req.C(), map[int64]string{1: "!room:example.org"})
+
+	first, _ := matrix.MessageID("$event0")
+	for i := 1; i < 10000; i++ {
+		matrix.MessageID(fmt.Sprintf("$event%d", i))
+	}
+	last, _ := matrix.MessageID("$event10000")
+
+	assert.ErrorContains(t, matrix.React(context.TODO(), 1, first, "🤝"), "is unknown")
+	assert.NoError(t, matrix.React(context.TODO(), 1, last, "🤝"))
+	assert.Equal(t, 1, reactions)
+}
+
+func TestMatrixTxnID(t *testing.T) {
+	var paths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = w.Write([]byte(`{"event_id":"$reply"}`))
+	}))
+	defer srv.Close()
+
+	matrix := notifications.NewMatrix(srv.URL, "syt_123", req.C(), map[int64]string{1: "!room:example.org"})
+
+	reply := func(eventID string) {
+		ctx := notifications.WithMatrixEvent(context.TODO(), eventID)
+
+		assert.NoError(t, matrix.SendMessage(ctx, 1, "first"))
+		assert.NoError(t, matrix.SendMessage(ctx, 1, "first"))
+	}
+
+	reply("$event")
+	reply("$event") // redelivered
+	reply("$other")
+
+	assert.NoError(t, matrix.SendMessage(context.TODO(), 1, "first"))
+	assert.NoError(t, matrix.SendMessage(context.TODO(), 1, "first"))
+
+	if assert.Len(t, paths, 8) {
+		assert.NotEqual(t, paths[0], paths[1]) // same text sent twice is not dropped
+		assert.Equal(t, paths[:2], paths[2:4])
+		assert.NotContains(t, paths[4:6], paths[0])
+		assert.NotEqual(t, paths[6], paths[7])
+		assert.NotContains(t, paths[:6], paths[6])
+	}
+}
@@ -0,0 +1,153 @@
+package notifications
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	TransportTelegram = "telegram"
+	TransportSlack    = "slack"
+	TransportMatrix   = "matrix"
+	TransportWebhook  = "webhook"
+)
+
+// Transport delivers replies and status markers to one chat platform and downloads files sent there.
+type Transport interface {
+	SendMessage(
+		ctx context.Context,
+		chatID int64,
+		text string,
+	) error
+
+	React(
+		ctx context.Context,
+		chatID int64,
+		messageID int64,
+		reaction string,
+	) error
+
+	GetFile(ctx context.Context, fileID string) ([]byte, error)
+
+	SendDocument(
+		ctx context.Context,
+		chatID int64,
+		document Document,
+		caption string,
+	) error
+}
+
+// InteractiveTransport supports inline buttons, transports without them get plain messages instead.
+type InteractiveTransport interface {
+	Transport
+
+	SendKeyboard(
+		ctx context.Context,
+		chatID int64,
+		text string,
+		keyboard Keyboard,
+	) error
+
+	EditMessage(
+		ctx context.Context,
+		chatID int64,
+		messageID int64,
+		text string,
+		keyboard Keyboard,
+	) error
+
+	AnswerCallback(
+		ctx context.Context,
+		callbackID string,
+		text string,
+	) error
+}
+
+// Router drives the processor from several transports. Chats are routed by id, files by the transport
+// prefix of the file id, e.g. "slack:https://files.slack.com/...". Everything else goes to the fallback.
+type Router struct {
+	fallback Transport
+	byName   map[string]Transport
+	byChat   map[int64]Transport
+}
+
+func NewRouter(fallback Transport) *Router {
+	return &Router{
+		fallback: fallback,
+		byName:   map[string]Transport{},
+		byChat:   map[int64]Transport{},
+	}
+}
+
+// Register routes the chats and the files prefixed with name to the transport.
+func (r *Router) Register(name string, transport Transport, chatIDs ...int64) {
+	r.byName[name] = transport
+
+	for _, chatID := range chatIDs {
+		r.byChat[chatID] = transport
+	}
+}
+
+// FileID prefixes the transport specific file id, so GetFile can be routed without knowing the chat.
+func FileID(transport string, id string) string {
+	return transport + ":" + id
+}
+
+func (r *Router) chat(chatID int64) Transport {
+	if transport, ok := r.byChat[chatID]; ok {
+		return transport
+	}
+
+	return r.fallback
+}
+
+func (r *Router) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return r.chat(chatID).SendMessage(ctx, chatID, text)
+}
+
+func (r *Router) React(ctx context.Context, chatID int64, messageID int64, reaction string) error {
+	return r.chat(chatID).React(ctx, chatID, messageID, reaction)
+}
+
+func (r *Router) SendDocument(ctx context.Context, chatID int64, document Document, caption string) error {
+	return r.chat(chatID).SendDocument(ctx, chatID, document, caption)
+}
+
+func (r *Router) GetFile(ctx context.Context, fileID string) ([]byte, error) {
+	if name, id, ok := strings.Cut(fileID, ":"); ok {
+		if transport, found := r.byName[name]; found {
+			return transport.GetFile(ctx, id)
+		}
+	}
+
+	return r.fallback.GetFile(ctx, fileID) // telegram file ids are not prefixed
+}
+
+func (r *Router) SendKeyboard(ctx context.Context, chatID int64, text string, keyboard Keyboard) error {
+	transport := r.chat(chatID)
+
+	if interactive, ok := transport.(InteractiveTransport); ok {
+		return interactive.SendKeyboard(ctx, chatID, text, keyboard)
+	}
+
+	return transport.SendMessage(ctx, chatID, text)
+}
+
+func (r *Router) EditMessage(ctx context.Context, chatID int64, messageID int64, text string, keyboard Keyboard) error {
+	transport := r.chat(chatID)
+
+	if interactive, ok := transport.(InteractiveTransport); ok {
+		return interactive.EditMessage(ctx, chatID, messageID, text, keyboard)
+	}
+
+	return transport.SendMessage(ctx, chatID, text)
+}
+
+// AnswerCallback is routed to the fallback, only interactive transports produce callbacks.
+func (r *Router) AnswerCallback(ctx context.Context, callbackID string, text string) error {
+	if interactive, ok := r.fallback.(InteractiveTransport); ok {
+		return interactive.AnswerCallback(ctx, callbackID, text)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package notifications_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+)
+
+type recordingTransport struct {
+	name  string
+	calls []string
+}
+
+func (r *recordingTransport) SendMessage(_ context.Context, chatID int64, text string) error {
+	r.calls = append(r.calls, fmt.Sprintf("send %d %s", chatID, text))
+	return nil
+}
+
+func (r *recordingTransport) React(_ context.Context, chatID int64, messageID int64, reaction string) error {
+	r.calls = append(r.calls, fmt.Sprintf("react %d %d %s", chatID, messageID, reaction))
+	return nil
+}
+
+func (r *recordingTransport) SendDocument(_ context.Context, chatID int64, document notifications.Document, caption string) error {
+	r.calls = append(r.calls, fmt.Sprintf("document %d %s %s", chatID, document.Name, caption))
+	return nil
+}
+
+func (r *recordingTransport) GetFile(_ context.Context, fileID string) ([]byte, error) {
+	return []byte(r.name + " " + fileID), nil
+}
+
+type interactiveTransport struct {
+	recordingTransport
+}
+
+func (r *interactiveTransport) SendKeyboard(_ context.Context, chatID int64, text string, _ notifications.Keyboard) error {
+	r.calls = append(r.calls, fmt.Sprintf("keyboard %d %s", chatID, text))
+	return nil
+}
+
+func (r *interactiveTransport) EditMessage(_ context.Context, chatID int64, messageID int64, text string, _ notifications.Keyboard) error {
+	r.calls = append(r.calls, fmt.Sprintf("edit %d %d %s", chatID, messageID, text))
+	return nil
+}
+
+func (r *interactiveTransport) AnswerCallback(_ context.Context, callbackID string, text string) error {
+	r.calls = append(r.calls, fmt.Sprintf("answer %s %s", callbackID, text))
+	return nil
+}
+
+func TestRouter(t *testing.T) {
+	tg := &interactiveTransport{recordingTransport{name: "telegram"}}
+	slack := &recordingTransport{name: "slack"}
+
+	router := notifications.NewRouter(tg)
+	router.Register(notifications.TransportSlack, slack, 2)
+
+	ctx := context.TODO()
+	keyboard := notifications.Keyboard{}
+
+	assert.NoError(t, router.SendMessage(ctx, 1, "a"))
+	assert.NoError(t, router.SendMessage(ctx, 2, "b"))
+	assert.NoError(t, router.React(ctx, 2, 5, "🤝"))
+	assert.NoError(t, router.SendKeyboard(ctx, 1, "c", keyboard))
+	assert.NoError(t, router.SendKeyboard(ctx, 2, "d", keyboard))
+	assert.NoError(t, router.EditMessage(ctx, 2, 5, "e", keyboard))
+	assert.NoError(t, router.AnswerCallback(ctx, "cb", "f"))
+	assert.NoError(t, router.SendDocument(ctx, 2, notifications.Document{Name: "dry.csv"}, "g"))
+
+	assert.Equal(t, []string{"send 1 a", "keyboard 1 c", "answer cb f"}, tg.calls)
+	assert.Equal(t, []string{"send 2 b", "react 2 5 🤝", "send 2 d", "send 2 e", "document 2 dry.csv g"}, slack.calls)
+
+	file, err := router.GetFile(ctx, notifications.FileID(notifications.TransportSlack, "https://files.slack.com/x"))
+	assert.NoError(t, err)
+	assert.Equal(t, "slack https://files.slack.com/x", string(file))
+
+	file, err = router.GetFile(ctx, "BQACAgIAAxkBAAI")
+	assert.NoError(t, err)
+	assert.Equal(t, "telegram BQACAgIAAxkBAAI", string(file))
+}
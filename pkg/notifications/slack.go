@@ -0,0 +1,202 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/imroc/req/v3"
+)
+
+const slackDefaultReaction = "white_check_mark"
+
+// slackReactions maps processor reactions to slack emoji names, slack does not accept unicode emoji.
+var slackReactions = map[string]string{
+	"🤝": "handshake",
+	"🍾": "champagne",
+	"🤬": "face_with_symbols_on_mouth",
+}
+
+// Slack sends replies with the web api, chats are mapped to slack channel ids.
+type Slack struct {
+	client   *req.Client
+	apiURL   string
+	token    string
+	channels map[int64]string
+}
+
+func NewSlack(
+	apiURL string,
+	token string,
+	cl *req.Client,
+	channels map[int64]string,
+) *Slack {
+	return &Slack{
+		client:   cl,
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		token:    token,
+		channels: channels,
+	}
+}
+
+// SlackMessageID converts a message ts, e.g. "1712345678.000100", to the message id used by the processor.
+func SlackMessageID(ts string) (int64, error) {
+	seconds, micros, _ := strings.Cut(ts, ".")
+
+	sec, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid slack ts %s", ts)
+	}
+
+	var micro int64
+
+	if micros != "" {
+		if micro, err = strconv.ParseInt((micros + "000000")[:6], 10, 64); err != nil {
+			return 0, errors.Wrapf(err, "invalid slack ts %s", ts)
+		}
+	}
+
+	return sec*1_000_000 + micro, nil
+}
+
+func (s *Slack) channel(chatID int64) (string, error) {
+	channel, ok := s.channels[chatID]
+	if !ok {
+		return "", errors.Newf("slack channel for chat %d is not configured", chatID)
+	}
+
+	return channel, nil
+}
+
+func (s *Slack) call(ctx context.Context, method string, body map[string]interface{}) error {
+	return s.do(ctx, method, s.client.R().SetBody(body), nil)
+}
+
+// do sends the api request, result receives the response when set.
+func (s *Slack) do(ctx context.Context, method string, request *req.Request, result interface{}) error {
+	var apiResp struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+
+	resp, err := request.
+		SetContext(ctx).
+		SetBearerAuthToken(s.token).
+		Post(s.apiURL + "/" + method)
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	if err = resp.Unmarshal(&apiResp); err != nil {
+		return errors.Wrapf(err, "slack %s returned invalid response", method)
+	}
+
+	if !apiResp.Ok { // slack reports errors with 200
+		return errors.Newf("slack %s failed: %s", method, apiResp.Error)
+	}
+
+	if result != nil {
+		return resp.Unmarshal(result)
+	}
+
+	return nil
+}
+
+func (s *Slack) SendMessage(ctx context.Context, chatID int64, raw string) error {
+	channel, err := s.channel(chatID)
+	if err != nil {
+		return err
+	}
+
+	for _, text := range splitText(raw, 4000, false) {
+		if err = s.call(ctx, "chat.postMessage", map[string]interface{}{
+			"channel": channel,
+			"text":    text,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Slack) React(ctx context.Context, chatID int64, messageID int64, reaction string) error {
+	channel, err := s.channel(chatID)
+	if err != nil {
+		return err
+	}
+
+	name, ok := slackReactions[reaction]
+	if !ok {
+		name = slackDefaultReaction
+	}
+
+	return s.call(ctx, "reactions.add", map[string]interface{}{
+		"channel":   channel,
+		"timestamp": fmt.Sprintf("%d.%06d", messageID/1_000_000, messageID%1_000_000),
+		"name":      name,
+	})
+}
+
+// SendDocument uploads the document with the external upload flow and shares it to the channel.
+func (s *Slack) SendDocument(ctx context.Context, chatID int64, document Document, caption string) error {
+	channel, err := s.channel(chatID)
+	if err != nil {
+		return err
+	}
+
+	var upload struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+
+	if err = s.do(ctx, "files.getUploadURLExternal", s.client.R().SetFormData(map[string]string{
+		"filename": document.Name,
+		"length":   strconv.Itoa(len(document.Data)),
+	}), &upload); err != nil {
+		return err
+	}
+
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetFileBytes("file", document.Name, document.Data).
+		Post(upload.UploadURL)
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return s.call(ctx, "files.completeUploadExternal", map[string]interface{}{
+		"files": []map[string]string{
+			{"id": upload.FileID, "title": document.Name},
+		},
+		"channel_id":      channel,
+		"initial_comment": caption,
+	})
+}
+
+// GetFile downloads the url_private_download of a shared file.
+func (s *Slack) GetFile(ctx context.Context, fileID string) ([]byte, error) {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetBearerAuthToken(s.token).
+		Get(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsErrorState() {
+		return nil, fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return resp.Bytes(), nil
+}
@@ -0,0 +1,78 @@
+package notifications_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+)
+
+func TestSlackSendMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat.postMessage", r.URL.Path)
+		assert.Equal(t, "Bearer xoxb-123", r.Header.Get("Authorization"))
+
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"channel":"C123","text":"test"}`, string(body))
+
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	slack := notifications.NewSlack(srv.URL+"/api", "xoxb-123", req.C(), map[int64]string{1: "C123"})
+
+	assert.NoError(t, slack.SendMessage(context.TODO(), 1, "test"))
+}
+
+func TestSlackSendMessageNotOk(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+	}))
+	defer srv.Close()
+
+	slack := notifications.NewSlack(srv.URL, "xoxb-123", req.C(), map[int64]string{1: "C123"})
+
+	assert.ErrorContains(t, slack.SendMessage(context.TODO(), 1, "test"), "channel_not_found")
+	assert.ErrorContains(t, slack.SendMessage(context.TODO(), 2, "test"), "slack channel for chat 2 is not configured")
+}
+
+func TestSlackReact(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/reactions.add", r.URL.Path)
+
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"channel":"C123","timestamp":"1712345678.000100","name":"champagne"}`, string(body))
+
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	slack := notifications.NewSlack(srv.URL, "xoxb-123", req.C(), map[int64]string{1: "C123"})
+
+	messageID, err := notifications.SlackMessageID("1712345678.000100")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1712345678000100, messageID)
+
+	assert.NoError(t, slack.React(context.TODO(), 1, messageID, "🍾"))
+}
+
+func TestSlackGetFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xoxb-123", r.Header.Get("Authorization"))
+
+		_, _ = w.Write([]byte(`file content`))
+	}))
+	defer srv.Close()
+
+	slack := notifications.NewSlack(srv.URL, "xoxb-123", req.C(), nil)
+
+	resp, err := slack.GetFile(context.TODO(), srv.URL+"/files-pri/T1-F1/download/statement.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "file content", string(resp))
+}
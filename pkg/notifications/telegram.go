@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/imroc/req/v3"
-	"github.com/samber/lo"
 )
 
+const ParseModeHTML = "HTML"
+
 type Telegram struct {
-	client   *req.Client
-	apiToken string
+	client    *req.Client
+	apiToken  string
+	parseMode string
 }
 
 func NewTelegram(
@@ -24,6 +27,20 @@ func NewTelegram(
 	}
 }
 
+// SetParseMode enables telegram formatting of all sent texts, e.g. ParseModeHTML. Texts must be escaped then.
+func (t *Telegram) SetParseMode(parseMode string) {
+	t.parseMode = parseMode
+}
+
+// withParseMode adds the parse mode to a request body when it is configured.
+func (t *Telegram) withParseMode(body map[string]interface{}) map[string]interface{} {
+	if t.parseMode != "" {
+		body["parse_mode"] = t.parseMode
+	}
+
+	return body
+}
+
 func (t *Telegram) GetFile(ctx context.Context, fileID string) ([]byte, error) {
 	var fileResp getFileResponse
 
@@ -55,14 +72,12 @@ func (t *Telegram) SendMessage(
 	chatID int64,
 	raw string,
 ) error {
-	texts := lo.Chunk([]rune(raw), 4090)
-
-	for _, text := range texts {
+	for _, text := range splitText(raw, 4090, t.parseMode == ParseModeHTML) {
 		resp, err := t.client.R().
-			SetBody(map[string]interface{}{
+			SetBody(t.withParseMode(map[string]interface{}{
 				"chat_id": chatID,
-				"text":    string(text),
-			}).
+				"text":    text,
+			})).
 			SetContext(ctx).
 			Post(fmt.Sprintf("https://api.telegram.org/bot%v/sendMessage", t.apiToken))
 
@@ -78,6 +93,139 @@ func (t *Telegram) SendMessage(
 	return nil
 }
 
+// SendKeyboard sends a single message with inline buttons, text is not split.
+func (t *Telegram) SendKeyboard(
+	ctx context.Context,
+	chatID int64,
+	text string,
+	keyboard Keyboard,
+) error {
+	resp, err := t.client.R().
+		SetBody(t.withParseMode(map[string]interface{}{
+			"chat_id":      chatID,
+			"text":         text,
+			"reply_markup": t.replyMarkup(keyboard),
+		})).
+		SetContext(ctx).
+		Post(fmt.Sprintf("https://api.telegram.org/bot%v/sendMessage", t.apiToken))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return nil
+}
+
+func (t *Telegram) EditMessage(
+	ctx context.Context,
+	chatID int64,
+	messageID int64,
+	text string,
+	keyboard Keyboard,
+) error {
+	resp, err := t.client.R().
+		SetBody(t.withParseMode(map[string]interface{}{
+			"chat_id":      chatID,
+			"message_id":   messageID,
+			"text":         text,
+			"reply_markup": t.replyMarkup(keyboard),
+		})).
+		SetContext(ctx).
+		Post(fmt.Sprintf("https://api.telegram.org/bot%v/editMessageText", t.apiToken))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return nil
+}
+
+// SendDocument uploads the document with the caption, captions are limited to 1024 characters.
+func (t *Telegram) SendDocument(
+	ctx context.Context,
+	chatID int64,
+	document Document,
+	caption string,
+) error {
+	form := map[string]string{
+		"chat_id": strconv.FormatInt(chatID, 10),
+		"caption": t.caption(caption),
+	}
+
+	if t.parseMode != "" {
+		form["parse_mode"] = t.parseMode
+	}
+
+	resp, err := t.client.R().
+		SetFormData(form).
+		SetFileBytes("document", document.Name, document.Data).
+		SetContext(ctx).
+		Post(fmt.Sprintf("https://api.telegram.org/bot%v/sendDocument", t.apiToken))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return nil
+}
+
+// caption cuts the caption to 1024 characters, html tags and entities are kept intact.
+func (t *Telegram) caption(raw string) string {
+	chunks := splitText(raw, 1024, t.parseMode == ParseModeHTML)
+	if len(chunks) == 0 {
+		return ""
+	}
+
+	return chunks[0]
+}
+
+// AnswerCallback stops the loading indicator of the pressed button, text is shown as a toast.
+func (t *Telegram) AnswerCallback(
+	ctx context.Context,
+	callbackID string,
+	text string,
+) error {
+	resp, err := t.client.R().
+		SetBody(map[string]interface{}{
+			"callback_query_id": callbackID,
+			"text":              text,
+		}).
+		SetContext(ctx).
+		Post(fmt.Sprintf("https://api.telegram.org/bot%v/answerCallbackQuery", t.apiToken))
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return nil
+}
+
+func (t *Telegram) replyMarkup(keyboard Keyboard) map[string]interface{} {
+	if keyboard == nil {
+		keyboard = Keyboard{}
+	}
+
+	return map[string]interface{}{
+		"inline_keyboard": keyboard,
+	}
+}
+
 func (t *Telegram) React(
 	ctx context.Context,
 	chatID int64,
@@ -2,6 +2,10 @@ package notifications_test
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/imroc/req/v3"
@@ -59,3 +63,173 @@ func TestReact(t *testing.T) {
 		tg.React(context.TODO(), 123, 123, "test")
 	assert.NoError(t, err)
 }
+
+func TestSendKeyboard(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	tg := notifications.NewTelegram("123:xxx", cl)
+
+	httpmock.RegisterResponder("POST", "https://api.telegram.org/bot123:xxx/sendMessage",
+		func(request *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(request.Body)
+			assert.JSONEq(t, `{"chat_id":123,"text":"test",
+				"reply_markup":{"inline_keyboard":[[{"text":"Commit","callback_data":"commit:abc"}]]}}`, string(body))
+
+			return httpmock.NewStringResponse(200, `{"ok":true}`), nil
+		})
+
+	assert.NoError(t, tg.SendKeyboard(context.TODO(), 123, "test", notifications.Keyboard{
+		{{Text: "Commit", CallbackData: "commit:abc"}},
+	}))
+}
+
+func TestEditMessage(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	tg := notifications.NewTelegram("123:xxx", cl)
+
+	httpmock.RegisterResponder("POST", "https://api.telegram.org/bot123:xxx/editMessageText",
+		func(request *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(request.Body)
+			assert.JSONEq(t, `{"chat_id":123,"message_id":456,"text":"edited",
+				"reply_markup":{"inline_keyboard":[]}}`, string(body))
+
+			return httpmock.NewStringResponse(400, `{"ok":false}`), nil
+		})
+
+	assert.Error(t, tg.EditMessage(context.TODO(), 123, 456, "edited", nil))
+}
+
+func TestAnswerCallback(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	tg := notifications.NewTelegram("123:xxx", cl)
+
+	httpmock.RegisterResponder("POST", "https://api.telegram.org/bot123:xxx/answerCallbackQuery",
+		httpmock.NewStringResponder(200, `{"ok":true,"result":true}`))
+
+	assert.NoError(t, tg.AnswerCallback(context.TODO(), "callback-id", "Skipped"))
+}
+
+func TestSendMessageSplitsAtBlocks(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	tg := notifications.NewTelegram("123:xxx", cl)
+	tg.SetParseMode(notifications.ParseModeHTML)
+
+	var texts []string
+
+	httpmock.RegisterResponder("POST", "https://api.telegram.org/bot123:xxx/sendMessage",
+		func(request *http.Request) (*http.Response, error) {
+			var body struct {
+				Text      string `json:"text"`
+				ParseMode string `json:"parse_mode"`
+			}
+
+			assert.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+			assert.Equal(t, "HTML", body.ParseMode)
+
+			texts = append(texts, body.Text)
+
+			return httpmock.NewStringResponse(200, `{"ok":true}`), nil
+		})
+
+	first := "<pre>" + strings.Repeat("a\n", 1500) + "</pre>"
+	second := "<pre>" + strings.Repeat("b\n", 1500) + "</pre>"
+
+	assert.NoError(t, tg.SendMessage(context.TODO(), 123, first+"\n\n"+second))
+	assert.Equal(t, []string{first, second}, texts)
+}
+
+func TestSendDocument(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	tg := notifications.NewTelegram("123:xxx", cl)
+
+	httpmock.RegisterResponder("POST", "https://api.telegram.org/bot123:xxx/sendDocument",
+		func(request *http.Request) (*http.Response, error) {
+			assert.NoError(t, request.ParseMultipartForm(1<<20))
+			assert.Equal(t, "123", request.FormValue("chat_id"))
+			assert.Equal(t, "Total transactions: 40", request.FormValue("caption"))
+			assert.Empty(t, request.FormValue("parse_mode"))
+
+			file, header, err := request.FormFile("document")
+			assert.NoError(t, err)
+			assert.Equal(t, "dry.csv", header.Filename)
+
+			data, _ := io.ReadAll(file)
+			assert.Equal(t, "status\nok\n", string(data))
+
+			return httpmock.NewStringResponse(200, `{"ok":true}`), nil
+		})
+
+	assert.NoError(t, tg.SendDocument(context.TODO(), 123, notifications.Document{
+		Name:     "dry.csv",
+		MimeType: "text/csv",
+		Data:     []byte("status\nok\n"),
+	}, "Total transactions: 40"))
+}
+
+func TestSendMessageKeepsTagsOfLongLines(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	tg := notifications.NewTelegram("123:xxx", cl)
+	tg.SetParseMode(notifications.ParseModeHTML)
+
+	var texts []string
+
+	httpmock.RegisterResponder("POST", "https://api.telegram.org/bot123:xxx/sendMessage",
+		func(request *http.Request) (*http.Response, error) {
+			var body struct {
+				Text string `json:"text"`
+			}
+
+			assert.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+			texts = append(texts, body.Text)
+
+			return httpmock.NewStringResponse(200, `{"ok":true}`), nil
+		})
+
+	raw := "<b>" + strings.Repeat("a", 4085) + "&amp;b</b>"
+
+	assert.NoError(t, tg.SendMessage(context.TODO(), 123, raw))
+	assert.Equal(t, []string{
+		"<b>" + strings.Repeat("a", 4085) + "</b>",
+		"<b>&amp;b</b>",
+	}, texts)
+}
+
+func TestSendDocumentHTMLCaption(t *testing.T) {
+	cl := req.DefaultClient()
+	httpmock.ActivateNonDefault(cl.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	tg := notifications.NewTelegram("123:xxx", cl)
+	tg.SetParseMode(notifications.ParseModeHTML)
+
+	httpmock.RegisterResponder("POST", "https://api.telegram.org/bot123:xxx/sendDocument",
+		func(request *http.Request) (*http.Response, error) {
+			assert.NoError(t, request.ParseMultipartForm(1<<20))
+			assert.Equal(t, "HTML", request.FormValue("parse_mode"))
+			assert.Equal(t, "<b>"+strings.Repeat("a", 1019)+"</b>", request.FormValue("caption"))
+
+			return httpmock.NewStringResponse(200, `{"ok":true}`), nil
+		})
+
+	assert.NoError(t, tg.SendDocument(context.TODO(), 123, notifications.Document{
+		Name: "dry.csv",
+		Data: []byte("status\nok\n"),
+	}, "<b>"+strings.Repeat("a", 1019)+"<i>x</i></b>"))
+}
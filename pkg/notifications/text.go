@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"regexp"
+	"strings"
+)
+
+var htmlTagRegex = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9-]*)[^>]*>`)
+
+// splitText splits a message into chunks of at most limit runes. Chunks end at a blank line or a line break
+// when possible, so transactions are not cut in the middle. In html mode chunks are never cut inside a tag or
+// an entity, tags still open at the end of a chunk are closed and opened again in the next one.
+func splitText(raw string, limit int, html bool) []string {
+	var chunks []string
+
+	runes := []rune(raw)
+
+	for len(runes) > limit {
+		chunk := string(runes[:limit])
+
+		cut := strings.LastIndex(chunk, "\n\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(chunk, "\n")
+		}
+
+		if cut <= 0 {
+			cut = len(chunk)
+			if html {
+				cut = htmlCut(chunk)
+			}
+
+			chunks = append(chunks, chunk[:cut])
+			runes = runes[len([]rune(chunk[:cut])):]
+
+			continue
+		}
+
+		chunks = append(chunks, chunk[:cut])
+		runes = []rune(strings.TrimLeft(string(runes[len([]rune(chunk[:cut])):]), "\n"))
+	}
+
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+
+	if html {
+		return balanceTags(chunks)
+	}
+
+	return chunks
+}
+
+// htmlCut returns the last position of the chunk outside of a tag or an entity.
+func htmlCut(chunk string) int {
+	cut := len(chunk)
+
+	if open := strings.LastIndex(chunk, "<"); open > strings.LastIndex(chunk, ">") {
+		cut = open
+	}
+
+	if amp := strings.LastIndex(chunk[:cut], "&"); amp > strings.LastIndex(chunk[:cut], ";") {
+		cut = amp
+	}
+
+	if cut == 0 { // a single tag or entity longer than the chunk
+		return len(chunk)
+	}
+
+	return cut
+}
+
+type htmlTag struct {
+	name string
+	raw  string
+}
+
+// balanceTags closes the tags still open at the end of every chunk and opens them again in the next one.
+func balanceTags(chunks []string) []string {
+	var open []htmlTag
+
+	for i, chunk := range chunks {
+		var prefix strings.Builder
+		for _, tag := range open {
+			prefix.WriteString(tag.raw)
+		}
+
+		for _, match := range htmlTagRegex.FindAllStringSubmatch(chunk, -1) {
+			name := strings.ToLower(match[2])
+
+			if match[1] == "" {
+				open = append(open, htmlTag{name: name, raw: match[0]})
+				continue
+			}
+
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j].name == name {
+					open = append(open[:j], open[j+1:]...)
+					break
+				}
+			}
+		}
+
+		var suffix strings.Builder
+		for j := len(open) - 1; j >= 0; j-- {
+			suffix.WriteString("</" + open[j].name + ">")
+		}
+
+		chunks[i] = prefix.String() + chunk + suffix.String()
+	}
+
+	return chunks
+}
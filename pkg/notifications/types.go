@@ -5,3 +5,18 @@ type getFileResponse struct {
 		FilePath string `json:"file_path"`
 	}
 }
+
+// Keyboard is a telegram inline keyboard, rows of buttons.
+type Keyboard [][]InlineButton
+
+type InlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// Document is a file attachment, e.g. a csv export of a large /dry.
+type Document struct {
+	Name     string
+	MimeType string
+	Data     []byte
+}
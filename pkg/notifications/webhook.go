@@ -0,0 +1,153 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/imroc/req/v3"
+	"github.com/samber/lo"
+)
+
+const (
+	WebhookEventMessage  = "message"
+	WebhookEventReaction = "reaction"
+	WebhookEventDocument = "document"
+)
+
+// WebhookEvent is posted to the callback url of the generic webhook transport.
+type WebhookEvent struct {
+	Type      string `json:"type"`
+	ChatID    int64  `json:"chatId"`
+	MessageID int64  `json:"messageId,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Reaction  string `json:"reaction,omitempty"`
+	// Document is sent base64 encoded with its name and mime type, the caption is in Text.
+	FileName string `json:"fileName,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Document []byte `json:"document,omitempty"`
+}
+
+// Webhook posts replies and reactions as json to a callback url, files are downloaded by their url.
+type Webhook struct {
+	client      *req.Client
+	callbackURL string
+	headers     map[string]string
+	fileHosts   []string
+}
+
+func NewWebhook(
+	callbackURL string,
+	cl *req.Client,
+	headers map[string]string,
+) *Webhook {
+	return &Webhook{
+		client:      cl,
+		callbackURL: callbackURL,
+		headers:     headers,
+	}
+}
+
+// SetFileHosts sets the hosts files are downloaded from, only the callback url host is allowed by default.
+func (w *Webhook) SetFileHosts(hosts ...string) {
+	w.fileHosts = lo.Map(hosts, func(item string, _ int) string {
+		return strings.ToLower(strings.TrimSpace(item))
+	})
+}
+
+// validFileURL rejects urls outside the allowed hosts, the file url is posted by the bridge and must not
+// reach internal services.
+func (w *Webhook) validFileURL(fileURL string) error {
+	parsed, err := url.Parse(fileURL)
+	if err != nil {
+		return errors.Wrap(err, "invalid file url")
+	}
+
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return errors.Newf("file url scheme %s is not allowed", parsed.Scheme)
+	}
+
+	hosts := w.fileHosts
+	if len(hosts) == 0 {
+		callback, callbackErr := url.Parse(w.callbackURL)
+		if callbackErr != nil || callback.Host == "" {
+			return errors.New("file hosts are not configured")
+		}
+
+		hosts = []string{strings.ToLower(callback.Host)}
+	}
+
+	if !lo.Contains(hosts, strings.ToLower(parsed.Host)) {
+		return errors.Newf("file host %s is not allowed", parsed.Host)
+	}
+
+	return nil
+}
+
+func (w *Webhook) post(ctx context.Context, event WebhookEvent) error {
+	resp, err := w.client.R().
+		SetContext(ctx).
+		SetHeaders(w.headers).
+		SetBody(event).
+		Post(w.callbackURL)
+	if err != nil {
+		return err
+	}
+
+	if resp.IsErrorState() {
+		return fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return nil
+}
+
+func (w *Webhook) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return w.post(ctx, WebhookEvent{
+		Type:   WebhookEventMessage,
+		ChatID: chatID,
+		Text:   text,
+	})
+}
+
+func (w *Webhook) React(ctx context.Context, chatID int64, messageID int64, reaction string) error {
+	return w.post(ctx, WebhookEvent{
+		Type:      WebhookEventReaction,
+		ChatID:    chatID,
+		MessageID: messageID,
+		Reaction:  reaction,
+	})
+}
+
+func (w *Webhook) SendDocument(ctx context.Context, chatID int64, document Document, caption string) error {
+	return w.post(ctx, WebhookEvent{
+		Type:     WebhookEventDocument,
+		ChatID:   chatID,
+		Text:     caption,
+		FileName: document.Name,
+		MimeType: document.MimeType,
+		Document: document.Data,
+	})
+}
+
+// GetFile downloads the file url sent with the message, see SetFileHosts.
+func (w *Webhook) GetFile(ctx context.Context, fileID string) ([]byte, error) {
+	if err := w.validFileURL(fileID); err != nil {
+		return nil, err
+	}
+
+	resp, err := w.client.R().
+		SetContext(ctx).
+		SetHeaders(w.headers).
+		Get(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsErrorState() {
+		return nil, fmt.Errorf("unexpected status code: %v and message %v", resp.StatusCode, resp.String())
+	}
+
+	return resp.Bytes(), nil
+}
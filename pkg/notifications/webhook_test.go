@@ -0,0 +1,77 @@
+package notifications_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+)
+
+func TestWebhook(t *testing.T) {
+	var bodies []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`file content`))
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer srv.Close()
+
+	webhook := notifications.NewWebhook(srv.URL, req.C(), map[string]string{"X-Api-Key": "secret"})
+
+	assert.NoError(t, webhook.SendMessage(context.TODO(), 1, "test"))
+	assert.NoError(t, webhook.React(context.TODO(), 1, 5, "🤝"))
+
+	assert.Len(t, bodies, 2)
+	assert.JSONEq(t, `{"type":"message","chatId":1,"text":"test"}`, bodies[0])
+	assert.JSONEq(t, `{"type":"reaction","chatId":1,"messageId":5,"reaction":"🤝"}`, bodies[1])
+
+	resp, err := webhook.GetFile(context.TODO(), srv.URL+"/statement.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "file content", string(resp))
+}
+
+func TestWebhookErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	webhook := notifications.NewWebhook(srv.URL, req.C(), nil)
+
+	assert.ErrorContains(t, webhook.SendMessage(context.TODO(), 1, "test"), "unexpected status code: 502")
+}
+
+func TestWebhookFileHosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`file content`))
+	}))
+	defer srv.Close()
+
+	webhook := notifications.NewWebhook("https://example.org/replies", req.C(), nil)
+
+	_, err := webhook.GetFile(context.TODO(), srv.URL+"/statement.csv")
+	assert.ErrorContains(t, err, "is not allowed")
+
+	_, err = webhook.GetFile(context.TODO(), "file:///etc/passwd")
+	assert.ErrorContains(t, err, "scheme file is not allowed")
+
+	webhook.SetFileHosts(strings.TrimPrefix(srv.URL, "http://"))
+
+	resp, err := webhook.GetFile(context.TODO(), srv.URL+"/statement.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "file content", string(resp))
+}
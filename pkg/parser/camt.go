@@ -0,0 +1,373 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+const (
+	camtCredit         = "CRDT"
+	camtDebit          = "DBIT"
+	camtPending        = "PDNG"
+	camtNotProvidedRef = "NOTPROVIDED"
+)
+
+// camtDocument covers camt.053 statements and camt.052 reports, tags are matched without namespace,
+// so every version of the schema is accepted.
+type camtDocument struct {
+	XMLName    xml.Name         `xml:"Document"`
+	Statements []*camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Reports    []*camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+}
+
+type camtStatement struct {
+	ID      string       `xml:"Id"`
+	Account camtAccount  `xml:"Acct"`
+	Entries []*camtEntry `xml:"Ntry"`
+}
+
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+	Raw      string `xml:",innerxml"`
+}
+
+func (a camtAccount) number() string {
+	return strings.TrimSpace(lo.Ternary(a.IBAN != "", a.IBAN, a.Other))
+}
+
+type camtEntry struct {
+	Amount         camtAmount       `xml:"Amt"`
+	CreditDebit    string           `xml:"CdtDbtInd"`
+	Status         camtStatus       `xml:"Sts"`
+	BookingDate    camtDate         `xml:"BookgDt"`
+	ValueDate      camtDate         `xml:"ValDt"`
+	AcctSvcrRef    string           `xml:"AcctSvcrRef"`
+	AdditionalInfo string           `xml:"AddtlNtryInf"`
+	Details        []*camtTxDetails `xml:"NtryDtls>TxDtls"`
+	Raw            string           `xml:",innerxml"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus is plain text up to camt.053.001.04 and <Cd> since then.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtTxDetails struct {
+	AcctSvcrRef      string        `xml:"Refs>AcctSvcrRef"`
+	EndToEndID       string        `xml:"Refs>EndToEndId"`
+	InstructedAmount camtAmount    `xml:"AmtDtls>InstdAmt>Amt"`
+	Debtor           camtParty     `xml:"RltdPties>Dbtr"`
+	DebtorAccount    camtAccountID `xml:"RltdPties>DbtrAcct"`
+	Creditor         camtParty     `xml:"RltdPties>Cdtr"`
+	CreditorAccount  camtAccountID `xml:"RltdPties>CdtrAcct"`
+	Unstructured     []string      `xml:"RmtInf>Ustrd"`
+}
+
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"` // camt.053.001.08+
+}
+
+func (p camtParty) name() string {
+	return strings.TrimSpace(lo.Ternary(p.Name != "", p.Name, p.PartyName))
+}
+
+type camtAccountID struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+func (a camtAccountID) number() string {
+	return strings.TrimSpace(lo.Ternary(a.IBAN != "", a.IBAN, a.Other))
+}
+
+type camtRecord struct {
+	tx        *database.Transaction
+	statement *camtStatement
+	entry     *camtEntry
+}
+
+type Camt struct {
+}
+
+func NewCamt() *Camt {
+	return &Camt{}
+}
+
+func (c *Camt) Type() database.TransactionSource {
+	return database.Camt
+}
+
+// SplitExcel emits one document per Ntry, keeping the statement account it belongs to.
+func (c *Camt) SplitExcel(
+	_ context.Context,
+	data []byte,
+) ([][]byte, error) {
+	doc, err := c.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var resultFiles [][]byte
+
+	for _, statement := range append(doc.Statements, doc.Reports...) {
+		for _, entry := range statement.Entries {
+			var buf bytes.Buffer
+
+			buf.WriteString("<Document><BkToCstmrStmt><Stmt><Id>")
+			if err = xml.EscapeText(&buf, []byte(statement.ID)); err != nil {
+				return nil, err
+			}
+			buf.WriteString("</Id><Acct>")
+			buf.WriteString(statement.Account.Raw)
+			buf.WriteString("</Acct><Ntry>")
+			buf.WriteString(entry.Raw)
+			buf.WriteString("</Ntry></Stmt></BkToCstmrStmt></Document>")
+
+			resultFiles = append(resultFiles, buf.Bytes())
+		}
+	}
+
+	if len(resultFiles) == 0 {
+		return nil, errors.New("no entries found")
+	}
+
+	return resultFiles, nil
+}
+
+func (c *Camt) decode(data []byte) (*camtDocument, error) {
+	var doc camtDocument
+
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse camt document")
+	}
+
+	return &doc, nil
+}
+
+func (c *Camt) ParseMessages(
+	_ context.Context,
+	rawArr []*Record,
+) ([]*database.Transaction, error) {
+	var transactions []*database.Transaction
+	var records []*camtRecord
+
+	ownAccounts := map[string]struct{}{}
+
+	for _, raw := range rawArr {
+		rawXml, err := hex.DecodeString(string(raw.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		tx := &database.Transaction{
+			ID:                uuid.NewString(),
+			Raw:               string(raw.Data),
+			OriginalMessage:   raw.Message,
+			TransactionSource: c.Type(),
+		}
+		transactions = append(transactions, tx)
+
+		doc, err := c.decode(rawXml)
+		if err != nil {
+			tx.ParsingError = err
+			continue
+		}
+
+		statements := append(doc.Statements, doc.Reports...)
+		if len(statements) != 1 || len(statements[0].Entries) != 1 {
+			tx.ParsingError = errors.New("expected exactly one entry per message")
+			continue
+		}
+
+		statement := statements[0]
+		if account := statement.Account.number(); account != "" {
+			ownAccounts[account] = struct{}{}
+		}
+
+		records = append(records, &camtRecord{
+			tx:        tx,
+			statement: statement,
+			entry:     statement.Entries[0],
+		})
+	}
+
+	// own accounts are only known after every statement of the upload is read
+	for _, rec := range records {
+		if err := c.parseTransaction(rec, ownAccounts); err != nil {
+			rec.tx.ParsingError = err
+		}
+	}
+
+	return mergeOwnTransfers(transactions), nil
+}
+
+func (c *Camt) parseTransaction(
+	rec *camtRecord,
+	ownAccounts map[string]struct{},
+) error {
+	tx := rec.tx
+	entry := rec.entry
+	account := rec.statement.Account.number()
+
+	tx.Raw = strings.Join(strings.Fields(entry.Raw), " ")
+	tx.OriginalTxType = entry.CreditDebit
+
+	if account == "" {
+		return errors.New("statement account is missing")
+	}
+
+	if status := strings.TrimSpace(lo.Ternary(entry.Status.Code != "", entry.Status.Code, entry.Status.Text)); status == camtPending {
+		return errors.New("transaction is still pending. will skip from firefly for now")
+	}
+
+	date, err := c.parseDate(entry)
+	if err != nil {
+		return err
+	}
+
+	tx.Date = date
+
+	amount, err := decimal.NewFromString(strings.TrimSpace(entry.Amount.Value))
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse amount %s", entry.Amount.Value)
+	}
+
+	currency := lo.Ternary(entry.Amount.Currency != "", entry.Amount.Currency, rec.statement.Account.Currency)
+
+	var details camtTxDetails
+	if len(entry.Details) > 0 {
+		details = *entry.Details[0]
+	}
+
+	counterAmount, counterCurrency := amount, currency
+	if details.InstructedAmount.Value != "" && details.InstructedAmount.Currency != "" {
+		if instructed, instructedErr := decimal.NewFromString(strings.TrimSpace(details.InstructedAmount.Value)); instructedErr == nil {
+			counterAmount, counterCurrency = instructed, details.InstructedAmount.Currency
+		}
+	}
+
+	tx.Description = strings.TrimSpace(strings.Join(details.Unstructured, " "))
+	if tx.Description == "" {
+		tx.Description = strings.TrimSpace(entry.AdditionalInfo)
+	}
+
+	tx.DeduplicationKeys = c.dedupKeys(account, amount, currency, entry, details)
+
+	var counterAccount string
+
+	switch entry.CreditDebit {
+	case camtDebit:
+		counterAccount = details.CreditorAccount.number()
+		tx.OriginalNadawcaName = details.Creditor.name()
+
+		tx.Type = database.TransactionTypeExpense
+		tx.SourceAccount = account
+		tx.SourceAmount = amount.Abs()
+		tx.SourceCurrency = currency
+		tx.DestinationAmount = counterAmount.Abs()
+		tx.DestinationCurrency = counterCurrency
+
+		if _, ok := ownAccounts[counterAccount]; ok && counterAccount != account {
+			tx.Type = database.TransactionTypeInternalTransfer
+			tx.DestinationAccount = counterAccount
+		}
+	case camtCredit:
+		counterAccount = details.DebtorAccount.number()
+		tx.OriginalNadawcaName = details.Debtor.name()
+
+		tx.Type = database.TransactionTypeIncome
+		tx.DestinationAccount = account
+		tx.DestinationAmount = amount.Abs()
+		tx.DestinationCurrency = currency
+		tx.SourceAmount = counterAmount.Abs()
+		tx.SourceCurrency = counterCurrency
+
+		if _, ok := ownAccounts[counterAccount]; ok && counterAccount != account {
+			tx.Type = database.TransactionTypeInternalTransfer
+			tx.SourceAccount = counterAccount
+			tx.InternalTransferDirectionTo = true
+		}
+	default:
+		return errors.Newf("unknown credit debit indicator %s", entry.CreditDebit)
+	}
+
+	if tx.Description == "" {
+		tx.Description = tx.OriginalNadawcaName
+	}
+
+	tx.Counterparty = tx.OriginalNadawcaName
+
+	return nil
+}
+
+func (c *Camt) parseDate(entry *camtEntry) (time.Time, error) {
+	for _, date := range []camtDate{entry.BookingDate, entry.ValueDate} {
+		if date.Date != "" {
+			return time.Parse("2006-01-02", strings.TrimSpace(date.Date))
+		}
+
+		if date.DateTime != "" {
+			return time.Parse(time.RFC3339, strings.TrimSpace(date.DateTime))
+		}
+	}
+
+	return time.Time{}, errors.New("booking date is missing")
+}
+
+// dedupKeys prefers bank references, end to end id is only unique together with the amount.
+func (c *Camt) dedupKeys(
+	account string,
+	amount decimal.Decimal,
+	currency string,
+	entry *camtEntry,
+	details camtTxDetails,
+) []string {
+	var keys []string
+
+	ref := lo.Ternary(entry.AcctSvcrRef != "", entry.AcctSvcrRef, details.AcctSvcrRef)
+	if ref = strings.TrimSpace(ref); ref != "" && ref != camtNotProvidedRef {
+		keys = append(keys, strings.Join([]string{"ref", account, ref}, "_"))
+	}
+
+	if e2e := strings.TrimSpace(details.EndToEndID); e2e != "" && e2e != camtNotProvidedRef {
+		keys = append(keys, strings.Join([]string{"e2e", e2e, amount.Abs().String(), currency}, "_"))
+	}
+
+	if len(keys) == 0 {
+		keys = append(keys, strings.Join([]string{
+			account,
+			entry.CreditDebit,
+			amount.String(),
+			currency,
+			entry.BookingDate.Date + entry.BookingDate.DateTime,
+			entry.AdditionalInfo,
+		}, "_"))
+	}
+
+	return keys
+}
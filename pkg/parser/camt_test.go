@@ -0,0 +1,150 @@
+package parser_test
+
+import (
+	"context"
+	_ "embed"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+)
+
+//go:embed testdata/camt/camt053.xml
+var camt053 []byte
+
+//go:embed testdata/camt/camt052.xml
+var camt052 []byte
+
+func parseCamt(t *testing.T, data []byte) []*database.Transaction {
+	srv := parser.NewCamt()
+
+	split, err := srv.SplitExcel(context.TODO(), data)
+	assert.NoError(t, err)
+
+	var records []*parser.Record
+	for _, chunk := range split {
+		records = append(records, &parser.Record{
+			Data: []byte(hex.EncodeToString(chunk)),
+		})
+	}
+
+	resp, err := srv.ParseMessages(context.TODO(), records)
+	assert.NoError(t, err)
+
+	return resp
+}
+
+func TestCamtSplit(t *testing.T) {
+	split, err := parser.NewCamt().SplitExcel(context.TODO(), camt053)
+	assert.NoError(t, err)
+	assert.Len(t, split, 5)
+
+	assert.Contains(t, string(split[0]), "PL61109010140000071219812874")
+	assert.Contains(t, string(split[0]), "PL-REF-0001")
+	assert.NotContains(t, string(split[0]), "PL-REF-0002")
+
+	assert.Contains(t, string(split[4]), "PL10105000997603123456789123")
+	assert.Contains(t, string(split[4]), "EUR-REF-0001")
+}
+
+func TestCamtSplitInvalid(t *testing.T) {
+	_, err := parser.NewCamt().SplitExcel(context.TODO(), []byte("<Document></Document>"))
+	assert.ErrorContains(t, err, "no entries found")
+
+	_, err = parser.NewCamt().SplitExcel(context.TODO(), []byte("not xml"))
+	assert.Error(t, err)
+}
+
+func TestCamt053(t *testing.T) {
+	resp := parseCamt(t, camt053)
+	assert.Len(t, resp, 4) // the own transfer is merged
+
+	expense := resp[0]
+	assert.NoError(t, expense.ParsingError)
+	assert.EqualValues(t, database.Camt, expense.TransactionSource)
+	assert.Equal(t, database.TransactionTypeExpense, expense.Type)
+	assert.Equal(t, "PL61109010140000071219812874", expense.SourceAccount)
+	assert.Equal(t, "45.99", expense.SourceAmount.StringFixed(2))
+	assert.Equal(t, "PLN", expense.SourceCurrency)
+	assert.Equal(t, "Card payment BIEDRONKA 1234 WARSZAWA", expense.Description)
+	assert.Equal(t, "BIEDRONKA 1234", expense.OriginalNadawcaName)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), expense.Date)
+	assert.Equal(t, []string{"ref_PL61109010140000071219812874_PL-REF-0001"}, expense.DeduplicationKeys)
+
+	income := resp[1]
+	assert.NoError(t, income.ParsingError)
+	assert.Equal(t, database.TransactionTypeIncome, income.Type)
+	assert.Equal(t, "PL61109010140000071219812874", income.DestinationAccount)
+	assert.Equal(t, "8500.00", income.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "Salary 09/2026", income.Description)
+	assert.Empty(t, income.SourceAccount) // not an own account
+	assert.Equal(t, []string{
+		"ref_PL61109010140000071219812874_PL-REF-0002",
+		"e2e_SALARY-2026-09_8500_PLN",
+	}, income.DeduplicationKeys)
+
+	transfer := resp[2]
+	assert.NoError(t, transfer.ParsingError)
+	assert.Equal(t, database.TransactionTypeInternalTransfer, transfer.Type)
+	assert.Equal(t, "PL61109010140000071219812874", transfer.SourceAccount)
+	assert.Equal(t, "430.00", transfer.SourceAmount.StringFixed(2))
+	assert.Equal(t, "PLN", transfer.SourceCurrency)
+	assert.Equal(t, "PL10105000997603123456789123", transfer.DestinationAccount)
+	assert.Equal(t, "100.00", transfer.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "EUR", transfer.DestinationCurrency)
+
+	if assert.Len(t, transfer.DuplicateTransactions, 1) {
+		assert.Equal(t, []string{
+			"ref_PL10105000997603123456789123_EUR-REF-0001",
+			"e2e_OWN-TRANSFER-1_100_EUR",
+		}, transfer.DuplicateTransactions[0].DeduplicationKeys)
+	}
+
+	assert.ErrorContains(t, resp[3].ParsingError, "pending")
+}
+
+func TestCamt053OwnTransferSingleSide(t *testing.T) {
+	srv := parser.NewCamt()
+
+	split, err := srv.SplitExcel(context.TODO(), camt053)
+	assert.NoError(t, err)
+
+	// only the credit side and the debit statement account are uploaded
+	resp, err := srv.ParseMessages(context.TODO(), []*parser.Record{
+		{Data: []byte(hex.EncodeToString(split[0]))},
+		{Data: []byte(hex.EncodeToString(split[4]))},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 2)
+
+	credit := resp[1]
+	assert.NoError(t, credit.ParsingError)
+	assert.Equal(t, database.TransactionTypeInternalTransfer, credit.Type)
+	assert.Equal(t, "PL61109010140000071219812874", credit.SourceAccount)
+	assert.Equal(t, "430.00", credit.SourceAmount.StringFixed(2))
+	assert.Equal(t, "PLN", credit.SourceCurrency)
+	assert.Equal(t, "PL10105000997603123456789123", credit.DestinationAccount)
+	assert.Equal(t, "100.00", credit.DestinationAmount.StringFixed(2))
+}
+
+func TestCamt052(t *testing.T) {
+	resp := parseCamt(t, camt052)
+	assert.Len(t, resp, 1)
+
+	tx := resp[0]
+	assert.NoError(t, tx.ParsingError)
+	assert.Equal(t, database.TransactionTypeExpense, tx.Type)
+	assert.Equal(t, "123456789", tx.SourceAccount)
+	assert.Equal(t, "19.90", tx.SourceAmount.StringFixed(2))
+	assert.Equal(t, "EUR", tx.SourceCurrency)
+	assert.Equal(t, "STREAMING SERVICE", tx.Description)
+	assert.True(t, tx.Date.Equal(time.Date(2026, 10, 3, 8, 15, 0, 0, time.UTC)))
+	assert.Equal(t, []string{
+		"ref_123456789_RPT-REF-1",
+		"e2e_E2E-STREAMING-10_19.9_EUR",
+	}, tx.DeduplicationKeys)
+}
@@ -3,6 +3,10 @@ package parser
 import (
 	"strings"
 	"unicode"
+
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 )
 
 func toLines(input string) []string {
@@ -28,3 +32,66 @@ func stripAccountPrefix(account string) string {
 
 	return accountStriped.String()
 }
+
+// mergeOwnTransfers pairs both sides of a transfer between own accounts, when statements of both
+// accounts are uploaded. InternalTransferDirectionTo marks the credit side.
+func mergeOwnTransfers(
+	transactions []*database.Transaction,
+) []*database.Transaction {
+	var finalTransactions []*database.Transaction
+	var merged []*database.Transaction
+
+	for _, tx := range transactions {
+		if lo.Contains(merged, tx) {
+			continue
+		}
+
+		if tx.Type != database.TransactionTypeInternalTransfer || tx.ParsingError != nil {
+			finalTransactions = append(finalTransactions, tx)
+			continue
+		}
+
+		for _, t := range transactions {
+			if t == tx || t.Type != database.TransactionTypeInternalTransfer || t.ParsingError != nil {
+				continue
+			}
+
+			if lo.Contains(merged, t) || lo.Contains(finalTransactions, t) {
+				continue
+			}
+
+			if t.InternalTransferDirectionTo == tx.InternalTransferDirectionTo || !t.Date.Equal(tx.Date) {
+				continue
+			}
+
+			if t.SourceAccount != tx.SourceAccount || t.DestinationAccount != tx.DestinationAccount {
+				continue
+			}
+
+			if !tx.InternalTransferDirectionTo { // debit side knows the source, credit side the destination
+				if !t.SourceAmount.Equal(tx.SourceAmount) && t.SourceCurrency == tx.SourceCurrency {
+					continue
+				}
+
+				tx.DestinationAmount = t.DestinationAmount
+				tx.DestinationCurrency = t.DestinationCurrency
+			} else {
+				if !t.DestinationAmount.Equal(tx.DestinationAmount) && t.DestinationCurrency == tx.DestinationCurrency {
+					continue
+				}
+
+				tx.SourceAmount = t.SourceAmount
+				tx.SourceCurrency = t.SourceCurrency
+			}
+
+			tx.DuplicateTransactions = append(tx.DuplicateTransactions, t)
+			merged = append(merged, t)
+
+			break
+		}
+
+		finalTransactions = append(finalTransactions, tx)
+	}
+
+	return finalTransactions
+}
@@ -0,0 +1,485 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+const (
+	CsvSignNegativeExpense = "negative_expense" // default, negative amounts are expenses
+	CsvSignPositiveExpense = "positive_expense" // e.g. credit card statements
+)
+
+type CsvProfilesFile struct {
+	Profiles []*CsvProfile `json:"profiles" yaml:"profiles"`
+}
+
+type CsvProfile struct {
+	Source    database.TransactionSource `json:"source" yaml:"source"`
+	Delimiter string                     `json:"delimiter" yaml:"delimiter"`
+	Header    CsvHeader                  `json:"header" yaml:"header"`
+	Date      CsvDate                    `json:"date" yaml:"date"`
+	Amount    CsvAmount                  `json:"amount" yaml:"amount"`
+	Currency  CsvCurrency                `json:"currency" yaml:"currency"`
+	// Description, Account and Counterparty are go templates, see CsvRow for available fields.
+	Description  string `json:"description" yaml:"description"`
+	Account      string `json:"account" yaml:"account"`
+	Counterparty string `json:"counterparty" yaml:"counterparty"` // optional
+	// DedupColumns build the deduplication key, the whole row is used when empty.
+	DedupColumns []string `json:"dedupColumns" yaml:"dedupColumns"`
+}
+
+type CsvHeader struct {
+	// Columns detect the header row, it must contain all of them.
+	Columns []string `json:"columns" yaml:"columns"`
+}
+
+type CsvDate struct {
+	Column  string   `json:"column" yaml:"column"`
+	Layouts []string `json:"layouts" yaml:"layouts"`
+}
+
+type CsvAmount struct {
+	Column string `json:"column" yaml:"column"`
+	// DebitColumn and CreditColumn are used instead of Column by statements with separate columns.
+	DebitColumn        string `json:"debitColumn" yaml:"debitColumn"`
+	CreditColumn       string `json:"creditColumn" yaml:"creditColumn"`
+	Sign               string `json:"sign" yaml:"sign"`
+	DecimalSeparator   string `json:"decimalSeparator" yaml:"decimalSeparator"`
+	ThousandsSeparator string `json:"thousandsSeparator" yaml:"thousandsSeparator"`
+}
+
+type CsvCurrency struct {
+	Column string `json:"column" yaml:"column"`
+	Fixed  string `json:"fixed" yaml:"fixed"`
+}
+
+// CsvRow is passed to the description, account and counterparty templates.
+type CsvRow struct {
+	Source   database.TransactionSource
+	Currency string
+	Amount   string
+
+	columns map[string]string
+}
+
+// Col returns the value of the named column, e.g. {{ .Col "Payee" }}.
+func (r CsvRow) Col(name string) string {
+	return r.columns[name]
+}
+
+type ProfileCsv struct {
+	profile      *CsvProfile
+	delimiter    rune
+	description  *template.Template
+	account      *template.Template
+	counterparty *template.Template
+}
+
+func NewProfileCsv(profile *CsvProfile) (*ProfileCsv, error) {
+	if profile.Source == "" {
+		return nil, errors.New("profile source is required")
+	}
+
+	if len(profile.Header.Columns) == 0 {
+		return nil, errors.Newf("header columns are required in profile %s", profile.Source)
+	}
+
+	if profile.Date.Column == "" || len(profile.Date.Layouts) == 0 {
+		return nil, errors.Newf("date column and layouts are required in profile %s", profile.Source)
+	}
+
+	if profile.Amount.Column == "" && (profile.Amount.DebitColumn == "" || profile.Amount.CreditColumn == "") {
+		return nil, errors.Newf("amount column or debit and credit columns are required in profile %s",
+			profile.Source)
+	}
+
+	switch profile.Amount.Sign {
+	case "":
+		profile.Amount.Sign = CsvSignNegativeExpense
+	case CsvSignNegativeExpense, CsvSignPositiveExpense:
+	default:
+		return nil, errors.Newf("unknown amount sign %s in profile %s", profile.Amount.Sign, profile.Source)
+	}
+
+	if profile.Currency.Column == "" && profile.Currency.Fixed == "" {
+		return nil, errors.Newf("currency column or fixed currency is required in profile %s", profile.Source)
+	}
+
+	p := &ProfileCsv{
+		profile:   profile,
+		delimiter: ',',
+	}
+
+	if profile.Delimiter != "" {
+		if utf8.RuneCountInString(profile.Delimiter) != 1 {
+			return nil, errors.Newf("delimiter must be a single character in profile %s", profile.Source)
+		}
+
+		p.delimiter, _ = utf8.DecodeRuneInString(profile.Delimiter)
+	}
+
+	descriptionTemplate := profile.Description
+	if descriptionTemplate == "" {
+		descriptionTemplate = "{{ .Source }}"
+	}
+
+	accountTemplate := profile.Account
+	if accountTemplate == "" {
+		accountTemplate = "{{ .Source }}_{{ .Currency }}"
+	}
+
+	var err error
+
+	if p.description, err = template.New("description").Parse(descriptionTemplate); err != nil {
+		return nil, errors.Wrapf(err, "invalid description template in profile %s", profile.Source)
+	}
+
+	if p.account, err = template.New("account").Parse(accountTemplate); err != nil {
+		return nil, errors.Wrapf(err, "invalid account template in profile %s", profile.Source)
+	}
+
+	if profile.Counterparty != "" {
+		if p.counterparty, err = template.New("counterparty").Parse(profile.Counterparty); err != nil {
+			return nil, errors.Wrapf(err, "invalid counterparty template in profile %s", profile.Source)
+		}
+	}
+
+	return p, nil
+}
+
+// LoadCsvProfiles reads profiles from a json file, any other extension is parsed as yaml.
+func LoadCsvProfiles(path string) ([]*ProfileCsv, error) {
+	var file CsvProfilesFile
+
+	if err := common.UnmarshalFile(path, "csv profiles", &file); err != nil {
+		return nil, err
+	}
+
+	var parsers []*ProfileCsv
+
+	for _, profile := range file.Profiles {
+		p, profileErr := NewProfileCsv(profile)
+		if profileErr != nil {
+			return nil, profileErr
+		}
+
+		parsers = append(parsers, p)
+	}
+
+	return parsers, nil
+}
+
+func (p *ProfileCsv) Type() database.TransactionSource {
+	return p.profile.Source
+}
+
+func (p *ProfileCsv) SplitExcel(ctx context.Context, data []byte) ([][]byte, error) {
+	return p.SplitCsv(ctx, data)
+}
+
+// SplitCsv keeps the header in every chunk, columns are resolved by name.
+func (p *ProfileCsv) SplitCsv(
+	_ context.Context,
+	data []byte,
+) ([][]byte, error) {
+	linesData, err := p.read(data)
+	if err != nil {
+		return nil, err
+	}
+
+	headerIndex := p.headerIndex(linesData)
+	if headerIndex < 0 {
+		return nil, errors.New("header not found")
+	}
+
+	header := linesData[headerIndex]
+
+	var resultFiles [][]byte
+	for i := headerIndex + 1; i < len(linesData); i++ {
+		if p.isEmpty(linesData[i]) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		writer.Comma = p.delimiter
+
+		if err = writer.WriteAll([][]string{header, linesData[i]}); err != nil {
+			return nil, err
+		}
+
+		writer.Flush()
+
+		resultFiles = append(resultFiles, buf.Bytes())
+	}
+
+	if len(resultFiles) == 0 {
+		return nil, errors.New("empty file")
+	}
+
+	return resultFiles, nil
+}
+
+func (p *ProfileCsv) ParseMessages(
+	_ context.Context,
+	rawArr []*Record,
+) ([]*database.Transaction, error) {
+	var transactions []*database.Transaction
+
+	for _, raw := range rawArr {
+		rawCsv, err := hex.DecodeString(string(raw.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		tx := &database.Transaction{
+			ID:                uuid.NewString(),
+			Raw:               string(raw.Data),
+			OriginalMessage:   raw.Message,
+			TransactionSource: p.Type(),
+		}
+		transactions = append(transactions, tx)
+
+		linesData, err := p.read(rawCsv)
+		if err != nil {
+			tx.ParsingError = err
+			continue
+		}
+
+		headerIndex := p.headerIndex(linesData)
+		if headerIndex < 0 || headerIndex+1 >= len(linesData) {
+			tx.ParsingError = errors.New("header or row is missing")
+			continue
+		}
+
+		if parsingErr := p.parseTransaction(tx, linesData[headerIndex], linesData[headerIndex+1]); parsingErr != nil {
+			tx.ParsingError = parsingErr
+			continue
+		}
+	}
+
+	return transactions, nil
+}
+
+func (p *ProfileCsv) read(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.Comma = p.delimiter
+
+	return reader.ReadAll()
+}
+
+func (p *ProfileCsv) headerIndex(linesData [][]string) int {
+	for index, line := range linesData {
+		trimmed := lo.Map(line, func(item string, _ int) string {
+			return p.clean(item)
+		})
+
+		if lo.Every(trimmed, p.profile.Header.Columns) {
+			return index
+		}
+	}
+
+	return -1
+}
+
+func (p *ProfileCsv) isEmpty(line []string) bool {
+	return lo.EveryBy(line, func(item string) bool {
+		return p.clean(item) == ""
+	})
+}
+
+func (p *ProfileCsv) clean(input string) string {
+	return strings.TrimFunc(input, func(r rune) bool {
+		return unicode.IsSpace(r) || !unicode.IsGraphic(r)
+	})
+}
+
+func (p *ProfileCsv) parseTransaction(
+	tx *database.Transaction,
+	header []string,
+	data []string,
+) error {
+	columns := map[string]string{}
+	for i, name := range header {
+		if i < len(data) {
+			columns[p.clean(name)] = p.clean(data[i])
+		}
+	}
+
+	tx.Raw = strings.Join(data, string(p.delimiter))
+
+	date, err := p.parseDate(columns[p.profile.Date.Column])
+	if err != nil {
+		return err
+	}
+
+	tx.Date = date
+
+	amount, err := p.parseAmount(columns)
+	if err != nil {
+		return err
+	}
+
+	currency := p.profile.Currency.Fixed
+	if p.profile.Currency.Column != "" {
+		currency = columns[p.profile.Currency.Column]
+	}
+
+	if currency == "" {
+		return errors.New("currency is empty")
+	}
+
+	row := CsvRow{
+		Source:   p.Type(),
+		Currency: currency,
+		Amount:   amount.Abs().String(),
+		columns:  columns,
+	}
+
+	if tx.Description, err = p.execute(p.description, row); err != nil {
+		return err
+	}
+
+	account, err := p.execute(p.account, row)
+	if err != nil {
+		return err
+	}
+
+	if p.counterparty != nil {
+		if tx.Counterparty, err = p.execute(p.counterparty, row); err != nil {
+			return err
+		}
+	}
+
+	tx.DeduplicationKeys = []string{p.dedupKey(columns, data)}
+
+	isExpense := amount.LessThan(decimal.Zero)
+	if p.profile.Amount.Sign == CsvSignPositiveExpense {
+		isExpense = amount.GreaterThan(decimal.Zero)
+	}
+
+	if isExpense {
+		tx.Type = database.TransactionTypeExpense
+		tx.SourceAmount = amount.Abs()
+		tx.SourceCurrency = currency
+		tx.SourceAccount = account
+	} else {
+		tx.Type = database.TransactionTypeIncome
+		tx.DestinationAmount = amount.Abs()
+		tx.DestinationCurrency = currency
+		tx.DestinationAccount = account
+	}
+
+	return nil
+}
+
+func (p *ProfileCsv) parseDate(input string) (time.Time, error) {
+	var finalErr error
+
+	for _, layout := range p.profile.Date.Layouts {
+		t, err := time.Parse(layout, input)
+		if err == nil {
+			return t, nil
+		}
+
+		finalErr = errors.Join(finalErr, err)
+	}
+
+	return time.Time{}, errors.Wrapf(finalErr, "failed to parse date %s", input)
+}
+
+func (p *ProfileCsv) parseAmount(columns map[string]string) (decimal.Decimal, error) {
+	if p.profile.Amount.Column != "" {
+		return p.parseDecimal(columns[p.profile.Amount.Column])
+	}
+
+	var debit, credit decimal.Decimal
+	var err error
+
+	if raw := columns[p.profile.Amount.DebitColumn]; raw != "" {
+		if debit, err = p.parseDecimal(raw); err != nil {
+			return decimal.Zero, err
+		}
+	}
+
+	if raw := columns[p.profile.Amount.CreditColumn]; raw != "" {
+		if credit, err = p.parseDecimal(raw); err != nil {
+			return decimal.Zero, err
+		}
+	}
+
+	amount := credit.Abs().Sub(debit.Abs())
+	if p.profile.Amount.Sign == CsvSignPositiveExpense {
+		amount = amount.Neg()
+	}
+
+	return amount, nil
+}
+
+func (p *ProfileCsv) parseDecimal(input string) (decimal.Decimal, error) {
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+
+		return r
+	}, input)
+
+	if p.profile.Amount.ThousandsSeparator != "" {
+		normalized = strings.ReplaceAll(normalized, p.profile.Amount.ThousandsSeparator, "")
+	}
+
+	if p.profile.Amount.DecimalSeparator != "" && p.profile.Amount.DecimalSeparator != "." {
+		normalized = strings.ReplaceAll(normalized, p.profile.Amount.DecimalSeparator, ".")
+	}
+
+	amount, err := decimal.NewFromString(normalized)
+	if err != nil {
+		return decimal.Zero, errors.Wrapf(err, "failed to parse amount %s", input)
+	}
+
+	return amount, nil
+}
+
+func (p *ProfileCsv) execute(tmpl *template.Template, row CsvRow) (string, error) {
+	var sb strings.Builder
+
+	if err := tmpl.Execute(&sb, row); err != nil {
+		return "", errors.Wrapf(err, "failed to execute %s template", tmpl.Name())
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func (p *ProfileCsv) dedupKey(columns map[string]string, data []string) string {
+	if len(p.profile.DedupColumns) == 0 {
+		return string(p.Type()) + "_" + strings.Join(data, "_")
+	}
+
+	values := []string{string(p.Type())}
+	for _, column := range p.profile.DedupColumns {
+		values = append(values, columns[column])
+	}
+
+	return strings.Join(values, "_")
+}
@@ -0,0 +1,150 @@
+package parser_test
+
+import (
+	"context"
+	_ "embed"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+)
+
+//go:embed testdata/csvprofile/ing.csv
+var csvProfileIng []byte
+
+//go:embed testdata/csvprofile/amex.csv
+var csvProfileAmex []byte
+
+func loadCsvProfiles(t *testing.T) map[database.TransactionSource]*parser.ProfileCsv {
+	parsers, err := parser.LoadCsvProfiles("testdata/csvprofile/profiles.yaml")
+	assert.NoError(t, err)
+
+	result := map[database.TransactionSource]*parser.ProfileCsv{}
+	for _, p := range parsers {
+		result[p.Type()] = p
+	}
+
+	return result
+}
+
+func parseCsvProfile(t *testing.T, p *parser.ProfileCsv, data []byte) []*database.Transaction {
+	split, err := p.SplitExcel(context.TODO(), data)
+	assert.NoError(t, err)
+
+	var records []*parser.Record
+	for _, chunk := range split {
+		records = append(records, &parser.Record{
+			Data: []byte(hex.EncodeToString(chunk)),
+		})
+	}
+
+	resp, err := p.ParseMessages(context.TODO(), records)
+	assert.NoError(t, err)
+
+	return resp
+}
+
+func TestCsvProfileSemicolonWithHeaderOffset(t *testing.T) {
+	p := loadCsvProfiles(t)[database.TransactionSource("ing")]
+
+	resp := parseCsvProfile(t, p, csvProfileIng)
+	assert.Len(t, resp, 2)
+
+	expense := resp[0]
+	assert.NoError(t, expense.ParsingError)
+	assert.EqualValues(t, "ing", expense.TransactionSource)
+	assert.Equal(t, database.TransactionTypeExpense, expense.Type)
+	assert.Equal(t, "1234.56", expense.SourceAmount.StringFixed(2))
+	assert.Equal(t, "PLN", expense.SourceCurrency)
+	assert.Equal(t, "ing_PLN", expense.SourceAccount)
+	assert.Equal(t, "Biedronka Zakupy", expense.Description)
+	assert.Equal(t, "Biedronka", expense.Counterparty)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), expense.Date)
+	assert.Equal(t, []string{"ing_TX-1"}, expense.DeduplicationKeys)
+
+	income := resp[1]
+	assert.NoError(t, income.ParsingError)
+	assert.Equal(t, database.TransactionTypeIncome, income.Type)
+	assert.Equal(t, "5000.00", income.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "PLN", income.DestinationCurrency)
+	assert.Equal(t, "ing_PLN", income.DestinationAccount)
+	assert.Equal(t, time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC), income.Date)
+}
+
+func TestCsvProfileDebitCredit(t *testing.T) {
+	p := loadCsvProfiles(t)[database.TransactionSource("amex")]
+
+	resp := parseCsvProfile(t, p, csvProfileAmex)
+	assert.Len(t, resp, 2)
+
+	assert.NoError(t, resp[0].ParsingError)
+	assert.Equal(t, database.TransactionTypeExpense, resp[0].Type)
+	assert.Equal(t, "4.50", resp[0].SourceAmount.StringFixed(2))
+	assert.Equal(t, "USD", resp[0].SourceCurrency)
+	assert.Equal(t, "amex_USD", resp[0].SourceAccount)
+	assert.Equal(t, "COFFEE SHOP", resp[0].Description)
+	assert.Empty(t, resp[0].Counterparty)
+	assert.Equal(t, []string{"amex_10/03/2026_COFFEE SHOP_4.50_"}, resp[0].DeduplicationKeys)
+
+	assert.NoError(t, resp[1].ParsingError)
+	assert.Equal(t, database.TransactionTypeIncome, resp[1].Type)
+	assert.Equal(t, "100.00", resp[1].DestinationAmount.StringFixed(2))
+	assert.Equal(t, "amex_USD", resp[1].DestinationAccount)
+}
+
+func TestCsvProfilePositiveExpense(t *testing.T) {
+	p, err := parser.NewProfileCsv(&parser.CsvProfile{
+		Source: "card",
+		Header: parser.CsvHeader{Columns: []string{"Date", "Amount"}},
+		Date:   parser.CsvDate{Column: "Date", Layouts: []string{"2006-01-02"}},
+		Amount: parser.CsvAmount{Column: "Amount", Sign: parser.CsvSignPositiveExpense},
+		Currency: parser.CsvCurrency{
+			Fixed: "EUR",
+		},
+	})
+	assert.NoError(t, err)
+
+	resp := parseCsvProfile(t, p, []byte("Date,Amount\n2026-10-01,12.00\n2026-10-02,-3.00\n"))
+	assert.Len(t, resp, 2)
+
+	assert.Equal(t, database.TransactionTypeExpense, resp[0].Type)
+	assert.Equal(t, "card_EUR", resp[0].SourceAccount)
+	assert.Equal(t, database.TransactionTypeIncome, resp[1].Type)
+	assert.Equal(t, "3.00", resp[1].DestinationAmount.StringFixed(2))
+}
+
+func TestCsvProfileInvalidRow(t *testing.T) {
+	p := loadCsvProfiles(t)[database.TransactionSource("ing")]
+
+	resp := parseCsvProfile(t, p, []byte("Data transakcji;Kwota;Waluta\nyesterday;1,00;PLN\n"))
+	assert.Len(t, resp, 1)
+	assert.ErrorContains(t, resp[0].ParsingError, "failed to parse date yesterday")
+}
+
+func TestCsvProfileValidation(t *testing.T) {
+	_, err := parser.NewProfileCsv(&parser.CsvProfile{Source: "broken"})
+	assert.ErrorContains(t, err, "header columns are required")
+
+	_, err = parser.NewProfileCsv(&parser.CsvProfile{
+		Source:   "broken",
+		Header:   parser.CsvHeader{Columns: []string{"Date"}},
+		Date:     parser.CsvDate{Column: "Date", Layouts: []string{"2006-01-02"}},
+		Amount:   parser.CsvAmount{Column: "Amount", Sign: "sideways"},
+		Currency: parser.CsvCurrency{Fixed: "EUR"},
+	})
+	assert.ErrorContains(t, err, "unknown amount sign sideways")
+
+	_, err = parser.NewProfileCsv(&parser.CsvProfile{
+		Source:      "broken",
+		Header:      parser.CsvHeader{Columns: []string{"Date"}},
+		Date:        parser.CsvDate{Column: "Date", Layouts: []string{"2006-01-02"}},
+		Amount:      parser.CsvAmount{Column: "Amount"},
+		Currency:    parser.CsvCurrency{Fixed: "EUR"},
+		Description: "{{ .Col ",
+	})
+	assert.ErrorContains(t, err, "invalid description template")
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/csv"
 	"encoding/hex"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 	"unicode"
@@ -14,10 +15,33 @@ import (
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 
-	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 )
 
+const (
+	monoDefaultCurrency = "UAH"
+	monoHeaderPrefix    = "Дата"
+)
+
+var (
+	monoCardCurrencyRegex = regexp.MustCompile(`\(([A-Z]{3})\)`)
+
+	// statement details for transfers between own mono cards and accounts
+	monoOwnTransferPrefixes = []string{
+		"з білої картки",
+		"з чорної картки",
+		"на білу картку",
+		"на чорну картку",
+		"з гривневого рахунку",
+		"на гривневий рахунок",
+		"з доларового рахунку",
+		"на доларовий рахунок",
+		"з єврового рахунку",
+		"на євровий рахунок",
+		"переказ між власними рахунками",
+	}
+)
+
 type Mono struct {
 }
 
@@ -37,6 +61,7 @@ func (m *Mono) AccountName(input string) string {
 	return fmt.Sprintf("mono_%s", input)
 }
 
+// SplitCsv keeps the header in every chunk, as the card currency is only present there.
 func (m *Mono) SplitCsv(
 	_ context.Context,
 	data []byte,
@@ -54,6 +79,7 @@ func (m *Mono) SplitCsv(
 	}
 
 	headerIndex := 1
+	header := linesData[0]
 
 	var resultFiles [][]byte
 	for i := headerIndex; i < len(linesData); i++ {
@@ -68,7 +94,7 @@ func (m *Mono) SplitCsv(
 
 		var buf bytes.Buffer
 		writer := csv.NewWriter(&buf)
-		if err = writer.WriteAll(linesData[i : i+1]); err != nil {
+		if err = writer.WriteAll([][]string{header, linesData[i]}); err != nil {
 			return nil, err
 		}
 
@@ -92,9 +118,6 @@ func (m *Mono) ParseMessages(
 			return nil, err
 		}
 
-		reader := csv.NewReader(bytes.NewReader(rawCsv))
-		reader.FieldsPerRecord = -1
-
 		tx := &database.Transaction{
 			ID:                uuid.NewString(),
 			Raw:               string(raw.Data),
@@ -103,6 +126,17 @@ func (m *Mono) ParseMessages(
 		}
 		transactions = append(transactions, tx)
 
+		if m.isWebhook(rawCsv) {
+			if err = m.parseWebhook(tx, rawCsv, m.defaultCurrency(raw)); err != nil {
+				tx.ParsingError = err
+			}
+
+			continue
+		}
+
+		reader := csv.NewReader(bytes.NewReader(rawCsv))
+		reader.FieldsPerRecord = -1
+
 		linesData, err := reader.ReadAll()
 		if err != nil {
 			tx.ParsingError = err
@@ -114,9 +148,23 @@ func (m *Mono) ParseMessages(
 			continue
 		}
 
-		tx.Raw = strings.Join(linesData[0], ",")
+		cardCurrency := m.defaultCurrency(raw) // messages stored before the header was kept
+		row := linesData[0]
 
-		additionalTx, parsingErr := m.parseTransaction(tx, linesData[0])
+		if m.isHeader(row) {
+			cardCurrency = m.cardCurrency(row, cardCurrency)
+
+			if len(linesData) < 2 {
+				tx.ParsingError = errors.New("row is missing")
+				continue
+			}
+
+			row = linesData[1]
+		}
+
+		tx.Raw = strings.Join(row, ",")
+
+		additionalTx, parsingErr := m.parseTransaction(tx, row, cardCurrency)
 		if parsingErr != nil {
 			tx.ParsingError = parsingErr
 			continue
@@ -125,12 +173,73 @@ func (m *Mono) ParseMessages(
 		transactions = append(transactions, additionalTx...)
 	}
 
-	return transactions, nil
+	return mergeOwnTransfers(transactions), nil
+}
+
+// defaultCurrency is the card currency when the statement does not carry it.
+func (m *Mono) defaultCurrency(raw *Record) string {
+	if raw.Currency != "" {
+		return raw.Currency
+	}
+
+	return monoDefaultCurrency
+}
+
+func (m *Mono) isHeader(row []string) bool {
+	if len(row) == 0 {
+		return false
+	}
+
+	return strings.HasPrefix(strings.TrimFunc(row[0], func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}), monoHeaderPrefix)
+}
+
+func (m *Mono) cardCurrency(header []string, fallback string) string {
+	if len(header) < 4 {
+		return fallback
+	}
+
+	matches := monoCardCurrencyRegex.FindStringSubmatch(header[3])
+	if len(matches) != 2 {
+		return fallback
+	}
+
+	return matches[1]
+}
+
+func (m *Mono) isOwnTransfer(description string) bool {
+	lower := strings.ToLower(description)
+
+	for _, prefix := range monoOwnTransferPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownTransferCredit marks the receiving side of a transfer between own cards, the sending card is
+// known by the operation currency only.
+func (m *Mono) ownTransferCredit(tx *database.Transaction, operationAmount decimal.Decimal, operationCurrency string) {
+	tx.Type = database.TransactionTypeInternalTransfer
+	tx.InternalTransferDirectionTo = true
+	tx.SourceAccount = m.AccountName(operationCurrency)
+	tx.SourceAmount = operationAmount.Abs()
+	tx.SourceCurrency = operationCurrency
+}
+
+// ownTransferDebit marks the sending side of a transfer between own cards.
+func (m *Mono) ownTransferDebit(tx *database.Transaction, operationCurrency string) {
+	tx.Type = database.TransactionTypeInternalTransfer
+	tx.DestinationAccount = m.AccountName(operationCurrency)
 }
 
 func (m *Mono) parseTransaction(
 	tx *database.Transaction,
 	data []string,
+	cardCurrency string,
 ) ([]*database.Transaction, error) {
 	if len(data) < 8 {
 		return nil, errors.Newf("expected len > 8, got %d", len(data))
@@ -145,42 +254,62 @@ func (m *Mono) parseTransaction(
 		return nil, errors.Wrapf(timeErr, "failed to parse operation time %s", data[0])
 	}
 
-	mcc := data[2]
+	tx.MCC = data[2]
+	tx.Date = operationTime
+	tx.Description = data[1]
+	tx.Counterparty = data[1]
 
 	tx.DeduplicationKeys = []string{
 		strings.Join(data, "_"),
 	}
 
-	switch mcc {
-	default:
-		//case "5262":
-		tx.Type = database.TransactionTypeExpense
-		tx.Date = operationTime
+	cardAmount, err := decimal.NewFromString(data[3])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse source amount %s", data[3])
+	}
 
-		sourceAmount, err := decimal.NewFromString(data[3])
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to parse source amount %s", data[3])
-		}
+	operationAmount, err := decimal.NewFromString(data[4])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse dest amount %s", data[4])
+	}
 
-		if sourceAmount.GreaterThan(decimal.Zero) {
-			return nil, errors.WithStack(common.ErrOperationNotSupported)
+	operationCurrency := data[5]
+	isOwnTransfer := m.isOwnTransfer(tx.Description)
+
+	if len(data) > 9 {
+		if balance, balanceErr := decimal.NewFromString(strings.TrimSpace(data[9])); balanceErr == nil {
+			tx.Balance = &database.Balance{
+				Account:  m.AccountName(cardCurrency),
+				Amount:   balance,
+				Currency: cardCurrency,
+			}
 		}
+	}
 
-		destAmount, err := decimal.NewFromString(data[4])
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to parse dest amount %s", data[4])
+	if cardAmount.GreaterThan(decimal.Zero) {
+		tx.Type = database.TransactionTypeIncome
+		if isOwnTransfer {
+			m.ownTransferCredit(tx, operationAmount, operationCurrency)
 		}
 
-		tx.SourceAmount = sourceAmount.Abs()
-		tx.SourceCurrency = "UAH"               // todo
-		tx.SourceAccount = m.AccountName("UAH") // todo
+		tx.DestinationAmount = cardAmount.Abs()
+		tx.DestinationCurrency = cardCurrency
+		tx.DestinationAccount = m.AccountName(cardCurrency)
 
-		tx.DestinationAmount = destAmount.Abs()
-		tx.DestinationCurrency = data[5]
-		tx.Description = data[1]
-		//default:
-		//	return nil, errors.Newf("unexpected MCC %s", mcc)
+		return nil, nil
 	}
 
+	tx.Type = database.TransactionTypeExpense
+	if isOwnTransfer {
+		m.ownTransferDebit(tx, operationCurrency)
+	}
+
+	tx.SourceAmount = cardAmount.Abs()
+	tx.SourceCurrency = cardCurrency
+	tx.SourceAccount = m.AccountName(cardCurrency)
+
+	tx.DestinationAmount = operationAmount.Abs()
+	tx.DestinationCurrency = operationCurrency
+
 	return nil, nil
 }
@@ -4,17 +4,40 @@ import (
 	"context"
 	_ "embed"
 	"encoding/hex"
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
 )
 
 //go:embed testdata/mono/chargeoff.csv
 var monoChargeOff []byte
 
+//go:embed testdata/mono/income.csv
+var monoIncome []byte
+
+//go:embed testdata/mono/usd_card.csv
+var monoUsdCard []byte
+
+//go:embed testdata/mono/own_transfer_uah.csv
+var monoOwnTransferUah []byte
+
+//go:embed testdata/mono/own_transfer_usd.csv
+var monoOwnTransferUsd []byte
+
+//go:embed testdata/mono/webhook_expense.json
+var monoWebhookExpense []byte
+
+//go:embed testdata/mono/webhook_foreign.json
+var monoWebhookForeign []byte
+
+//go:embed testdata/mono/webhook_income.json
+var monoWebhookIncome []byte
+
 func TestParseMonoSimpleExpense(t *testing.T) {
 	mono := parser.NewMono()
 
@@ -38,3 +61,237 @@ func TestParseMonoSimpleExpense(t *testing.T) {
 	assert.EqualValues(t, "PLN", txs[0].DestinationCurrency)
 	assert.EqualValues(t, "128.71", txs[0].DestinationAmount.StringFixed(2))
 }
+
+func parseMonoFiles(t *testing.T, files ...[]byte) []*database.Transaction {
+	mono := parser.NewMono()
+
+	var records []*parser.Record
+	for _, file := range files {
+		resp, err := mono.SplitExcel(context.TODO(), file)
+		assert.NoError(t, err)
+
+		for _, r := range resp {
+			records = append(records, &parser.Record{
+				Data: []byte(hex.EncodeToString(r)),
+			})
+		}
+	}
+
+	txs, err := mono.ParseMessages(context.TODO(), records)
+	assert.NoError(t, err)
+
+	return txs
+}
+
+func TestParseMonoIncome(t *testing.T) {
+	txs := parseMonoFiles(t, monoIncome)
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, database.TransactionTypeIncome, txs[0].Type)
+	assert.EqualValues(t, "Від: Іван Петренко", txs[0].Description)
+	assert.EqualValues(t, "4829", txs[0].MCC)
+	assert.EqualValues(t, "UAH", txs[0].DestinationCurrency)
+	assert.EqualValues(t, "mono_UAH", txs[0].DestinationAccount)
+	assert.EqualValues(t, "1500.00", txs[0].DestinationAmount.StringFixed(2))
+	assert.Empty(t, txs[0].SourceAccount)
+
+	if assert.NotNil(t, txs[0].Balance) {
+		assert.EqualValues(t, "mono_UAH", txs[0].Balance.Account)
+		assert.EqualValues(t, "2731.79", txs[0].Balance.Amount.StringFixed(2))
+	}
+}
+
+func TestParseMonoCurrencyCard(t *testing.T) {
+	txs := parseMonoFiles(t, monoUsdCard)
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, database.TransactionTypeExpense, txs[0].Type)
+	assert.EqualValues(t, "USD", txs[0].SourceCurrency)
+	assert.EqualValues(t, "mono_USD", txs[0].SourceAccount)
+	assert.EqualValues(t, "15.49", txs[0].SourceAmount.StringFixed(2))
+	assert.EqualValues(t, "4899", txs[0].MCC)
+}
+
+func TestParseMonoOwnTransfer(t *testing.T) {
+	txs := parseMonoFiles(t, monoOwnTransferUah, monoOwnTransferUsd)
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, database.TransactionTypeInternalTransfer, txs[0].Type)
+
+	assert.EqualValues(t, "mono_UAH", txs[0].SourceAccount)
+	assert.EqualValues(t, "UAH", txs[0].SourceCurrency)
+	assert.EqualValues(t, "4150.00", txs[0].SourceAmount.StringFixed(2))
+
+	assert.EqualValues(t, "mono_USD", txs[0].DestinationAccount)
+	assert.EqualValues(t, "USD", txs[0].DestinationCurrency)
+	assert.EqualValues(t, "100.00", txs[0].DestinationAmount.StringFixed(2))
+
+	assert.Len(t, txs[0].DuplicateTransactions, 1)
+}
+
+func TestParseMonoLegacyMessage(t *testing.T) { // stored before the header was kept in the message
+	mono := parser.NewMono()
+
+	txs, err := mono.ParseMessages(context.TODO(), []*parser.Record{
+		{
+			Data: []byte(hex.EncodeToString([]byte("11.08.2024 12:19:14,Списання Allegro,5262,-1231.79,-128.71,PLN,10.8096,—,—,\n"))),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, "mono_UAH", txs[0].SourceAccount)
+	assert.Equal(t, []string{"11.08.2024 12:19:14_Списання Allegro_5262_-1231.79_-128.71_PLN_10.8096_—_—_"},
+		txs[0].DeduplicationKeys)
+}
+
+func TestParseMonoLegacyMessageChatCurrency(t *testing.T) {
+	mono := parser.NewMono()
+
+	txs, err := mono.ParseMessages(context.TODO(), []*parser.Record{
+		{
+			Data:     []byte(hex.EncodeToString([]byte("11.08.2024 12:19:14,Списання Allegro,5262,-31.79,-128.71,PLN,4.05,—,—,\n"))),
+			Currency: "EUR",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, "mono_EUR", txs[0].SourceAccount)
+	assert.EqualValues(t, "EUR", txs[0].SourceCurrency)
+}
+
+func TestParseMonoOwnTransferSingleSide(t *testing.T) {
+	txs := parseMonoFiles(t, monoOwnTransferUsd)
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, database.TransactionTypeInternalTransfer, txs[0].Type)
+	assert.True(t, txs[0].InternalTransferDirectionTo)
+	assert.EqualValues(t, "mono_UAH", txs[0].SourceAccount)
+	assert.EqualValues(t, "4150.00", txs[0].SourceAmount.StringFixed(2))
+	assert.EqualValues(t, "mono_USD", txs[0].DestinationAccount)
+	assert.EqualValues(t, "100.00", txs[0].DestinationAmount.StringFixed(2))
+}
+
+func parseMonoWebhooks(t *testing.T, payloads ...[]byte) []*database.Transaction {
+	var records []*parser.Record
+	for _, payload := range payloads {
+		records = append(records, &parser.Record{
+			Data: []byte(hex.EncodeToString(payload)),
+		})
+	}
+
+	txs, err := parser.NewMono().ParseMessages(context.TODO(), records)
+	assert.NoError(t, err)
+
+	return txs
+}
+
+func TestParseMonoWebhookExpense(t *testing.T) {
+	txs := parseMonoWebhooks(t, monoWebhookExpense, monoWebhookForeign)
+	assert.Len(t, txs, 2)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, database.TransactionTypeExpense, txs[0].Type)
+	assert.EqualValues(t, "Сільпо", txs[0].Description)
+	assert.EqualValues(t, "5411", txs[0].MCC)
+	assert.EqualValues(t, "mono_UAH", txs[0].SourceAccount)
+	assert.EqualValues(t, "UAH", txs[0].SourceCurrency)
+	assert.EqualValues(t, "452.50", txs[0].SourceAmount.StringFixed(2))
+	assert.EqualValues(t, "UAH", txs[0].DestinationCurrency)
+	assert.EqualValues(t, "452.50", txs[0].DestinationAmount.StringFixed(2))
+	assert.Equal(t, time.Unix(1760000000, 0).UTC(), txs[0].Date)
+	assert.Equal(t, []string{"ZuHWzqkKGVo="}, txs[0].DeduplicationKeys)
+
+	if assert.NotNil(t, txs[0].Balance) {
+		assert.EqualValues(t, "mono_UAH", txs[0].Balance.Account)
+		assert.EqualValues(t, "12547.50", txs[0].Balance.Amount.StringFixed(2))
+		assert.EqualValues(t, "UAH", txs[0].Balance.Currency)
+	}
+
+	assert.NoError(t, txs[1].ParsingError)
+	assert.EqualValues(t, "4895.31", txs[1].SourceAmount.StringFixed(2))
+	assert.EqualValues(t, "UAH", txs[1].SourceCurrency)
+	assert.EqualValues(t, "100.00", txs[1].DestinationAmount.StringFixed(2))
+	assert.EqualValues(t, "EUR", txs[1].DestinationCurrency)
+	assert.Equal(t, []string{"Qw1Er2Ty3Ui="}, txs[1].DeduplicationKeys)
+}
+
+func TestParseMonoWebhookCurrencyAccount(t *testing.T) {
+	var webhook parser.MonoWebhook
+	assert.NoError(t, json.Unmarshal(monoWebhookIncome, &webhook))
+
+	webhook.Data.Currency = "USD" // set by the server from MONO_ACCOUNTS
+
+	payload, err := json.Marshal(webhook)
+	assert.NoError(t, err)
+
+	txs := parseMonoWebhooks(t, payload)
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, database.TransactionTypeIncome, txs[0].Type)
+	assert.EqualValues(t, "mono_USD", txs[0].DestinationAccount)
+	assert.EqualValues(t, "USD", txs[0].DestinationCurrency)
+	assert.EqualValues(t, "150.00", txs[0].DestinationAmount.StringFixed(2))
+	assert.EqualValues(t, "ІВАН ПЕТРЕНКО", txs[0].OriginalNadawcaName)
+	assert.Equal(t, []string{"Mn0Bv9Cx8Za="}, txs[0].DeduplicationKeys)
+}
+
+func TestParseMonoWebhookChatCurrency(t *testing.T) {
+	txs, err := parser.NewMono().ParseMessages(context.TODO(), []*parser.Record{
+		{
+			Data:     []byte(hex.EncodeToString(monoWebhookIncome)),
+			Currency: "EUR",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, "mono_EUR", txs[0].DestinationAccount)
+	assert.EqualValues(t, "EUR", txs[0].DestinationCurrency)
+}
+
+func TestParseMonoWebhookZeroDecimalCurrency(t *testing.T) {
+	txs := parseMonoWebhooks(t, []byte(`{"type":"StatementItem","data":{"statementItem":`+
+		`{"id":"Jp1","time":1760000000,"description":"LAWSON","mcc":5411,"amount":-41250,`+
+		`"operationAmount":-1500,"currencyCode":392,"balance":100000}}}`))
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, "412.50", txs[0].SourceAmount.StringFixed(2))
+	assert.EqualValues(t, "UAH", txs[0].SourceCurrency)
+	assert.EqualValues(t, "1500", txs[0].DestinationAmount.String())
+	assert.EqualValues(t, "JPY", txs[0].DestinationCurrency)
+	assert.EqualValues(t, "1000.00", txs[0].Balance.Amount.StringFixed(2))
+}
+
+func TestParseMonoWebhookHold(t *testing.T) { // mono sends card payments once, still on hold
+	txs := parseMonoWebhooks(t, []byte(`{"type":"StatementItem","data":{"statementItem":`+
+		`{"id":"Hd1","time":1760000000,"description":"Сільпо","hold":true,"amount":-100,`+
+		`"operationAmount":-100,"currencyCode":980}}}`))
+	assert.Len(t, txs, 1)
+
+	assert.NoError(t, txs[0].ParsingError)
+	assert.EqualValues(t, "1.00", txs[0].SourceAmount.StringFixed(2))
+}
+
+func TestParseMonoWebhookInvalid(t *testing.T) {
+	txs := parseMonoWebhooks(t,
+		[]byte(`{"type":"Other"}`),
+		[]byte(`{"type":"StatementItem","data":{"statementItem":{"id":"1","currencyCode":1}}}`),
+		[]byte(`{"type":`),
+	)
+	assert.Len(t, txs, 3)
+
+	assert.ErrorContains(t, txs[0].ParsingError, "unsupported mono webhook type Other")
+	assert.ErrorContains(t, txs[1].ParsingError, "unknown currency code 1")
+	assert.ErrorContains(t, txs[2].ParsingError, "failed to parse mono webhook")
+}
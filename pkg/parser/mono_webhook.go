@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+const MonoWebhookStatementItem = "StatementItem"
+
+// monoCurrencies maps ISO 4217 numeric codes used by the mono api.
+var monoCurrencies = map[int]string{
+	36:  "AUD",
+	51:  "AMD",
+	124: "CAD",
+	156: "CNY",
+	203: "CZK",
+	208: "DKK",
+	348: "HUF",
+	376: "ILS",
+	392: "JPY",
+	398: "KZT",
+	498: "MDL",
+	578: "NOK",
+	752: "SEK",
+	756: "CHF",
+	784: "AED",
+	826: "GBP",
+	840: "USD",
+	933: "BYN",
+	944: "AZN",
+	946: "RON",
+	949: "TRY",
+	975: "BGN",
+	978: "EUR",
+	980: "UAH",
+	981: "GEL",
+	985: "PLN",
+}
+
+// monoMinorUnits are the ISO 4217 minor units of currencies without two decimals.
+var monoMinorUnits = map[string]int32{
+	"JPY": 0,
+}
+
+// monoAmount converts an amount in minor units of the currency.
+func monoAmount(amount int64, currency string) decimal.Decimal {
+	exp, ok := monoMinorUnits[currency]
+	if !ok {
+		exp = 2
+	}
+
+	return decimal.New(amount, -exp)
+}
+
+// MonoWebhook is the payload pushed by the mono personal api, see https://api.monobank.ua/docs/.
+type MonoWebhook struct {
+	Type string          `json:"type"`
+	Data MonoWebhookData `json:"data"`
+}
+
+type MonoWebhookData struct {
+	Account       string            `json:"account"`
+	StatementItem MonoStatementItem `json:"statementItem"`
+	// Currency of the account, not part of the mono payload. Set by the importer, the chat currency
+	// or UAH when empty.
+	Currency string `json:"currency,omitempty"`
+}
+
+// MonoStatementItem amounts are in minor units, Amount and Balance in the account currency,
+// OperationAmount in CurrencyCode.
+type MonoStatementItem struct {
+	ID              string `json:"id"`
+	Time            int64  `json:"time"`
+	Description     string `json:"description"`
+	MCC             int    `json:"mcc"`
+	OriginalMCC     int    `json:"originalMcc"`
+	Hold            bool   `json:"hold"`
+	Amount          int64  `json:"amount"`
+	OperationAmount int64  `json:"operationAmount"`
+	CurrencyCode    int    `json:"currencyCode"`
+	CommissionRate  int64  `json:"commissionRate"`
+	CashbackAmount  int64  `json:"cashbackAmount"`
+	Balance         int64  `json:"balance"`
+	Comment         string `json:"comment"`
+	ReceiptID       string `json:"receiptId"`
+	CounterIban     string `json:"counterIban"`
+	CounterName     string `json:"counterName"`
+}
+
+func (m *Mono) isWebhook(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+func (m *Mono) parseWebhook(tx *database.Transaction, data []byte, defaultCurrency string) error {
+	var webhook MonoWebhook
+
+	if err := json.Unmarshal(data, &webhook); err != nil {
+		return errors.Wrap(err, "failed to parse mono webhook")
+	}
+
+	if webhook.Type != MonoWebhookStatementItem {
+		return errors.Newf("unsupported mono webhook type %s", webhook.Type)
+	}
+
+	item := webhook.Data.StatementItem
+	if item.ID == "" {
+		return errors.New("statement item id is missing")
+	}
+
+	operationCurrency, ok := monoCurrencies[item.CurrencyCode]
+	if !ok {
+		return errors.Newf("unknown currency code %d", item.CurrencyCode)
+	}
+
+	cardCurrency := webhook.Data.Currency
+	if cardCurrency == "" {
+		cardCurrency = defaultCurrency
+	}
+
+	tx.Raw = string(data)
+	tx.Date = time.Unix(item.Time, 0).UTC()
+	tx.MCC = fmt.Sprint(item.MCC)
+	tx.Description = strings.TrimSpace(item.Description)
+	tx.OriginalNadawcaName = strings.TrimSpace(item.CounterName)
+	tx.Counterparty = tx.OriginalNadawcaName
+
+	if tx.Counterparty == "" {
+		tx.Counterparty = tx.Description
+	}
+	tx.DeduplicationKeys = []string{item.ID}
+
+	cardAmount := monoAmount(item.Amount, cardCurrency)
+	operationAmount := monoAmount(item.OperationAmount, operationCurrency)
+	isOwnTransfer := m.isOwnTransfer(tx.Description)
+
+	tx.Balance = &database.Balance{
+		Account:  m.AccountName(cardCurrency),
+		Amount:   monoAmount(item.Balance, cardCurrency),
+		Currency: cardCurrency,
+	}
+
+	if cardAmount.GreaterThan(decimal.Zero) {
+		tx.Type = database.TransactionTypeIncome
+		if isOwnTransfer {
+			m.ownTransferCredit(tx, operationAmount, operationCurrency)
+		}
+
+		tx.DestinationAmount = cardAmount.Abs()
+		tx.DestinationCurrency = cardCurrency
+		tx.DestinationAccount = m.AccountName(cardCurrency)
+
+		return nil
+	}
+
+	tx.Type = database.TransactionTypeExpense
+	if isOwnTransfer {
+		m.ownTransferDebit(tx, operationCurrency)
+	}
+
+	tx.SourceAmount = cardAmount.Abs()
+	tx.SourceCurrency = cardCurrency
+	tx.SourceAccount = m.AccountName(cardCurrency)
+
+	tx.DestinationAmount = operationAmount.Abs()
+	tx.DestinationCurrency = operationCurrency
+
+	return nil
+}
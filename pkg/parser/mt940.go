@@ -0,0 +1,577 @@
+package parser
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+const (
+	mt940NoReference = "NONREF"
+	// mt940OccurrencePrefix marks the position of an entry among identical entries of its statement in split
+	// messages, it is not part of the format.
+	mt940OccurrencePrefix = ":occurrence:"
+)
+
+var (
+	mt940TagRegex     = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):(.*)$`)
+	mt940BalanceRegex = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)$`)
+	// value date, entry date, mark, funds code, amount, type, customer reference, bank reference
+	mt940LineRegex = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+	// structured :86: narratives start with a 3 digit code followed by the subfield separator, e.g. 020~00
+	mt940StructuredRegex = regexp.MustCompile(`^\d{3}([~^?<])`)
+)
+
+type mt940Statement struct {
+	Reference string
+	Account   string
+	Number    string
+	Opening   *mt940Balance
+	Closing   *mt940Balance
+	Entries   []*mt940Entry
+}
+
+type mt940Balance struct {
+	Raw      string
+	Tag      string
+	Amount   decimal.Decimal
+	Currency string
+}
+
+type mt940Entry struct {
+	Line      string
+	Narrative []string
+	// Occurrence counts identical entries of the statement up to this one, identical entries only differ by it.
+	Occurrence int
+}
+
+type mt940Line struct {
+	ValueDate         time.Time
+	EntryDate         time.Time
+	IsDebit           bool
+	Amount            decimal.Decimal
+	TypeCode          string
+	CustomerReference string
+	BankReference     string
+}
+
+type MT940 struct {
+}
+
+func NewMT940() *MT940 {
+	return &MT940{}
+}
+
+func (m *MT940) Type() database.TransactionSource {
+	return database.MT940
+}
+
+// SplitExcel emits one statement per :61: entry with the statement header and balances,
+// statements with inconsistent balances are rejected.
+func (m *MT940) SplitExcel(
+	_ context.Context,
+	data []byte,
+) ([][]byte, error) {
+	statements, err := m.parseStatements(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var resultFiles [][]byte
+
+	for _, statement := range statements {
+		if err = m.checkBalances(statement); err != nil {
+			return nil, err
+		}
+
+		occurrences := map[string]int{}
+
+		for _, entry := range statement.Entries {
+			content := entry.Line + "\n" + strings.Join(entry.Narrative, "\n")
+			occurrences[content] += 1
+			entry.Occurrence = occurrences[content]
+
+			resultFiles = append(resultFiles, []byte(m.format(statement, entry)))
+		}
+	}
+
+	if len(resultFiles) == 0 {
+		return nil, errors.New("no entries found")
+	}
+
+	return resultFiles, nil
+}
+
+func (m *MT940) format(statement *mt940Statement, entry *mt940Entry) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf(":20:%s\n", statement.Reference))
+	sb.WriteString(fmt.Sprintf(":25:%s\n", statement.Account))
+
+	if statement.Number != "" {
+		sb.WriteString(fmt.Sprintf(":28C:%s\n", statement.Number))
+	}
+
+	if statement.Opening != nil {
+		sb.WriteString(fmt.Sprintf(":%s:%s\n", statement.Opening.Tag, statement.Opening.Raw))
+	}
+
+	sb.WriteString(fmt.Sprintf(":61:%s\n", entry.Line))
+
+	if entry.Occurrence > 1 {
+		sb.WriteString(fmt.Sprintf("%s%d\n", mt940OccurrencePrefix, entry.Occurrence))
+	}
+
+	if len(entry.Narrative) > 0 {
+		sb.WriteString(fmt.Sprintf(":86:%s\n", strings.Join(entry.Narrative, "\n")))
+	}
+
+	if statement.Closing != nil {
+		sb.WriteString(fmt.Sprintf(":%s:%s\n", statement.Closing.Tag, statement.Closing.Raw))
+	}
+
+	sb.WriteString("-\n")
+
+	return sb.String()
+}
+
+func (m *MT940) checkBalances(statement *mt940Statement) error {
+	if statement.Opening == nil || statement.Closing == nil {
+		return nil
+	}
+
+	total := statement.Opening.Amount
+
+	for _, entry := range statement.Entries {
+		line, err := m.parseLine(entry.Line)
+		if err != nil {
+			return errors.Wrapf(err, "statement %s", statement.Reference)
+		}
+
+		total = lo.Ternary(line.IsDebit, total.Sub(line.Amount), total.Add(line.Amount))
+	}
+
+	if !total.Equal(statement.Closing.Amount) {
+		return errors.Newf("statement %s balance mismatch: opening %s and entries give %s, closing is %s",
+			statement.Reference,
+			statement.Opening.Amount.StringFixed(2),
+			total.StringFixed(2),
+			statement.Closing.Amount.StringFixed(2),
+		)
+	}
+
+	return nil
+}
+
+// parseStatements reads raw mt940, swift envelopes ({1:...}{4:...-}) are accepted as well.
+func (m *MT940) parseStatements(data string) ([]*mt940Statement, error) {
+	var statements []*mt940Statement
+	var current *mt940Statement
+	var lastTag string
+
+	for _, rawLine := range toLines(data) {
+		line := rawLine // trailing spaces are meaningful inside :86: subfields
+
+		if idx := strings.Index(line, "{4:"); idx >= 0 {
+			line = line[idx+3:]
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "{") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, mt940OccurrencePrefix) {
+			if current != nil && len(current.Entries) > 0 {
+				occurrence, err := strconv.Atoi(strings.TrimPrefix(trimmed, mt940OccurrencePrefix))
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid entry occurrence %s", trimmed)
+				}
+
+				current.Entries[len(current.Entries)-1].Occurrence = occurrence
+			}
+
+			continue
+		}
+
+		if trimmed == "-" || trimmed == "-}" {
+			current = nil
+			lastTag = ""
+			continue
+		}
+
+		matches := mt940TagRegex.FindStringSubmatch(strings.TrimLeft(line, " "))
+		if matches == nil { // continuation of the previous field
+			// supplementary details of :61: are not used
+			if current != nil && lastTag == "86" && len(current.Entries) > 0 {
+				entry := current.Entries[len(current.Entries)-1]
+				entry.Narrative = append(entry.Narrative, line)
+			}
+
+			continue
+		}
+
+		tag, value := matches[1], matches[2]
+		lastTag = tag
+
+		if tag == "20" || current == nil {
+			current = &mt940Statement{}
+			statements = append(statements, current)
+		}
+
+		switch tag {
+		case "20":
+			current.Reference = strings.TrimSpace(value)
+		case "25":
+			current.Account = strings.TrimSpace(value)
+		case "28C", "28":
+			current.Number = strings.TrimSpace(value)
+		case "60F", "60M":
+			balance, err := m.parseBalance(tag, value)
+			if err != nil {
+				return nil, err
+			}
+
+			current.Opening = balance
+		case "62F", "62M":
+			balance, err := m.parseBalance(tag, value)
+			if err != nil {
+				return nil, err
+			}
+
+			current.Closing = balance
+		case "61":
+			current.Entries = append(current.Entries, &mt940Entry{
+				Line: strings.TrimSpace(value),
+			})
+		case "86":
+			if len(current.Entries) == 0 { // statement level information
+				continue
+			}
+
+			entry := current.Entries[len(current.Entries)-1]
+			entry.Narrative = append(entry.Narrative, value)
+		}
+	}
+
+	statements = lo.Filter(statements, func(item *mt940Statement, _ int) bool {
+		return item.Account != "" || len(item.Entries) > 0
+	})
+
+	if len(statements) == 0 {
+		return nil, errors.New("no mt940 statements found")
+	}
+
+	return statements, nil
+}
+
+func (m *MT940) parseBalance(tag string, value string) (*mt940Balance, error) {
+	value = strings.TrimSpace(value)
+
+	matches := mt940BalanceRegex.FindStringSubmatch(value)
+	if matches == nil {
+		return nil, errors.Newf("invalid balance :%s:%s", tag, value)
+	}
+
+	amount, err := m.parseAmount(matches[4])
+	if err != nil {
+		return nil, err
+	}
+
+	if matches[1] == "D" {
+		amount = amount.Neg()
+	}
+
+	return &mt940Balance{
+		Raw:      value,
+		Tag:      tag,
+		Amount:   amount,
+		Currency: matches[3],
+	}, nil
+}
+
+func (m *MT940) parseAmount(input string) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(strings.ReplaceAll(input, ",", "."))
+	if err != nil {
+		return decimal.Zero, errors.Wrapf(err, "failed to parse amount %s", input)
+	}
+
+	return amount, nil
+}
+
+func (m *MT940) parseLine(input string) (*mt940Line, error) {
+	matches := mt940LineRegex.FindStringSubmatch(input)
+	if matches == nil {
+		return nil, errors.Newf("invalid :61: line %s", input)
+	}
+
+	valueDate, err := time.Parse("060102", matches[1])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse value date %s", matches[1])
+	}
+
+	amount, err := m.parseAmount(matches[5])
+	if err != nil {
+		return nil, err
+	}
+
+	line := &mt940Line{
+		ValueDate:         valueDate,
+		EntryDate:         valueDate,
+		IsDebit:           matches[3] == "D" || matches[3] == "RC", // reversal of credit is a debit
+		Amount:            amount,
+		TypeCode:          matches[6],
+		CustomerReference: strings.TrimSpace(matches[7]),
+		BankReference:     strings.TrimSpace(matches[8]),
+	}
+
+	if matches[2] != "" {
+		entryDate, entryErr := time.Parse("0102", matches[2])
+		if entryErr != nil {
+			return nil, errors.Wrapf(entryErr, "failed to parse entry date %s", matches[2])
+		}
+
+		year := valueDate.Year()
+		switch {
+		case valueDate.Month() == time.December && entryDate.Month() == time.January:
+			year += 1
+		case valueDate.Month() == time.January && entryDate.Month() == time.December:
+			year -= 1
+		}
+
+		line.EntryDate = time.Date(year, entryDate.Month(), entryDate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	return line, nil
+}
+
+// parseNarrative splits structured :86: into subfields, e.g. 020~00TRANSFER~20Invoice 1~32Company.
+// Free text narratives are returned as the "" subfield.
+func (m *MT940) parseNarrative(lines []string) map[string]string {
+	joined := strings.Join(lines, "")
+
+	matches := mt940StructuredRegex.FindStringSubmatch(joined)
+	if matches == nil {
+		return map[string]string{
+			"": strings.Join(strings.Fields(strings.Join(lines, " ")), " "),
+		}
+	}
+
+	separator := matches[1]
+	fields := map[string]string{}
+
+	parts := strings.Split(joined, separator)
+	fields["code"] = parts[0]
+
+	for _, part := range parts[1:] {
+		if len(part) < 2 {
+			continue
+		}
+
+		fields[part[:2]] += part[2:]
+	}
+
+	return fields
+}
+
+func (m *MT940) subfields(fields map[string]string, from int, to int) string {
+	var sb strings.Builder
+
+	for i := from; i <= to; i++ {
+		sb.WriteString(fields[strconv.Itoa(i)])
+	}
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func (m *MT940) account(input string) string {
+	input = strings.TrimSpace(input)
+
+	if idx := strings.LastIndex(input, "/"); idx >= 0 { // BIC/account or /account
+		input = input[idx+1:]
+	}
+
+	return strings.ReplaceAll(input, " ", "")
+}
+
+func (m *MT940) ParseMessages(
+	_ context.Context,
+	rawArr []*Record,
+) ([]*database.Transaction, error) {
+	var transactions []*database.Transaction
+
+	type record struct {
+		tx        *database.Transaction
+		statement *mt940Statement
+	}
+
+	var records []*record
+	ownAccounts := map[string]struct{}{}
+
+	for _, raw := range rawArr {
+		rawData, err := hex.DecodeString(string(raw.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		tx := &database.Transaction{
+			ID:                uuid.NewString(),
+			Raw:               string(rawData),
+			OriginalMessage:   raw.Message,
+			TransactionSource: m.Type(),
+		}
+		transactions = append(transactions, tx)
+
+		statements, err := m.parseStatements(string(rawData))
+		if err != nil {
+			tx.ParsingError = err
+			continue
+		}
+
+		if len(statements) != 1 || len(statements[0].Entries) != 1 {
+			tx.ParsingError = errors.New("expected exactly one entry per message")
+			continue
+		}
+
+		ownAccounts[m.account(statements[0].Account)] = struct{}{}
+
+		records = append(records, &record{
+			tx:        tx,
+			statement: statements[0],
+		})
+	}
+
+	// own accounts are only known after every statement of the upload is read
+	for _, rec := range records {
+		if err := m.parseTransaction(rec.tx, rec.statement, ownAccounts); err != nil {
+			rec.tx.ParsingError = err
+		}
+	}
+
+	return mergeOwnTransfers(transactions), nil
+}
+
+func (m *MT940) parseTransaction(
+	tx *database.Transaction,
+	statement *mt940Statement,
+	ownAccounts map[string]struct{},
+) error {
+	entry := statement.Entries[0]
+	account := m.account(statement.Account)
+
+	if account == "" {
+		return errors.New("statement account is missing")
+	}
+
+	line, err := m.parseLine(entry.Line)
+	if err != nil {
+		return err
+	}
+
+	var currency string
+	for _, balance := range []*mt940Balance{statement.Opening, statement.Closing} {
+		if balance != nil {
+			currency = balance.Currency
+			break
+		}
+	}
+
+	if currency == "" {
+		return errors.New("statement currency is missing, no balances found")
+	}
+
+	fields := m.parseNarrative(entry.Narrative)
+
+	tx.Date = line.EntryDate
+	tx.OriginalTxType = line.TypeCode
+	tx.Raw = strings.Join(append([]string{entry.Line}, entry.Narrative...), "\n")
+
+	tx.Description = fields[""]
+	if _, structured := fields["code"]; structured {
+		tx.Description = m.subfields(fields, 20, 25)
+		tx.OriginalNadawcaName = m.subfields(fields, 32, 33)
+
+		if tx.OriginalNadawcaName == "" {
+			tx.OriginalNadawcaName = m.subfields(fields, 27, 28)
+		}
+
+		if tx.Description == "" {
+			tx.Description = strings.TrimSpace(fields["00"])
+		}
+	}
+
+	if tx.Description == "" {
+		tx.Description = tx.OriginalNadawcaName
+	}
+
+	tx.Counterparty = tx.OriginalNadawcaName
+
+	if tx.Description == "" && line.CustomerReference != mt940NoReference {
+		tx.Description = line.CustomerReference
+	}
+
+	counterAccount := strings.ReplaceAll(fields["38"], " ", "")
+	if counterAccount == "" && fields["30"] != "" { // bank sort code and account number
+		counterAccount = strings.ReplaceAll(fields["30"]+fields["31"], " ", "")
+	}
+
+	tx.DeduplicationKeys = []string{strings.Join([]string{
+		account,
+		statement.Reference,
+		statement.Number,
+		line.ValueDate.Format("060102"),
+		lo.Ternary(line.IsDebit, "D", "C"),
+		line.Amount.String(),
+		line.CustomerReference,
+		line.BankReference,
+	}, "_")}
+
+	if entry.Occurrence > 1 { // identical entries of one statement only differ by their position
+		tx.DeduplicationKeys[0] = fmt.Sprintf("%s_%d", tx.DeduplicationKeys[0], entry.Occurrence)
+	}
+
+	_, isOwnTransfer := ownAccounts[counterAccount]
+	isOwnTransfer = isOwnTransfer && counterAccount != account
+
+	if line.IsDebit {
+		tx.Type = database.TransactionTypeExpense
+		tx.SourceAccount = account
+		tx.SourceAmount = line.Amount
+		tx.SourceCurrency = currency
+		tx.DestinationAmount = line.Amount
+		tx.DestinationCurrency = currency
+
+		if isOwnTransfer {
+			tx.Type = database.TransactionTypeInternalTransfer
+			tx.DestinationAccount = counterAccount
+		}
+
+		return nil
+	}
+
+	tx.Type = database.TransactionTypeIncome
+	tx.DestinationAccount = account
+	tx.DestinationAmount = line.Amount
+	tx.DestinationCurrency = currency
+	tx.SourceAmount = line.Amount
+	tx.SourceCurrency = currency
+
+	if isOwnTransfer {
+		tx.Type = database.TransactionTypeInternalTransfer
+		tx.SourceAccount = counterAccount
+		tx.InternalTransferDirectionTo = true
+	}
+
+	return nil
+}
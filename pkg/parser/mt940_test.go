@@ -0,0 +1,185 @@
+package parser_test
+
+import (
+	"context"
+	_ "embed"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+)
+
+//go:embed testdata/mt940/pl.sta
+var mt940Pl []byte
+
+//go:embed testdata/mt940/swift.sta
+var mt940Swift []byte
+
+func parseMT940(t *testing.T, data []byte) []*database.Transaction {
+	srv := parser.NewMT940()
+
+	split, err := srv.SplitExcel(context.TODO(), data)
+	assert.NoError(t, err)
+
+	var records []*parser.Record
+	for _, chunk := range split {
+		records = append(records, &parser.Record{
+			Data: []byte(hex.EncodeToString(chunk)),
+		})
+	}
+
+	resp, err := srv.ParseMessages(context.TODO(), records)
+	assert.NoError(t, err)
+
+	return resp
+}
+
+func TestMT940Split(t *testing.T) {
+	split, err := parser.NewMT940().SplitExcel(context.TODO(), mt940Pl)
+	assert.NoError(t, err)
+	assert.Len(t, split, 6)
+
+	assert.Equal(t, ":20:ST261001\n"+
+		":25:/PL61109010140000071219812874\n"+
+		":28C:00123\n"+
+		":60F:C261001PLN1000,00\n"+
+		":61:2610011001D45,99N073NONREF//BR26274001\n"+
+		":86:073~00ZAKUP KARTĄ~20Zakup kartą BIEDRONKA 12\n"+
+		"~2134 WARSZAWA~32BIEDRONKA 1234\n"+
+		":62F:C261004PLN9004,01\n"+
+		"-\n", string(split[0]))
+
+	assert.Contains(t, string(split[5]), ":25:/PL10105000997603123456789123")
+}
+
+func TestMT940SplitInvalid(t *testing.T) {
+	_, err := parser.NewMT940().SplitExcel(context.TODO(), []byte("not a statement"))
+	assert.ErrorContains(t, err, "no mt940 statements found")
+
+	_, err = parser.NewMT940().SplitExcel(context.TODO(), []byte(":20:REF\n"+
+		":25:PL61109010140000071219812874\n"+
+		":60F:C261001PLN100,00\n"+
+		":61:2610011001D10,00NTRFNONREF\n"+
+		":62F:C261001PLN100,00\n"+
+		"-\n"))
+	assert.ErrorContains(t, err, "statement REF balance mismatch: opening 100.00 and entries give 90.00, closing is 100.00")
+
+	_, err = parser.NewMT940().SplitExcel(context.TODO(), []byte(":20:REF\n:25:ACC\n:60F:C26PLN1\n-\n"))
+	assert.ErrorContains(t, err, "invalid balance :60F:C26PLN1")
+}
+
+func TestMT940Polish(t *testing.T) {
+	resp := parseMT940(t, mt940Pl)
+	assert.Len(t, resp, 5) // the own transfer is merged
+
+	expense := resp[0]
+	assert.NoError(t, expense.ParsingError)
+	assert.EqualValues(t, database.MT940, expense.TransactionSource)
+	assert.Equal(t, database.TransactionTypeExpense, expense.Type)
+	assert.Equal(t, "PL61109010140000071219812874", expense.SourceAccount)
+	assert.Equal(t, "45.99", expense.SourceAmount.StringFixed(2))
+	assert.Equal(t, "PLN", expense.SourceCurrency)
+	assert.Equal(t, "Zakup kartą BIEDRONKA 1234 WARSZAWA", expense.Description)
+	assert.Equal(t, "BIEDRONKA 1234", expense.OriginalNadawcaName)
+	assert.Equal(t, "N073", expense.OriginalTxType)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), expense.Date)
+	assert.Equal(t, []string{
+		"PL61109010140000071219812874_ST261001_00123_261001_D_45.99_NONREF_BR26274001",
+	}, expense.DeduplicationKeys)
+
+	income := resp[1]
+	assert.NoError(t, income.ParsingError)
+	assert.Equal(t, database.TransactionTypeIncome, income.Type)
+	assert.Equal(t, "PL61109010140000071219812874", income.DestinationAccount)
+	assert.Equal(t, "8500.00", income.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "Wynagrodzenie 09/2026", income.Description)
+	assert.Equal(t, "ACME SP. Z O.O.", income.OriginalNadawcaName)
+	assert.Empty(t, income.SourceAccount) // not an own account
+
+	transfer := resp[2]
+	assert.NoError(t, transfer.ParsingError)
+	assert.Equal(t, database.TransactionTypeInternalTransfer, transfer.Type)
+	assert.Equal(t, "PL61109010140000071219812874", transfer.SourceAccount)
+	assert.Equal(t, "430.00", transfer.SourceAmount.StringFixed(2))
+	assert.Equal(t, "PLN", transfer.SourceCurrency)
+	assert.Equal(t, "PL10105000997603123456789123", transfer.DestinationAccount)
+	assert.Equal(t, "100.00", transfer.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "EUR", transfer.DestinationCurrency)
+
+	if assert.Len(t, transfer.DuplicateTransactions, 1) {
+		assert.Equal(t, []string{
+			"PL10105000997603123456789123_ST261003E_00045_261003_C_100_NONREF_BR26276004",
+		}, transfer.DuplicateTransactions[0].DeduplicationKeys)
+	}
+
+	// identical entries of one statement get distinct keys
+	assert.Equal(t, "Opłata za kartę", resp[3].Description)
+	assert.Equal(t, []string{
+		"PL61109010140000071219812874_ST261001_00123_261004_D_10_NONREF_",
+	}, resp[3].DeduplicationKeys)
+	assert.Equal(t, []string{
+		"PL61109010140000071219812874_ST261001_00123_261004_D_10_NONREF__2",
+	}, resp[4].DeduplicationKeys)
+}
+
+func TestMT940OccurrenceIndependentOfOrder(t *testing.T) {
+	srv := parser.NewMT940()
+
+	split, err := srv.SplitExcel(context.TODO(), mt940Pl)
+	assert.NoError(t, err)
+	assert.Contains(t, string(split[4]), "\n:occurrence:2\n")
+
+	resp, err := srv.ParseMessages(context.TODO(), []*parser.Record{ // uploaded in reverse order
+		{Data: []byte(hex.EncodeToString(split[4]))},
+		{Data: []byte(hex.EncodeToString(split[3]))},
+	})
+	assert.NoError(t, err)
+
+	if assert.Len(t, resp, 2) {
+		assert.Equal(t, []string{
+			"PL61109010140000071219812874_ST261001_00123_261004_D_10_NONREF__2",
+		}, resp[0].DeduplicationKeys)
+		assert.Equal(t, []string{
+			"PL61109010140000071219812874_ST261001_00123_261004_D_10_NONREF_",
+		}, resp[1].DeduplicationKeys)
+	}
+}
+
+func TestMT940SwiftEnvelope(t *testing.T) {
+	resp := parseMT940(t, mt940Swift)
+	assert.Len(t, resp, 3)
+
+	expense := resp[0]
+	assert.NoError(t, expense.ParsingError)
+	assert.Equal(t, database.TransactionTypeExpense, expense.Type)
+	assert.Equal(t, "UA213223130000026007233566001", expense.SourceAccount)
+	assert.Equal(t, "1200.50", expense.SourceAmount.StringFixed(2))
+	assert.Equal(t, "UAH", expense.SourceCurrency)
+	assert.Equal(t, "Оплата послуг зв'язку Київстар, рахунок 123", expense.Description)
+	assert.Equal(t, time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), expense.Date)
+
+	reversal := resp[1]
+	assert.NoError(t, reversal.ParsingError)
+	assert.Equal(t, database.TransactionTypeIncome, reversal.Type)
+	assert.Equal(t, "UA213223130000026007233566001", reversal.DestinationAccount)
+	assert.Equal(t, "200.00", reversal.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "Повернення коштів", reversal.Description)
+
+	fee := resp[2]
+	assert.NoError(t, fee.ParsingError)
+	assert.Equal(t, database.TransactionTypeExpense, fee.Type)
+	assert.Equal(t, time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC), fee.Date) // booked in the next year
+}
+
+func TestMT940InvalidMessage(t *testing.T) {
+	resp, err := parser.NewMT940().ParseMessages(context.TODO(), []*parser.Record{
+		{Data: []byte(hex.EncodeToString([]byte(":20:REF\n:25:ACC\n:61:2610011001D1,00NTRFNONREF\n-\n")))},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+	assert.ErrorContains(t, resp[0].ParsingError, "statement currency is missing")
+}
@@ -0,0 +1,384 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/xml"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+var (
+	ofxTagRegex  = regexp.MustCompile(`<(/?)([A-Za-z0-9.]+)[^>]*>`)
+	ofxDateRegex = regexp.MustCompile(`^(\d{8})(\d{6})?(?:\.\d+)?(?:\[([+-]?\d+(?:\.\d+)?)(?::[A-Za-z]+)?])?$`)
+)
+
+// ofxNode is a generic OFX element, the same tree is built for 1.x SGML (leaf tags are not closed)
+// and 2.x XML documents.
+type ofxNode struct {
+	Name     string
+	Value    string
+	Children []*ofxNode
+}
+
+func (n *ofxNode) child(path ...string) *ofxNode {
+	current := n
+
+	for _, name := range path {
+		next, ok := lo.Find(current.Children, func(item *ofxNode) bool {
+			return item.Name == name
+		})
+		if !ok {
+			return nil
+		}
+
+		current = next
+	}
+
+	return current
+}
+
+func (n *ofxNode) value(path ...string) string {
+	if node := n.child(path...); node != nil {
+		return node.Value
+	}
+
+	return ""
+}
+
+// find returns every descendant with one of the given names, not descending into matches.
+func (n *ofxNode) find(names ...string) []*ofxNode {
+	var result []*ofxNode
+
+	for _, c := range n.Children {
+		if lo.Contains(names, c.Name) {
+			result = append(result, c)
+			continue
+		}
+
+		result = append(result, c.find(names...)...)
+	}
+
+	return result
+}
+
+func (n *ofxNode) write(buf *bytes.Buffer) {
+	buf.WriteString("<" + n.Name + ">")
+
+	if len(n.Children) == 0 {
+		_ = xml.EscapeText(buf, []byte(n.Value))
+	}
+
+	for _, c := range n.Children {
+		c.write(buf)
+	}
+
+	buf.WriteString("</" + n.Name + ">")
+}
+
+type Ofx struct {
+}
+
+func NewOfx() *Ofx {
+	return &Ofx{}
+}
+
+func (o *Ofx) Type() database.TransactionSource {
+	return database.Ofx
+}
+
+// SplitExcel emits one OFX 2.x document per STMTTRN, keeping the statement currency and account.
+func (o *Ofx) SplitExcel(
+	_ context.Context,
+	data []byte,
+) ([][]byte, error) {
+	root, err := o.decode(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var resultFiles [][]byte
+
+	for _, statement := range root.find("STMTRS", "CCSTMTRS") {
+		account := statement.child("BANKACCTFROM")
+		if account == nil {
+			account = statement.child("CCACCTFROM")
+		}
+
+		if account == nil {
+			return nil, errors.New("statement account is missing")
+		}
+
+		for _, tx := range statement.find("STMTTRN") {
+			chunk := &ofxNode{
+				Name: "OFX",
+				Children: []*ofxNode{
+					{
+						Name: statement.Name,
+						Children: []*ofxNode{
+							{Name: "CURDEF", Value: statement.value("CURDEF")},
+							account,
+							{Name: "BANKTRANLIST", Children: []*ofxNode{tx}},
+						},
+					},
+				},
+			}
+
+			var buf bytes.Buffer
+			chunk.write(&buf)
+
+			resultFiles = append(resultFiles, buf.Bytes())
+		}
+	}
+
+	if len(resultFiles) == 0 {
+		return nil, errors.New("no transactions found")
+	}
+
+	return resultFiles, nil
+}
+
+// decode skips the OFX 1.x header or xml prolog and builds the element tree.
+func (o *Ofx) decode(data string) (*ofxNode, error) {
+	start := strings.Index(strings.ToUpper(data), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("OFX element not found")
+	}
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	body := data[start:]
+	upperBody := strings.ToUpper(body)
+
+	matches := ofxTagRegex.FindAllStringSubmatchIndex(body, -1)
+	for i, match := range matches {
+		isClosing := body[match[2]:match[3]] == "/"
+		name := strings.ToUpper(body[match[4]:match[5]])
+
+		if isClosing {
+			// leaf tags are already closed, unknown closing tags are ignored
+			for idx := len(stack) - 1; idx > 0; idx-- {
+				if stack[idx].Name == name {
+					stack = stack[:idx]
+					break
+				}
+			}
+
+			continue
+		}
+
+		textEnd := len(body)
+		if i+1 < len(matches) {
+			textEnd = matches[i+1][0]
+		}
+
+		node := &ofxNode{Name: name}
+		parent := stack[len(stack)-1]
+		parent.Children = append(parent.Children, node)
+
+		if text := strings.TrimSpace(body[match[1]:textEnd]); text != "" {
+			node.Value = html.UnescapeString(text) // leaf element
+			continue
+		}
+
+		// empty sgml leaves are never closed, aggregates always are
+		if !strings.Contains(upperBody[match[1]:], "</"+name+">") {
+			continue
+		}
+
+		stack = append(stack, node)
+	}
+
+	ofx := root.child("OFX")
+	if ofx == nil {
+		return nil, errors.New("OFX element not found")
+	}
+
+	return ofx, nil
+}
+
+func (o *Ofx) ParseMessages(
+	_ context.Context,
+	rawArr []*Record,
+) ([]*database.Transaction, error) {
+	var transactions []*database.Transaction
+
+	for _, raw := range rawArr {
+		rawData, err := hex.DecodeString(string(raw.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		tx := &database.Transaction{
+			ID:                uuid.NewString(),
+			Raw:               string(rawData),
+			OriginalMessage:   raw.Message,
+			TransactionSource: o.Type(),
+		}
+		transactions = append(transactions, tx)
+
+		if err = o.parseTransaction(tx, string(rawData)); err != nil {
+			tx.ParsingError = err
+		}
+	}
+
+	return transactions, nil
+}
+
+func (o *Ofx) parseTransaction(tx *database.Transaction, data string) error {
+	root, err := o.decode(data)
+	if err != nil {
+		return err
+	}
+
+	statements := root.find("STMTRS", "CCSTMTRS")
+	if len(statements) != 1 {
+		return errors.New("expected exactly one statement per message")
+	}
+
+	statement := statements[0]
+	entries := statement.find("STMTTRN")
+
+	if len(entries) != 1 {
+		return errors.New("expected exactly one transaction per message")
+	}
+
+	entry := entries[0]
+
+	account := strings.TrimSpace(statement.value("BANKACCTFROM", "ACCTID"))
+	if account == "" {
+		account = strings.TrimSpace(statement.value("CCACCTFROM", "ACCTID"))
+	}
+
+	if account == "" {
+		return errors.New("statement account is missing")
+	}
+
+	fitID := strings.TrimSpace(entry.value("FITID"))
+	if fitID == "" {
+		return errors.New("FITID is missing")
+	}
+
+	tx.DeduplicationKeys = []string{strings.Join([]string{account, fitID}, "_")}
+	tx.OriginalTxType = entry.value("TRNTYPE")
+
+	date, err := o.parseDate(lo.Ternary(entry.value("DTPOSTED") != "", entry.value("DTPOSTED"), entry.value("DTUSER")))
+	if err != nil {
+		return err
+	}
+
+	tx.Date = date
+
+	amount, err := o.parseAmount(entry.value("TRNAMT"))
+	if err != nil {
+		return err
+	}
+
+	currency := statement.value("CURDEF")
+	counterAmount, counterCurrency := amount.Abs(), currency
+
+	// CURRENCY: amounts are in CURSYM, ORIGCURRENCY: amounts are in CURDEF. CURRATE converts CURSYM to CURDEF.
+	if foreign := entry.child("CURRENCY"); foreign != nil {
+		rate, rateErr := o.parseAmount(foreign.value("CURRATE"))
+		if rateErr != nil {
+			return rateErr
+		}
+
+		counterAmount, counterCurrency = amount.Abs(), foreign.value("CURSYM")
+		amount = amount.Mul(rate).Round(2)
+	} else if original := entry.child("ORIGCURRENCY"); original != nil {
+		rate, rateErr := o.parseAmount(original.value("CURRATE"))
+		if rateErr != nil {
+			return rateErr
+		}
+
+		if !rate.IsZero() {
+			counterAmount, counterCurrency = amount.Abs().Div(rate).Round(2), original.value("CURSYM")
+		}
+	}
+
+	if currency == "" || counterCurrency == "" {
+		return errors.New("currency is missing")
+	}
+
+	name := strings.TrimSpace(lo.Ternary(entry.value("NAME") != "", entry.value("NAME"), entry.value("PAYEE", "NAME")))
+	memo := strings.TrimSpace(entry.value("MEMO"))
+
+	tx.OriginalNadawcaName = name
+	tx.Counterparty = name
+	tx.Description = strings.Join(lo.Uniq(lo.Compact([]string{name, memo})), " ")
+
+	if amount.IsNegative() {
+		tx.Type = database.TransactionTypeExpense
+		tx.SourceAccount = account
+		tx.SourceAmount = amount.Abs()
+		tx.SourceCurrency = currency
+		tx.DestinationAmount = counterAmount
+		tx.DestinationCurrency = counterCurrency
+
+		return nil
+	}
+
+	tx.Type = database.TransactionTypeIncome
+	tx.DestinationAccount = account
+	tx.DestinationAmount = amount.Abs()
+	tx.DestinationCurrency = currency
+	tx.SourceAmount = counterAmount
+	tx.SourceCurrency = counterCurrency
+
+	return nil
+}
+
+func (o *Ofx) parseAmount(input string) (decimal.Decimal, error) {
+	input = strings.ReplaceAll(strings.TrimSpace(input), ",", ".") // some european banks use comma
+
+	amount, err := decimal.NewFromString(input)
+	if err != nil {
+		return decimal.Zero, errors.Wrapf(err, "failed to parse amount %s", input)
+	}
+
+	return amount, nil
+}
+
+// parseDate handles YYYYMMDD[HHMMSS[.XXX]][[gmt offset[:tz name]]], without offset the time is GMT.
+func (o *Ofx) parseDate(input string) (time.Time, error) {
+	input = strings.TrimSpace(input)
+
+	matches := ofxDateRegex.FindStringSubmatch(input)
+	if matches == nil {
+		return time.Time{}, errors.Newf("failed to parse date %s", input)
+	}
+
+	offset := 0
+	if matches[3] != "" {
+		hours, err := strconv.ParseFloat(matches[3], 64)
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "failed to parse date offset %s", matches[3])
+		}
+
+		offset = int(hours * 3600)
+	}
+
+	layout, value := "20060102", matches[1]
+	if matches[2] != "" {
+		layout, value = "20060102150405", matches[1]+matches[2]
+	}
+
+	date, err := time.ParseInLocation(layout, value, time.FixedZone("", offset))
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to parse date %s", input)
+	}
+
+	return date, nil
+}
@@ -0,0 +1,118 @@
+package parser_test
+
+import (
+	"context"
+	_ "embed"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+)
+
+//go:embed testdata/ofx/bank_v1.ofx
+var ofxBankV1 []byte
+
+//go:embed testdata/ofx/card_v2.ofx
+var ofxCardV2 []byte
+
+func parseOfx(t *testing.T, data []byte) []*database.Transaction {
+	srv := parser.NewOfx()
+
+	split, err := srv.SplitExcel(context.TODO(), data)
+	assert.NoError(t, err)
+
+	var records []*parser.Record
+	for _, chunk := range split {
+		records = append(records, &parser.Record{
+			Data: []byte(hex.EncodeToString(chunk)),
+		})
+	}
+
+	resp, err := srv.ParseMessages(context.TODO(), records)
+	assert.NoError(t, err)
+
+	return resp
+}
+
+func TestOfxSplit(t *testing.T) {
+	split, err := parser.NewOfx().SplitExcel(context.TODO(), ofxBankV1)
+	assert.NoError(t, err)
+	assert.Len(t, split, 2)
+
+	assert.Equal(t, "<OFX><STMTRS><CURDEF>USD</CURDEF>"+
+		"<BANKACCTFROM><BANKID>021000021</BANKID><ACCTID>000123456789</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>"+
+		"<BANKTRANLIST><STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20261001120000.000[-5:EST]</DTPOSTED>"+
+		"<TRNAMT>-42.15</TRNAMT><FITID>202610010001</FITID><NAME>GROCERY &amp; DELI</NAME><MEMO></MEMO></STMTTRN>"+
+		"</BANKTRANLIST></STMTRS></OFX>", string(split[0]))
+}
+
+func TestOfxSplitInvalid(t *testing.T) {
+	_, err := parser.NewOfx().SplitExcel(context.TODO(), []byte("not ofx"))
+	assert.ErrorContains(t, err, "OFX element not found")
+
+	_, err = parser.NewOfx().SplitExcel(context.TODO(), []byte("<OFX><STMTRS><CURDEF>USD</STMTRS></OFX>"))
+	assert.ErrorContains(t, err, "statement account is missing")
+}
+
+func TestOfxV1Bank(t *testing.T) {
+	resp := parseOfx(t, ofxBankV1)
+	assert.Len(t, resp, 2)
+
+	expense := resp[0]
+	assert.NoError(t, expense.ParsingError)
+	assert.EqualValues(t, database.Ofx, expense.TransactionSource)
+	assert.Equal(t, database.TransactionTypeExpense, expense.Type)
+	assert.Equal(t, "000123456789", expense.SourceAccount)
+	assert.Equal(t, "42.15", expense.SourceAmount.StringFixed(2))
+	assert.Equal(t, "USD", expense.SourceCurrency)
+	assert.Equal(t, "GROCERY & DELI", expense.Description)
+	assert.Equal(t, "DEBIT", expense.OriginalTxType)
+	assert.True(t, expense.Date.Equal(time.Date(2026, 10, 1, 17, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []string{"000123456789_202610010001"}, expense.DeduplicationKeys)
+
+	income := resp[1]
+	assert.NoError(t, income.ParsingError)
+	assert.Equal(t, database.TransactionTypeIncome, income.Type)
+	assert.Equal(t, "000123456789", income.DestinationAccount)
+	assert.Equal(t, "2500.00", income.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "PAYROLL ACME CORP SALARY", income.Description)
+	assert.Equal(t, time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC), income.Date.UTC())
+}
+
+func TestOfxV2CreditCard(t *testing.T) {
+	resp := parseOfx(t, ofxCardV2)
+	assert.Len(t, resp, 2)
+
+	expense := resp[0]
+	assert.NoError(t, expense.ParsingError)
+	assert.Equal(t, database.TransactionTypeExpense, expense.Type)
+	assert.Equal(t, "4111XXXXXXXX1111", expense.SourceAccount)
+	assert.Equal(t, "92.00", expense.SourceAmount.StringFixed(2))
+	assert.Equal(t, "EUR", expense.SourceCurrency)
+	assert.Equal(t, "80.00", expense.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "GBP", expense.DestinationCurrency)
+	assert.Equal(t, "HOTEL LONDON Booking 1234", expense.Description)
+	assert.True(t, expense.Date.Equal(time.Date(2026, 10, 2, 7, 30, 0, 0, time.UTC)))
+	assert.Equal(t, []string{"4111XXXXXXXX1111_CC-0001"}, expense.DeduplicationKeys)
+
+	refund := resp[1]
+	assert.NoError(t, refund.ParsingError)
+	assert.Equal(t, database.TransactionTypeIncome, refund.Type)
+	assert.Equal(t, "4111XXXXXXXX1111", refund.DestinationAccount)
+	assert.Equal(t, "15.50", refund.DestinationAmount.StringFixed(2))
+	assert.Equal(t, "REFUND", refund.Description)
+}
+
+func TestOfxMissingFitID(t *testing.T) {
+	resp, err := parser.NewOfx().ParseMessages(context.TODO(), []*parser.Record{
+		{Data: []byte(hex.EncodeToString([]byte("<OFX><STMTRS><CURDEF>USD<BANKACCTFROM><ACCTID>1</BANKACCTFROM>" +
+			"<BANKTRANLIST><STMTTRN><TRNAMT>-1.00</STMTTRN></BANKTRANLIST></STMTRS></OFX>")))},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+	assert.ErrorContains(t, resp[0].ParsingError, "FITID is missing")
+}
@@ -133,6 +133,7 @@ func (p *Paribas) ParseMessages(
 			tx.OriginalTxType = data.TransactionType
 			tx.Raw = data.Raw
 			tx.Description = data.Description
+			tx.Counterparty = data.Counterparty
 
 			var transactionType = data.TransactionType
 			var currency = data.Currency
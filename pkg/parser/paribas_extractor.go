@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/cockroachdb/errors"
 	"github.com/samber/lo"
@@ -32,6 +33,7 @@ type ParibasData struct {
 	Description             string
 	Account                 string
 	DestinationAccount      string
+	Counterparty            string
 	TransactionType         string
 	Raw                     string
 	ExecutedAt              string
@@ -91,6 +93,7 @@ func (d DataExtractorV1) Extract(ctx context.Context, cells []*xlsx.Cell) (Parib
 		ExecutedAt:              executedAt,
 		Account:                 account,
 		DestinationAccount:      destinationAccount,
+		Counterparty:            paribasCounterparty(senderOrReceiver),
 	}, nil
 }
 
@@ -159,5 +162,27 @@ func (d DataExtractorV2) Extract(ctx context.Context, cells []*xlsx.Cell) (Parib
 		ExecutedAt:              executedAt,
 		Account:                 account,
 		DestinationAccount:      destinationAccount,
+		Counterparty:            paribasCounterparty(destinationAccountRaw),
 	}, nil
 }
+
+// paribasCounterparty returns the name from the "Nadawca / odbiorca" cell, the account number comes first
+// and is followed by the name and the address, e.g. "1233465\nALLEGRO SP. Z O.O.\nul. Wierzbiecice 1B".
+func paribasCounterparty(senderOrReceiver string) string {
+	lines := toLines(senderOrReceiver)
+	if len(lines) < 2 {
+		return ""
+	}
+
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "/") || strings.IndexFunc(line, unicode.IsLetter) < 0 {
+			continue // "/ES123432523424213132" or "/EU/"
+		}
+
+		return line
+	}
+
+	return ""
+}
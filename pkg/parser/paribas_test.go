@@ -418,6 +418,7 @@ func TestParibasBlik(t *testing.T) {
 			assert.Equal(t, "00:00", resp[0].DateFromMessage)
 			assert.Equal(t, "2024-02-02 00:00:00 +0000", resp[0].Date.Format("2006-01-02 15:04:05 -0700"))
 			assert.Equal(t, "Transakcja BLIK, Allegro xxxx-c21, Płatność BLIK w internecie, Nr 12324, ALLEGRO SP. Z O.O., allegro.pl", resp[0].Description)
+			assert.Equal(t, "ALLEGRO SP. Z O.O.", resp[0].Counterparty)
 		})
 	}
 }
@@ -567,6 +568,7 @@ func TestParibasIncome(t *testing.T) {
 			assert.Equal(t, "00:00", resp[0].DateFromMessage)
 			assert.Equal(t, "2024-02-01 00:00:00 +0000", resp[0].Date.Format("2006-01-02 15:04:05 -0700"))
 			assert.Equal(t, "SOFTWARE DEVELOPMENT SERVICES, INVOICE NO 1-2 XXYY, 31.01.2024", resp[0].Description)
+			assert.Equal(t, "Somecompany SLU", resp[0].Counterparty)
 		})
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/cockroachdb/errors"
 	"github.com/davecgh/go-spew/spew"
@@ -304,9 +305,71 @@ func (p *Parser) Merge(
 }
 
 func (p *Parser) appendTxOrError(finalTx []*database.Transaction, tx *database.Transaction, err error, raw string, item *Record) []*database.Transaction {
+	if !lo.IsNil(tx) && tx.Balance == nil {
+		tx.Balance = p.parseBalance(raw)
+	}
+
+	if !lo.IsNil(tx) && tx.Type != database.TransactionTypeInternalTransfer {
+		tx.Counterparty = p.parseCounterparty(tx.Description)
+	}
+
 	return appendTxOrError(finalTx, tx, err, raw, item)
 }
 
+// parseCounterparty reads the merchant from "Розваги. Steam" or the person from "... Одержувач: Імя Фамілія".
+func (p *Parser) parseCounterparty(description string) string {
+	for _, marker := range []string{"Одержувач:", "Відправник:"} {
+		if _, after, ok := strings.Cut(description, marker); ok {
+			return strings.TrimSpace(after)
+		}
+	}
+
+	idx := strings.LastIndex(description, ". ")
+	if idx < 0 {
+		return ""
+	}
+
+	counterparty := strings.TrimSpace(description[idx+2:])
+	if strings.IndexFunc(counterparty, unicode.IsLetter) < 0 { // masked card, e.g. "Зарахування переказу. *1959"
+		return ""
+	}
+
+	return counterparty
+}
+
+// parseBalance reads "Бал. 1.86USD", the balance belongs to the card from the second line.
+func (p *Parser) parseBalance(raw string) *database.Balance {
+	lines := toLines(raw)
+	if len(lines) < 2 {
+		return nil
+	}
+
+	card := strings.Split(strings.TrimSpace(lines[1]), " ")[0]
+	if card == "" {
+		return nil
+	}
+
+	for _, line := range lines {
+		matches := balanceAmountRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if len(matches) != 3 {
+			continue
+		}
+
+		amount, err := decimal.NewFromString(matches[1])
+		if err != nil {
+			return nil
+		}
+
+		return &database.Balance{
+			Account:  card,
+			Amount:   amount,
+			Currency: matches[2],
+		}
+	}
+
+	return nil
+}
+
 func appendTxOrError(finalTx []*database.Transaction, tx *database.Transaction, err error, raw string, item *Record) []*database.Transaction {
 	if !lo.IsNil(tx) {
 		tx.OriginalMessage = item.Message
@@ -327,6 +390,7 @@ func appendTxOrError(finalTx []*database.Transaction, tx *database.Transaction,
 var (
 	simpleExpenseRegex        = regexp.MustCompile(`(\d+.?\d+)([A-Z]{3}) (.*)$`)
 	balanceRegex              = regexp.MustCompile(`Бал\. .*(\w{3})`)
+	balanceAmountRegex        = regexp.MustCompile(`^Бал\. (-?\d+(?:\.\d+)?)([A-Z]{3})$`)
 	remoteTransferRegex       = simpleExpenseRegex
 	incomeTransferRegex       = simpleExpenseRegex
 	internalTransferToRegex   = regexp.MustCompile(`(\d+.?\d+)([A-Z]{3}) (Переказ на свою карт[^ ]+ (?:(\d+\*\*\d+) )?(.*))$`)
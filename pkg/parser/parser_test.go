@@ -174,6 +174,7 @@ func TestTransferBetweenOwnCards3(t *testing.T) {
 		assert.Equal(t, "USD", resp[0].DestinationCurrency)
 
 		assert.Equal(t, "Зарахування переказу. *1959", resp[0].Description)
+		assert.Empty(t, resp[0].Counterparty)
 		assert.Equal(t, database.TransactionTypeInternalTransfer, resp[0].Type)
 	})
 	t.Run("order 2", func(t *testing.T) {
@@ -428,6 +429,7 @@ func TestParseSimpleExpense(t *testing.T) {
 	assert.Equal(t, "1.33", resp[0].SourceAmount.String())
 	assert.Equal(t, "USD", resp[0].SourceCurrency)
 	assert.Equal(t, "Розваги. Steam", resp[0].Description)
+	assert.Equal(t, "Steam", resp[0].Counterparty)
 	assert.Equal(t, "4*71", resp[0].SourceAccount)
 	assert.Equal(t, database.TransactionTypeExpense, resp[0].Type)
 }
@@ -752,6 +754,7 @@ func TestParseRemoteTransfer2(t *testing.T) {
 	assert.Equal(t, "4000.00", resp[0].SourceAmount.StringFixed(2))
 	assert.Equal(t, "UAH", resp[0].SourceCurrency)
 	assert.Equal(t, "Переказ через додаток Приват24. Одержувач: ХХ УУ ММ", resp[0].Description)
+	assert.Equal(t, "ХХ УУ ММ", resp[0].Counterparty)
 	assert.Equal(t, "5*20", resp[0].SourceAccount)
 	assert.Equal(t, database.TransactionTypeRemoteTransfer, resp[0].Type)
 }
@@ -863,6 +866,7 @@ func TestParseIncomeTransfer(t *testing.T) {
 	assert.Equal(t, "123.11", resp[0].DestinationAmount.StringFixed(2))
 	assert.Equal(t, "UAH", resp[0].DestinationCurrency)
 	assert.Equal(t, "Переказ через Приват24 Відправник: Імя Фамілія ПоБатькові", resp[0].Description)
+	assert.Equal(t, "Імя Фамілія ПоБатькові", resp[0].Counterparty)
 	assert.Equal(t, "5*20", resp[0].DestinationAccount)
 	assert.Equal(t, database.TransactionTypeIncome, resp[0].Type)
 }
@@ -1139,3 +1143,27 @@ func TestMerger(t *testing.T) {
 		assert.Equal(t, database.TransactionTypeInternalTransfer, resp[0].Type)
 	})
 }
+
+func TestParsePrivatBalance(t *testing.T) {
+	srv := parser.NewParser()
+
+	resp, err := srv.ParseMessages(context.TODO(), []*parser.Record{
+		{
+			Data: []byte(`89.80PLN Ресторани, кафе, бари. Pyszne.pl, Wroclaw
+4*67 16:17
+Бал. 1.86USD
+Курс 0.2547 USD/PLN`),
+			Message: &database.Message{
+				CreatedAt: time.Now(),
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+
+	if assert.NotNil(t, resp[0].Balance) {
+		assert.Equal(t, "4*67", resp[0].Balance.Account)
+		assert.Equal(t, "1.86", resp[0].Balance.Amount.StringFixed(2))
+		assert.Equal(t, "USD", resp[0].Balance.Currency)
+	}
+}
@@ -15,7 +15,6 @@ import (
 	"github.com/samber/lo"
 	"github.com/shopspring/decimal"
 
-	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 )
 
@@ -228,6 +227,7 @@ func (m *Revolut) parseTransaction(
 	tx.SourceAccount = m.AccountName(tx.SourceCurrency)
 
 	tx.Description = fmt.Sprintf("%s.%s", operationType, data[4])
+	tx.Counterparty = data[4]
 
 	tx.DeduplicationKeys = []string{
 		strings.Join([]string{
@@ -257,8 +257,16 @@ func (m *Revolut) parseTransaction(
 
 	tx.SourceAmount = sourceAmount.Abs()
 
-	if sourceAmount.GreaterThan(decimal.Zero) {
-		return nil, errors.WithStack(common.ErrOperationNotSupported)
+	if sourceAmount.GreaterThan(decimal.Zero) { // TOPUP, CARD_REFUND, incoming TRANSFER, REWARD etc.
+		tx.Type = database.TransactionTypeIncome
+
+		tx.DestinationCurrency = tx.SourceCurrency
+		tx.DestinationAmount = sourceAmount.Abs()
+		tx.DestinationAccount = m.AccountName(tx.DestinationCurrency)
+
+		tx.SourceCurrency = ""
+		tx.SourceAmount = decimal.Zero
+		tx.SourceAccount = ""
 	}
 
 	return nil, nil
@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
 )
 
@@ -21,6 +22,9 @@ var revolutExchange []byte
 //go:embed testdata/revolut/exchange_swap.csv
 var revolutExchangeSwap []byte
 
+//go:embed testdata/revolut/income.csv
+var revolutIncome []byte
+
 func TestRevolutSimple(t *testing.T) {
 	srv := parser.NewRevolut()
 
@@ -101,3 +105,44 @@ func TestRevolutExchangeSwap(t *testing.T) {
 	assert.EqualValues(t, "revolut_PLN", txs[0].DestinationAccount)
 	assert.EqualValues(t, "1907.07", txs[0].DestinationAmount.StringFixed(2))
 }
+
+func TestRevolutIncome(t *testing.T) {
+	srv := parser.NewRevolut()
+
+	resp, err := srv.SplitExcel(context.TODO(), revolutIncome)
+	assert.NoError(t, err)
+	assert.Len(t, resp, 3)
+
+	var records []*parser.Record
+	for _, r := range resp {
+		records = append(records, &parser.Record{
+			Data: []byte(hex.EncodeToString(r)),
+		})
+	}
+
+	txs, err := srv.ParseMessages(context.TODO(), records)
+	assert.NoError(t, err)
+	assert.Len(t, txs, 3)
+
+	for _, tx := range txs {
+		assert.NoError(t, tx.ParsingError)
+		assert.EqualValues(t, database.TransactionTypeIncome, tx.Type)
+		assert.Empty(t, tx.SourceAccount)
+		assert.True(t, tx.SourceAmount.IsZero())
+	}
+
+	assert.EqualValues(t, "2024-11-02 09:15:10", txs[0].Date.Format(time.DateTime))
+	assert.EqualValues(t, "TOPUP.Payment from John Doe", txs[0].Description)
+	assert.EqualValues(t, "EUR", txs[0].DestinationCurrency)
+	assert.EqualValues(t, "revolut_EUR", txs[0].DestinationAccount)
+	assert.EqualValues(t, "500.00", txs[0].DestinationAmount.StringFixed(2))
+	assert.Equal(t, []string{"TOPUP_2024-11-02 09:15:10_Payment from John Doe_500.00_EUR"}, txs[0].DeduplicationKeys)
+
+	assert.EqualValues(t, "CARD_REFUND.Amazon", txs[1].Description)
+	assert.EqualValues(t, "23.99", txs[1].DestinationAmount.StringFixed(2))
+
+	assert.EqualValues(t, "TRANSFER.From JANE DOE", txs[2].Description)
+	assert.EqualValues(t, "PLN", txs[2].DestinationCurrency)
+	assert.EqualValues(t, "revolut_PLN", txs[2].DestinationAccount)
+	assert.EqualValues(t, "120.50", txs[2].DestinationAmount.StringFixed(2))
+}
@@ -7,4 +7,6 @@ import (
 type Record struct {
 	Message *database.Message
 	Data    []byte
+	// Currency of the account from the chat configuration, used when the statement does not carry it.
+	Currency string
 }
@@ -197,6 +197,9 @@ func (z *Zen) parseTransaction(
 
 	tx.Raw = strings.Join(data, ",")
 	tx.Description = data[2]
+	tx.Counterparty = data[2]
+
+	tx.Balance = z.parseBalance(data, settlementCurrency)
 
 	switch txType {
 	case "Exchange money":
@@ -249,3 +252,21 @@ func (z *Zen) parseTransaction(
 
 	return additionalTx, nil
 }
+
+// parseBalance returns the balance after the transaction, nil when the statement has no valid balance.
+func (z *Zen) parseBalance(data []string, currency string) *database.Balance {
+	if len(data) <= 11 {
+		return nil
+	}
+
+	balance, err := decimal.NewFromString(strings.TrimSpace(data[11]))
+	if err != nil {
+		return nil
+	}
+
+	return &database.Balance{
+		Account:  z.AccountName(currency),
+		Amount:   balance,
+		Currency: currency,
+	}
+}
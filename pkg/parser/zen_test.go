@@ -87,3 +87,38 @@ func TestParseIncome(t *testing.T) {
 //	assert.NoError(t, err)
 //	assert.NotNil(t, resp)
 //}
+
+func TestParseZenBalance(t *testing.T) {
+	srv := parser.NewZen()
+
+	resp, err := srv.ParseMessages(context.TODO(), []*parser.Record{
+		{
+			Data: []byte(hex.EncodeToString([]byte("19-Jun-24,Card payment,SHOP,-10.00,USD,-9.20,EUR,1.087,Fee for processing transaction,,,123.45\n"))),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+
+	assert.NoError(t, resp[0].ParsingError)
+	if assert.NotNil(t, resp[0].Balance) {
+		assert.Equal(t, "zen_USD", resp[0].Balance.Account)
+		assert.Equal(t, "123.45", resp[0].Balance.Amount.StringFixed(2))
+		assert.Equal(t, "USD", resp[0].Balance.Currency)
+	}
+}
+
+func TestParseZenInvalidBalance(t *testing.T) {
+	srv := parser.NewZen()
+
+	resp, err := srv.ParseMessages(context.TODO(), []*parser.Record{
+		{
+			Data: []byte(hex.EncodeToString([]byte("19-Jun-24,Card payment,SHOP,-10.00,USD,-9.20,EUR,1.087,Fee for processing transaction,,,n/a\n"))),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resp, 1)
+
+	assert.NoError(t, resp[0].ParsingError)
+	assert.Nil(t, resp[0].Balance)
+	assert.Equal(t, "SHOP", resp[0].Description)
+}
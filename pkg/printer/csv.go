@@ -0,0 +1,300 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
+)
+
+const csvMimeType = "text/csv"
+
+// CSV renders replies as csv with a header row, transactions use the same columns everywhere.
+type CSV struct {
+}
+
+func NewCSV() *CSV {
+	return &CSV{}
+}
+
+func (p *CSV) write(rows [][]string) string {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	_ = w.WriteAll(rows) // writes to a buffer do not fail
+
+	return buf.String()
+}
+
+// transactionRows lists the transactions, parse errors get a row with the error status.
+func transactionRows(mappedTx []*firefly.MappedTransaction, errArr []error) [][]string {
+	rows := [][]string{transactionViewHeader}
+	views := newTransactionsView(mappedTx, mappedTx, errArr)
+
+	for _, err := range views.Errors {
+		row := make([]string, len(transactionViewHeader))
+		row[0] = StatusError
+		row[len(row)-1] = err
+
+		rows = append(rows, row)
+	}
+
+	for _, view := range views.Transactions {
+		rows = append(rows, []string{
+			view.Status, view.Source, view.Date, view.Type,
+			view.SourceAmount, view.SourceCurrency, view.SourceAccount,
+			view.DestinationAmount, view.DestinationCurrency, view.DestinationAccount,
+			view.Description, view.Counterparty,
+			view.FireflyType, view.FireflySource, view.FireflyDestination,
+			view.Category, view.Budget, strings.Join(view.Tags, ";"), strings.Join(view.Rules, ";"),
+			view.FireflyID, view.ExistingFireflyID, view.Decision, view.Error,
+		})
+	}
+
+	return rows
+}
+
+// Dry lists all transactions with their status, unlike the text printer duplicates are included.
+func (p *CSV) Dry(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.write(transactionRows(mappedTx, errArr))
+}
+
+func (p *CSV) Commit(
+	ctx context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.Dry(ctx, mappedTx, errArr)
+}
+
+// Document is the /dry csv as an attachment.
+func (p *CSV) Document(
+	ctx context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) (notifications.Document, error) {
+	return notifications.Document{
+		Name:     "dry.csv",
+		MimeType: csvMimeType,
+		Data:     []byte(p.Dry(ctx, mappedTx, errArr)),
+	}, nil
+}
+
+func (p *CSV) Stat(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	st := countStat(mappedTx, errArr)
+
+	return p.write([][]string{
+		{"metric", "count"},
+		{"total", strconv.Itoa(st.Total)},
+		{"ok", strconv.Itoa(st.Ok)},
+		{"errors", strconv.Itoa(st.Errors)},
+		{"unsupported", strconv.Itoa(st.Unsupported)},
+		{"duplicates", strconv.Itoa(st.Duplicates)},
+		{"probable_duplicates", strconv.Itoa(st.ProbableDuplicates)},
+		{"skipped", strconv.Itoa(st.Skipped)},
+	})
+}
+
+func (p *CSV) Duplicates(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	_ []error,
+) string {
+	return p.write(transactionRows(filterTransactions(mappedTx, isDuplicate), nil))
+}
+
+func (p *CSV) Errors(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.write(transactionRows(filterTransactions(mappedTx, isError), errArr))
+}
+
+func (p *CSV) Transaction(
+	_ context.Context,
+	tx *firefly.MappedTransaction,
+) string {
+	return p.write(transactionRows([]*firefly.MappedTransaction{tx}, nil))
+}
+
+var commitRowsHeader = []string{
+	"commit_id", "created_at", "undone_at", "status", "firefly_id", "date", "amount", "currency", "description", "error",
+}
+
+func commitRows(batches []*database.CommitBatch) [][]string {
+	rows := [][]string{commitRowsHeader}
+
+	for _, batch := range batches {
+		undoneAt := ""
+		if batch.UndoneAt != nil {
+			undoneAt = batch.UndoneAt.Format("2006-01-02 15:04")
+		}
+
+		for _, tx := range batch.Transactions {
+			row := []string{
+				batch.ID, batch.CreatedAt.Format("2006-01-02 15:04"), undoneAt,
+				string(tx.Status), tx.FireflyID, "", "", "", "", tx.Error,
+			}
+
+			if tx.Transaction != nil {
+				amount, currency := tx.Transaction.SourceAmount, tx.Transaction.SourceCurrency
+				if amount.IsZero() {
+					amount, currency = tx.Transaction.DestinationAmount, tx.Transaction.DestinationCurrency
+				}
+
+				row[5] = tx.Transaction.Date.Format("2006-01-02")
+				row[6] = amount.StringFixed(2)
+				row[7] = currency
+				row[8] = tx.Transaction.Description
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+func (p *CSV) Undo(
+	_ context.Context,
+	batch *database.CommitBatch,
+	errArr []error,
+) string {
+	var batches []*database.CommitBatch
+	if batch != nil {
+		batches = append(batches, batch)
+	}
+
+	rows := commitRows(batches)
+
+	for _, err := range errArr {
+		row := make([]string, len(commitRowsHeader))
+		row[3] = StatusError
+		row[len(row)-1] = err.Error()
+
+		rows = append(rows, row)
+	}
+
+	return p.write(rows)
+}
+
+func (p *CSV) History(
+	_ context.Context,
+	batches []*database.CommitBatch,
+) string {
+	return p.write(commitRows(batches))
+}
+
+func (p *CSV) Balances(
+	_ context.Context,
+	checks []*firefly.BalanceCheck,
+) string {
+	rows := [][]string{
+		{"account", "account_name", "currency", "bank_balance", "firefly_balance", "drift", "error"},
+	}
+
+	for _, view := range newBalanceViews(checks) {
+		rows = append(rows, []string{
+			view.Account, view.AccountName, view.Currency,
+			view.BankBalance, view.FireflyBalance, view.Drift, view.Error,
+		})
+	}
+
+	return p.write(rows)
+}
+
+// Reconcile lists the differences, one row per bank or firefly transaction.
+func (p *CSV) Reconcile(
+	_ context.Context,
+	result *reconcile.Result,
+) string {
+	rows := [][]string{
+		{"kind", "date", "amount", "currency", "description", "firefly_id", "error"},
+	}
+
+	view := newReconcileView(result)
+
+	reconcileRow := func(kind string, row reconcileRowView) []string {
+		return []string{kind, row.Date, row.Amount, row.Currency, row.Description, row.FireflyID, ""}
+	}
+
+	for _, row := range view.BankOnlyRows {
+		rows = append(rows, reconcileRow("bank_only", row))
+	}
+
+	for _, row := range view.FireflyOnly {
+		rows = append(rows, reconcileRow("firefly_only", row))
+	}
+
+	for _, match := range view.Mismatches {
+		reason := "date"
+		if match.AmountDiffers {
+			reason = "amount"
+		}
+
+		rows = append(rows,
+			reconcileRow(fmt.Sprintf("%s_differs_bank", reason), match.BankRow),
+			reconcileRow(fmt.Sprintf("%s_differs_firefly", reason), match.Firefly),
+		)
+	}
+
+	for _, err := range view.Errors {
+		rows = append(rows, []string{StatusError, "", "", "", "", "", err})
+	}
+
+	return p.write(rows)
+}
+
+func (p *CSV) AccountMappings(
+	_ context.Context,
+	mappings []*database.AccountMapping,
+	accounts []*firefly.Account,
+) string {
+	rows := [][]string{
+		{"pattern", "firefly_account_id", "firefly_account_name"},
+	}
+
+	for _, view := range newAccountMappingViews(mappings, accounts) {
+		rows = append(rows, []string{view.Pattern, view.FireflyAccountID, view.FireflyAccountName})
+	}
+
+	return p.write(rows)
+}
+
+func (p *CSV) Error(
+	_ context.Context,
+	command string,
+	err error,
+) string {
+	return p.write([][]string{
+		{"command", "error"},
+		{command, err.Error()},
+	})
+}
+
+func (p *CSV) Page(
+	_ context.Context,
+	page int,
+	pages int,
+) string {
+	return p.write([][]string{
+		{"page", "pages"},
+		{strconv.Itoa(page), strconv.Itoa(pages)},
+	})
+}
@@ -0,0 +1,84 @@
+package printer_test
+
+import (
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/printer"
+)
+
+func TestCSV_Dry(t *testing.T) {
+	result := printer.NewCSV().Dry(context.Background(), formatTestTransactions(),
+		[]error{errors.New("bad line")})
+
+	rows, err := csv.NewReader(strings.NewReader(result)).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 5)
+
+	header := rows[0]
+	column := func(row []string, name string) string {
+		for i, h := range header {
+			if h == name {
+				return row[i]
+			}
+		}
+
+		t.Fatalf("column %s not found", name)
+
+		return ""
+	}
+
+	assert.Equal(t, printer.StatusError, column(rows[1], "status"))
+	assert.Equal(t, "bad line", column(rows[1], "error"))
+
+	assert.Equal(t, printer.StatusOk, column(rows[2], "status"))
+	assert.Equal(t, "2024-05-01 10:30", column(rows[2], "date"))
+	assert.Equal(t, "12.50", column(rows[2], "source_amount"))
+	assert.Equal(t, "Coffee <to go>", column(rows[2], "description"))
+	assert.Equal(t, "Privat card", column(rows[2], "firefly_source"))
+
+	assert.Equal(t, printer.StatusDuplicate, column(rows[3], "status"))
+	assert.Equal(t, "account not found", column(rows[4], "error"))
+}
+
+func TestCSV_Document(t *testing.T) {
+	ctx := context.Background()
+	p := printer.NewCSV()
+
+	document, err := p.Document(ctx, formatTestTransactions(), nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "dry.csv", document.Name)
+	assert.Equal(t, "text/csv", document.MimeType)
+	assert.Equal(t, p.Dry(ctx, formatTestTransactions(), nil), string(document.Data))
+}
+
+func TestCSV_Stat(t *testing.T) {
+	result := printer.NewCSV().Stat(context.Background(), formatTestTransactions(), nil)
+
+	assert.Contains(t, result, "metric,count\ntotal,3\nok,1\nerrors,1\n")
+}
+
+func TestCSV_Reconcile(t *testing.T) {
+	result := printer.NewCSV().Reconcile(context.Background(), formatTestReconcile())
+
+	assert.Equal(t, "kind,date,amount,currency,description,firefly_id,error\n"+
+		"bank_only,2024-05-01,800.00,EUR,Rent,,\n"+
+		"firefly_only,2024-05-02,99.00,EUR,Cash,13,\n"+
+		"amount_differs_bank,2024-05-01,42.10,EUR,Market,,\n"+
+		"amount_differs_firefly,2024-05-02,42.00,EUR,Market,11,\n"+
+		"error,,,,,,account not found\n", result)
+}
+
+func TestCSV_Balances(t *testing.T) {
+	result := printer.NewCSV().Balances(context.Background(), formatTestBalances())
+
+	assert.Equal(t, "account,account_name,currency,bank_balance,firefly_balance,drift,error\n"+
+		"mono_UAH,Mono,UAH,120.00,100.00,-20.00,\n"+
+		"zen_EUR,,EUR,1.00,,,account not found\n", result)
+}
@@ -0,0 +1,270 @@
+package printer
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
+)
+
+// HTML renders replies for telegram with parse_mode HTML: transactions are aligned tables in <pre> blocks
+// separated by blank lines, so long replies are split between transactions. Replies without a table layout
+// are the escaped text printer output.
+type HTML struct {
+	plain *Printer
+}
+
+func NewHTML() *HTML {
+	return &HTML{
+		plain: NewPrinter(),
+	}
+}
+
+// table renders key value rows as an aligned <pre> block, rows with empty values are dropped.
+func (p *HTML) table(rows [][2]string) string {
+	var width int
+
+	for _, row := range rows {
+		if row[1] != "" && len([]rune(row[0])) > width {
+			width = len([]rune(row[0]))
+		}
+	}
+
+	var lines []string
+
+	for _, row := range rows {
+		if row[1] == "" {
+			continue
+		}
+
+		lines = append(lines, html.EscapeString(fmt.Sprintf("%-*s %s", width, row[0], row[1])))
+	}
+
+	return "<pre>" + strings.Join(lines, "\n") + "</pre>"
+}
+
+func (p *HTML) transaction(tx *firefly.MappedTransaction) string {
+	view := newTransactionView(tx)
+
+	var sb strings.Builder
+
+	switch view.Status {
+	case StatusCommitted:
+		sb.WriteString("<b>✅ Committed</b>\n")
+	case StatusDuplicate:
+		sb.WriteString("<b>✨ Duplicate</b>\n")
+	case StatusSkipped:
+		sb.WriteString("<b>⏭ Skipped</b>\n")
+	case StatusProbableDuplicate:
+		sb.WriteString(fmt.Sprintf("<b>👯 Probable duplicate %.0f%%</b>\n", tx.ProbableDuplicate.Confidence*100))
+	case StatusUnsupported, StatusError:
+		sb.WriteString("<b>❌ Has error</b>\n")
+	}
+
+	source := strings.TrimSpace(view.SourceAmount + " " + view.SourceCurrency)
+	destination := strings.TrimSpace(view.DestinationAmount + " " + view.DestinationCurrency)
+
+	existing := ""
+	if tx.ProbableDuplicate != nil {
+		existing = fmt.Sprintf("#%s %s %s %s",
+			tx.ProbableDuplicate.FireflyID,
+			tx.ProbableDuplicate.Date.Format("2006-01-02"),
+			tx.ProbableDuplicate.Amount,
+			tx.ProbableDuplicate.Description,
+		)
+	}
+
+	sb.WriteString(p.table([][2]string{
+		{"Date", view.Date},
+		{"Source", view.Source},
+		{"Type", strings.TrimSpace(view.Type + " " + view.FireflyType)},
+		{"Amount", source},
+		{"From", view.SourceAccount},
+		{"From [FF]", view.FireflySource},
+		{"Received", destination},
+		{"To", view.DestinationAccount},
+		{"To [FF]", view.FireflyDestination},
+		{"Description", view.Description},
+		{"Category", view.Category},
+		{"Budget", view.Budget},
+		{"Tags", strings.Join(view.Tags, ", ")},
+		{"Rules", strings.Join(view.Rules, ", ")},
+		{"Existing", existing},
+		{"Decision", view.Decision},
+	}))
+
+	if view.Error != "" {
+		sb.WriteString("\n<i>" + html.EscapeString(view.Error) + "</i>")
+	}
+
+	return sb.String()
+}
+
+func (p *HTML) transactions(mappedTx []*firefly.MappedTransaction) string {
+	var blocks []string
+
+	for _, tx := range mappedTx {
+		blocks = append(blocks, p.transaction(tx))
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
+
+func (p *HTML) Stat(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	st := countStat(mappedTx, errArr)
+
+	rows := [][2]string{
+		{"Ok", fmt.Sprintf("%v 🔥", st.Ok)},
+		{"Errors", fmt.Sprintf("%v 🚒", st.Errors)},
+		{"Unsupported", fmt.Sprintf("%v 🚯", st.Unsupported)},
+		{"Duplicates", fmt.Sprintf("%v ✨", st.Duplicates)},
+	}
+
+	if st.ProbableDuplicates > 0 {
+		rows = append(rows, [2]string{"Probable duplicates", fmt.Sprintf("%v 👯", st.ProbableDuplicates)})
+	}
+
+	if st.Skipped > 0 {
+		rows = append(rows, [2]string{"Skipped", fmt.Sprintf("%v ⏭", st.Skipped)})
+	}
+
+	result := fmt.Sprintf("<b>Total transactions: %v</b>\n%s", st.Total, p.table(rows))
+
+	if st.Ok == st.Total {
+		result += "\nAll transactions are ok! 🎉"
+	}
+
+	return result
+}
+
+func (p *HTML) Dry(
+	ctx context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	result := p.Stat(ctx, mappedTx, errArr)
+
+	if listed := filterTransactions(mappedTx, isListed); len(listed) > 0 {
+		result += "\n\n" + p.transactions(listed)
+	}
+
+	return result
+}
+
+func (p *HTML) Commit(
+	ctx context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.Dry(ctx, mappedTx, errArr)
+}
+
+func (p *HTML) Duplicates(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	_ []error,
+) string {
+	duplicates := filterTransactions(mappedTx, isDuplicate)
+
+	if len(duplicates) == 0 {
+		return "No duplicates found"
+	}
+
+	result := p.transactions(duplicates)
+
+	if len(duplicates) == len(mappedTx) {
+		result += "\n\nAll transactions are duplicates: ✅"
+	}
+
+	return result
+}
+
+func (p *HTML) Errors(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	var blocks []string
+
+	for _, err := range errArr {
+		blocks = append(blocks, "<b>Error:</b> "+html.EscapeString(err.Error()))
+	}
+
+	failed := filterTransactions(mappedTx, isError)
+	if len(failed) > 0 {
+		blocks = append(blocks, p.transactions(failed))
+	} else {
+		blocks = append(blocks, "No errors.")
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
+
+func (p *HTML) Transaction(
+	_ context.Context,
+	tx *firefly.MappedTransaction,
+) string {
+	return p.transaction(tx)
+}
+
+func (p *HTML) Undo(
+	ctx context.Context,
+	batch *database.CommitBatch,
+	errArr []error,
+) string {
+	return html.EscapeString(p.plain.Undo(ctx, batch, errArr))
+}
+
+func (p *HTML) History(
+	ctx context.Context,
+	batches []*database.CommitBatch,
+) string {
+	return html.EscapeString(p.plain.History(ctx, batches))
+}
+
+func (p *HTML) Balances(
+	ctx context.Context,
+	checks []*firefly.BalanceCheck,
+) string {
+	return html.EscapeString(p.plain.Balances(ctx, checks))
+}
+
+func (p *HTML) Reconcile(
+	ctx context.Context,
+	result *reconcile.Result,
+) string {
+	return html.EscapeString(p.plain.Reconcile(ctx, result))
+}
+
+func (p *HTML) AccountMappings(
+	ctx context.Context,
+	mappings []*database.AccountMapping,
+	accounts []*firefly.Account,
+) string {
+	return html.EscapeString(p.plain.AccountMappings(ctx, mappings, accounts))
+}
+
+func (p *HTML) Error(
+	_ context.Context,
+	command string,
+	err error,
+) string {
+	return fmt.Sprintf("<b>Failed to process command:</b> <code>%s</code>\n<pre>%s</pre>",
+		html.EscapeString(command), html.EscapeString(err.Error()))
+}
+
+func (p *HTML) Page(
+	ctx context.Context,
+	page int,
+	pages int,
+) string {
+	return html.EscapeString(p.plain.Page(ctx, page, pages))
+}
@@ -0,0 +1,40 @@
+package printer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/printer"
+)
+
+func TestHTML_Dry(t *testing.T) {
+	result := printer.NewHTML().Dry(context.Background(), formatTestTransactions(), nil)
+
+	assert.True(t, strings.HasPrefix(result, "<b>Total transactions: 3</b>\n<pre>"))
+	assert.Contains(t, result, "Description Coffee &lt;to go&gt;")
+	assert.Contains(t, result, "Category    Food")
+	assert.Contains(t, result, "<b>❌ Has error</b>\n<pre>")
+	assert.Contains(t, result, "<i>account not found</i>")
+	assert.NotContains(t, result, "Salary") // duplicates are only counted
+
+	blocks := strings.Split(result, "\n\n")
+	assert.Len(t, blocks, 3) // stat and one block per listed transaction
+
+	for _, block := range blocks {
+		assert.Equal(t, strings.Count(block, "<pre>"), strings.Count(block, "</pre>"))
+	}
+}
+
+func TestHTML_Error(t *testing.T) {
+	result := printer.NewHTML().Error(context.Background(), "/map a<b", errors.New("x > y"))
+
+	assert.Equal(t, "<b>Failed to process command:</b> <code>/map a&lt;b</code>\n<pre>x &gt; y</pre>", result)
+}
+
+func TestHTML_Page(t *testing.T) {
+	assert.Equal(t, "Page 1/2, send /dry 2 for the next one.", printer.NewHTML().Page(context.Background(), 1, 2))
+}
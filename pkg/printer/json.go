@@ -0,0 +1,180 @@
+package printer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
+)
+
+// JSON renders every reply as a single line json document, used by the cli for scripting.
+type JSON struct {
+}
+
+func NewJSON() *JSON {
+	return &JSON{}
+}
+
+type jsonCommitBatch struct {
+	ID           string                             `json:"id"`
+	CreatedAt    time.Time                          `json:"createdAt"`
+	UndoneAt     *time.Time                         `json:"undoneAt"`
+	Transactions []*database.CommitBatchTransaction `json:"transactions"`
+}
+
+type jsonUndo struct {
+	Batch  *jsonCommitBatch `json:"batch"`
+	Errors []string         `json:"errors"`
+}
+
+type jsonError struct {
+	Command string `json:"command"`
+	Error   string `json:"error"`
+}
+
+type jsonPage struct {
+	Page  int `json:"page"`
+	Pages int `json:"pages"`
+}
+
+func (p *JSON) marshal(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return p.marshal(jsonError{Error: err.Error()})
+	}
+
+	return string(data)
+}
+
+// Dry lists all transactions with their status, unlike the text printer duplicates are included.
+func (p *JSON) Dry(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.marshal(newTransactionsView(mappedTx, mappedTx, errArr))
+}
+
+func (p *JSON) Commit(
+	ctx context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.Dry(ctx, mappedTx, errArr)
+}
+
+func (p *JSON) Stat(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.marshal(countStat(mappedTx, errArr))
+}
+
+func (p *JSON) Duplicates(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.marshal(newTransactionsView(filterTransactions(mappedTx, isDuplicate), mappedTx, errArr))
+}
+
+func (p *JSON) Errors(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	return p.marshal(newTransactionsView(filterTransactions(mappedTx, isError), mappedTx, errArr))
+}
+
+func (p *JSON) Transaction(
+	_ context.Context,
+	tx *firefly.MappedTransaction,
+) string {
+	return p.marshal(newTransactionView(tx))
+}
+
+func (p *JSON) Undo(
+	_ context.Context,
+	batch *database.CommitBatch,
+	errArr []error,
+) string {
+	resp := jsonUndo{
+		Errors: errorStrings(errArr),
+	}
+
+	if batch != nil {
+		resp.Batch = newJSONCommitBatch(batch)
+	}
+
+	return p.marshal(resp)
+}
+
+func (p *JSON) History(
+	_ context.Context,
+	batches []*database.CommitBatch,
+) string {
+	resp := []*jsonCommitBatch{}
+
+	for _, batch := range batches {
+		resp = append(resp, newJSONCommitBatch(batch))
+	}
+
+	return p.marshal(resp)
+}
+
+func (p *JSON) Balances(
+	_ context.Context,
+	checks []*firefly.BalanceCheck,
+) string {
+	return p.marshal(newBalanceViews(checks))
+}
+
+func (p *JSON) Reconcile(
+	_ context.Context,
+	result *reconcile.Result,
+) string {
+	return p.marshal(newReconcileView(result))
+}
+
+func (p *JSON) AccountMappings(
+	_ context.Context,
+	mappings []*database.AccountMapping,
+	accounts []*firefly.Account,
+) string {
+	return p.marshal(newAccountMappingViews(mappings, accounts))
+}
+
+func (p *JSON) Error(
+	_ context.Context,
+	command string,
+	err error,
+) string {
+	return p.marshal(jsonError{
+		Command: command,
+		Error:   err.Error(),
+	})
+}
+
+func (p *JSON) Page(
+	_ context.Context,
+	page int,
+	pages int,
+) string {
+	return p.marshal(jsonPage{
+		Page:  page,
+		Pages: pages,
+	})
+}
+
+func newJSONCommitBatch(batch *database.CommitBatch) *jsonCommitBatch {
+	return &jsonCommitBatch{
+		ID:           batch.ID,
+		CreatedAt:    batch.CreatedAt,
+		UndoneAt:     batch.UndoneAt,
+		Transactions: batch.Transactions,
+	}
+}
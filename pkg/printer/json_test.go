@@ -0,0 +1,206 @@
+package printer_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/printer"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
+)
+
+func formatTestTransactions() []*firefly.MappedTransaction {
+	date := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	return []*firefly.MappedTransaction{
+		{
+			Original: &database.Transaction{
+				TransactionSource: database.PrivatBank,
+				Type:              database.TransactionTypeExpense,
+				Date:              date,
+				SourceAmount:      decimal.RequireFromString("12.5"),
+				SourceCurrency:    "UAH",
+				SourceAccount:     "5168**1234",
+				Description:       "Coffee <to go>",
+			},
+			Transaction: &firefly.Transaction{
+				Type:         "withdrawal",
+				SourceName:   "Privat card",
+				CategoryName: "Food",
+				Tags:         []string{"cafe"},
+			},
+		},
+		{
+			Original: &database.Transaction{
+				TransactionSource: database.PrivatBank,
+				Date:              date,
+				Description:       "Salary",
+			},
+			Error: common.ErrDuplicate,
+		},
+		{
+			Original: &database.Transaction{
+				TransactionSource: database.PrivatBank,
+				Date:              date,
+				Description:       "Unknown",
+			},
+			Error: errors.New("account not found"),
+		},
+	}
+}
+
+func TestJSON_Dry(t *testing.T) {
+	result := printer.NewJSON().Dry(context.Background(), formatTestTransactions(),
+		[]error{errors.New("bad line")})
+
+	var resp struct {
+		Stat struct {
+			Total      int `json:"total"`
+			Ok         int `json:"ok"`
+			Errors     int `json:"errors"`
+			Duplicates int `json:"duplicates"`
+		} `json:"stat"`
+		Transactions []struct {
+			Status       string   `json:"status"`
+			SourceAmount string   `json:"sourceAmount"`
+			Description  string   `json:"description"`
+			Tags         []string `json:"tags"`
+			Error        string   `json:"error"`
+		} `json:"transactions"`
+		Errors []string `json:"errors"`
+	}
+
+	assert.NotContains(t, result, "\n")
+	assert.NoError(t, json.Unmarshal([]byte(result), &resp))
+
+	assert.Equal(t, 3, resp.Stat.Total)
+	assert.Equal(t, 1, resp.Stat.Ok)
+	assert.Equal(t, 2, resp.Stat.Errors)
+	assert.Equal(t, 1, resp.Stat.Duplicates)
+	assert.Equal(t, []string{"bad line"}, resp.Errors)
+
+	assert.Len(t, resp.Transactions, 3)
+	assert.Equal(t, printer.StatusOk, resp.Transactions[0].Status)
+	assert.Equal(t, "12.50", resp.Transactions[0].SourceAmount)
+	assert.Equal(t, "Coffee <to go>", resp.Transactions[0].Description)
+	assert.Equal(t, []string{"cafe"}, resp.Transactions[0].Tags)
+	assert.Equal(t, printer.StatusDuplicate, resp.Transactions[1].Status)
+	assert.Equal(t, printer.StatusError, resp.Transactions[2].Status)
+	assert.Equal(t, "account not found", resp.Transactions[2].Error)
+}
+
+func TestJSON_Errors(t *testing.T) {
+	result := printer.NewJSON().Errors(context.Background(), formatTestTransactions(), nil)
+
+	var resp struct {
+		Transactions []struct {
+			Description string `json:"description"`
+		} `json:"transactions"`
+		Errors []string `json:"errors"`
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(result), &resp))
+	assert.Len(t, resp.Transactions, 1)
+	assert.Equal(t, "Unknown", resp.Transactions[0].Description)
+	assert.Equal(t, []string{}, resp.Errors)
+}
+
+func TestJSON_Error(t *testing.T) {
+	result := printer.NewJSON().Error(context.Background(), "/dry", errors.New("boom"))
+
+	assert.JSONEq(t, `{"command":"/dry","error":"boom"}`, result)
+}
+
+func formatTestReconcile() *reconcile.Result {
+	date := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	bank := func(amount string, description string) *firefly.MappedTransaction {
+		return &firefly.MappedTransaction{
+			Original: &database.Transaction{
+				Date:         date,
+				SourceAmount: decimal.RequireFromString(amount),
+				Description:  description,
+			},
+			Transaction: &firefly.Transaction{Amount: amount, CurrencyCode: "EUR", Description: description},
+		}
+	}
+	row := func(id string, amount string, description string) *reconcile.Row {
+		return &reconcile.Row{
+			GroupID:     id,
+			Date:        date.AddDate(0, 0, 1),
+			Amount:      decimal.RequireFromString(amount),
+			Transaction: &firefly.Transaction{CurrencyCode: "EUR", Description: description},
+		}
+	}
+
+	return &reconcile.Result{
+		Start:       date,
+		End:         date.AddDate(0, 0, 30),
+		Matched:     []*reconcile.Match{{Bank: bank("3.50", "Coffee"), Firefly: row("10", "3.50", "Coffee")}},
+		BankOnly:    []*firefly.MappedTransaction{bank("800.00", "Rent")},
+		FireflyOnly: []*reconcile.Row{row("13", "99", "Cash")},
+		Mismatches: []*reconcile.Match{
+			{Bank: bank("42.10", "Market"), Firefly: row("11", "42", "Market"), AmountDiffers: true},
+		},
+		Errors: []error{errors.New("account not found")},
+	}
+}
+
+func formatTestBalances() []*firefly.BalanceCheck {
+	return []*firefly.BalanceCheck{
+		{
+			Balance:        &database.Balance{Account: "mono_UAH", Amount: decimal.RequireFromString("120"), Currency: "UAH"},
+			AccountName:    "Mono",
+			FireflyBalance: decimal.RequireFromString("100"),
+		},
+		{
+			Balance: &database.Balance{Account: "zen_EUR", Amount: decimal.RequireFromString("1"), Currency: "EUR"},
+			Error:   errors.New("account not found"),
+		},
+	}
+}
+
+func TestJSON_Reconcile(t *testing.T) {
+	result := printer.NewJSON().Reconcile(context.Background(), formatTestReconcile())
+
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(result), &resp))
+
+	assert.Equal(t, "2024-05-01", resp["start"])
+	assert.EqualValues(t, 1, resp["matched"])
+	assert.Equal(t, []interface{}{"account not found"}, resp["errors"])
+	assert.Equal(t, "Rent", resp["bankOnly"].([]interface{})[0].(map[string]interface{})["description"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"fireflyId": "13", "date": "2024-05-02", "amount": "99.00", "currency": "EUR", "description": "Cash",
+	}}, resp["fireflyOnly"])
+
+	mismatch := resp["mismatches"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, mismatch["amountDiffers"])
+	assert.Equal(t, "42.10", mismatch["bank"].(map[string]interface{})["sourceAmount"])
+	assert.Equal(t, "11", mismatch["firefly"].(map[string]interface{})["fireflyId"])
+}
+
+func TestJSON_BalancesAndMappings(t *testing.T) {
+	p := printer.NewJSON()
+
+	assert.JSONEq(t, `[`+
+		`{"account":"mono_UAH","accountName":"Mono","currency":"UAH","bankBalance":"120.00",`+
+		`"fireflyBalance":"100.00","drift":"-20.00","error":""},`+
+		`{"account":"zen_EUR","accountName":"","currency":"EUR","bankBalance":"1.00",`+
+		`"fireflyBalance":"","drift":"","error":"account not found"}]`,
+		p.Balances(context.Background(), formatTestBalances()))
+
+	assert.JSONEq(t, `[{"pattern":"5168**1234","fireflyAccountId":"1","fireflyAccountName":"Privat card"}]`,
+		p.AccountMappings(context.Background(), []*database.AccountMapping{
+			{Pattern: "5168**1234", FireflyAccountID: "1"},
+		}, []*firefly.Account{
+			{Id: "1", Attributes: firefly.AccountAttributes{Name: "Privat card"}},
+		}))
+}
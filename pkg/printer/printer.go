@@ -8,7 +8,9 @@ import (
 	"github.com/cockroachdb/errors"
 
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
 )
 
 type Printer struct {
@@ -36,32 +38,31 @@ func (p *Printer) Dry(
 	sb.WriteString(p.Stat(ctx, mappedTx, errArr))
 	sb.WriteString("\n\n")
 
-	for _, tx := range mappedTx {
-		if errors.Is(tx.Error, common.ErrDuplicate) {
-			continue
-		}
-		if errors.Is(tx.Error, common.ErrOperationNotSupported) {
-			continue
-		}
-
+	for _, tx := range filterTransactions(mappedTx, isListed) {
 		p.FancyPrintTx(tx, &sb)
 	}
 
 	return sb.String()
 }
 
+// Transaction renders a single transaction, used for messages with inline buttons.
+func (p *Printer) Transaction(
+	_ context.Context,
+	tx *firefly.MappedTransaction,
+) string {
+	var sb strings.Builder
+
+	p.FancyPrintTx(tx, &sb)
+
+	return sb.String()
+}
+
 func (p *Printer) Duplicates(
 	_ context.Context,
 	mappedTx []*firefly.MappedTransaction,
 	_ []error,
 ) string {
-	var duplicates []*firefly.MappedTransaction
-
-	for _, tx := range mappedTx {
-		if errors.Is(tx.Error, common.ErrDuplicate) {
-			duplicates = append(duplicates, tx)
-		}
-	}
+	duplicates := filterTransactions(mappedTx, isDuplicate)
 
 	if len(duplicates) == 0 {
 		return "No duplicates found"
@@ -84,79 +85,315 @@ func (p *Printer) Errors(
 	mappedTx []*firefly.MappedTransaction,
 	errArr []error,
 ) string {
-	var errCount int
 	var sb strings.Builder
 
 	for _, err := range errArr {
 		sb.WriteString(fmt.Sprintf("Error: %s\n", err))
 	}
 
-	for _, tx := range mappedTx {
-		if tx.Error == nil {
-			continue
-		}
+	failed := filterTransactions(mappedTx, isError)
+	for _, tx := range failed {
+		p.FancyPrintTx(tx, &sb)
+	}
 
-		if errors.Is(tx.Error, common.ErrDuplicate) {
-			continue
+	if len(failed) == 0 {
+		sb.WriteString("No errors.")
+	}
+
+	return sb.String()
+}
+
+func (p *Printer) Undo(
+	_ context.Context,
+	batch *database.CommitBatch,
+	errArr []error,
+) string {
+	if batch == nil {
+		return "Nothing to undo."
+	}
+
+	var sb strings.Builder
+	var deletedCount int
+	var messageCount int
+
+	for _, tx := range batch.Transactions {
+		if tx.FireflyID != "" {
+			deletedCount += 1
 		}
 
-		p.FancyPrintTx(tx, &sb)
+		messageCount += len(tx.MessageIDs)
+	}
+
+	sb.WriteString(fmt.Sprintf("Commit from %s", batch.CreatedAt.Format("2006-01-02 15:04")))
+	sb.WriteString(fmt.Sprintf("\nFirefly transactions: %v 🗑", deletedCount))
+	sb.WriteString(fmt.Sprintf("\nMessages: %v ↩️", messageCount))
 
-		errCount += 1
+	for _, err := range errArr {
+		sb.WriteString(fmt.Sprintf("\nError: %s", err))
 	}
 
-	if errCount == 0 {
-		sb.WriteString("No errors.")
+	if len(errArr) == 0 {
+		sb.WriteString("\n\nCommit is undone! 🎉")
+	} else {
+		sb.WriteString("\n\nCommit is partially undone, run /undo again to retry.")
 	}
 
 	return sb.String()
 }
 
-func (p *Printer) Stat(
+func (p *Printer) Balances(
 	_ context.Context,
-	mappedTx []*firefly.MappedTransaction,
-	errArr []error,
+	checks []*firefly.BalanceCheck,
 ) string {
-	var duplicateCount int
-	var notSupportedCount int
-	var okCount int
+	var sb strings.Builder
 
-	for _, tx := range mappedTx {
-		if tx.Error == nil {
-			okCount += 1
-			continue
+	sb.WriteString("Balances:")
+
+	for _, check := range checks {
+		name := check.Balance.Account
+		if check.AccountName != "" {
+			name = fmt.Sprintf("%s (%s)", check.AccountName, check.Balance.Account)
 		}
 
-		if errors.Is(tx.Error, common.ErrDuplicate) {
-			duplicateCount += 1
-			continue
+		switch {
+		case check.Error != nil:
+			sb.WriteString(fmt.Sprintf("\n❔ %s: %s", name, check.Error))
+		case check.Drift().IsZero():
+			sb.WriteString(fmt.Sprintf("\n✅ %s: %s%s",
+				name, check.Balance.Amount.StringFixed(2), check.Balance.Currency))
+		default:
+			sb.WriteString(fmt.Sprintf("\n⚠️ %s: bank %s%s, firefly %s%s, drift %s%s",
+				name,
+				check.Balance.Amount.StringFixed(2), check.Balance.Currency,
+				check.FireflyBalance.StringFixed(2), check.Balance.Currency,
+				check.Drift().StringFixed(2), check.Balance.Currency,
+			))
 		}
+	}
+
+	return sb.String()
+}
 
-		if errors.Is(tx.Error, common.ErrOperationNotSupported) {
-			notSupportedCount += 1
+func (p *Printer) AccountMappings(
+	_ context.Context,
+	mappings []*database.AccountMapping,
+	accounts []*firefly.Account,
+) string {
+	if len(mappings) == 0 {
+		return "No account mappings. Add one with /map <raw> <firefly-account>"
+	}
+
+	names := map[string]string{}
+	for _, acc := range accounts {
+		names[acc.Id] = acc.Attributes.Name
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("Account mappings:")
+
+	for _, mapping := range mappings {
+		name, ok := names[mapping.FireflyAccountID]
+		if !ok {
+			sb.WriteString(fmt.Sprintf("\n❔ %s => [FF #%s] not found", mapping.Pattern, mapping.FireflyAccountID))
 			continue
 		}
 
-		errArr = append(errArr, tx.Error)
+		sb.WriteString(fmt.Sprintf("\n🔗 %s => %s [FF #%s]", mapping.Pattern, name, mapping.FireflyAccountID))
 	}
 
+	return sb.String()
+}
+
+func (p *Printer) Reconcile(
+	_ context.Context,
+	result *reconcile.Result,
+) string {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("Total transactions: %v", len(mappedTx)))
-	sb.WriteString(fmt.Sprintf("\nOk: %v 🔥", okCount))
+	sb.WriteString(fmt.Sprintf("Reconcile %s - %s",
+		result.Start.Format("2006-01-02"), result.End.Format("2006-01-02")))
+	sb.WriteString(fmt.Sprintf("\nMatched: %v ✅", len(result.Matched)))
+	sb.WriteString(fmt.Sprintf("\nBank only: %v 🏦", len(result.BankOnly)))
+	sb.WriteString(fmt.Sprintf("\nFirefly only: %v 🔥", len(result.FireflyOnly)))
+	sb.WriteString(fmt.Sprintf("\nMismatches: %v ⚠️", len(result.Mismatches)))
+	sb.WriteString(fmt.Sprintf("\nErrors: %v 🚒", len(result.Errors)))
 
-	sb.WriteString(fmt.Sprintf("\nErrors: %v 🚒", len(errArr)))
-	sb.WriteString(fmt.Sprintf("\nUnsupported operations: %v 🚯", notSupportedCount))
+	if len(result.BankOnly) > 0 {
+		sb.WriteString("\n\nMissing in Firefly:")
 
-	sb.WriteString(fmt.Sprintf("\nDuplicates: %v ✨", duplicateCount))
+		for _, tx := range result.BankOnly {
+			sb.WriteString("\n" + p.reconcileBankLine(tx))
+		}
+	}
+
+	if len(result.FireflyOnly) > 0 {
+		sb.WriteString("\n\nMissing in bank statement:")
+
+		for _, row := range result.FireflyOnly {
+			sb.WriteString("\n" + p.reconcileFireflyLine(row))
+		}
+	}
+
+	if len(result.Mismatches) > 0 {
+		sb.WriteString("\n\nMismatches:")
+
+		for _, match := range result.Mismatches {
+			reason := "date"
+			if match.AmountDiffers {
+				reason = "amount"
+			}
+
+			sb.WriteString(fmt.Sprintf("\n%s differs:", reason))
+			sb.WriteString("\n  bank: " + p.reconcileBankLine(match.Bank))
+			sb.WriteString("\n  firefly: " + p.reconcileFireflyLine(match.Firefly))
+		}
+	}
+
+	for _, err := range result.Errors {
+		sb.WriteString(fmt.Sprintf("\nError: %s", err))
+	}
+
+	if len(result.BankOnly) == 0 && len(result.FireflyOnly) == 0 && len(result.Mismatches) == 0 {
+		sb.WriteString("\n\nStatement is reconciled! 🎉")
+	}
+
+	return sb.String()
+}
+
+func (p *Printer) reconcileBankLine(tx *firefly.MappedTransaction) string {
+	return fmt.Sprintf("%s %s%s %s",
+		tx.Original.Date.Format("2006-01-02"),
+		tx.Transaction.Amount,
+		tx.Transaction.CurrencyCode,
+		tx.Transaction.Description,
+	)
+}
+
+func (p *Printer) reconcileFireflyLine(row *reconcile.Row) string {
+	return fmt.Sprintf("%s %s%s %s [FF #%s]",
+		row.Date.Format("2006-01-02"),
+		row.Amount.StringFixed(2),
+		row.Transaction.CurrencyCode,
+		row.Transaction.Description,
+		row.GroupID,
+	)
+}
+
+func (p *Printer) History(
+	_ context.Context,
+	batches []*database.CommitBatch,
+) string {
+	if len(batches) == 0 {
+		return "No commits yet."
+	}
+
+	var sb strings.Builder
+
+	for _, batch := range batches {
+		sb.WriteString(fmt.Sprintf("Commit from %s", batch.CreatedAt.Format("2006-01-02 15:04")))
+		if batch.UndoneAt != nil {
+			sb.WriteString(fmt.Sprintf(" (undone %s)", batch.UndoneAt.Format("2006-01-02 15:04")))
+		}
+		sb.WriteString("\n")
+
+		for _, tx := range batch.Transactions {
+			sb.WriteString(fmt.Sprintf("\n%s %s", p.commitStatusIcon(tx.Status), tx.Status))
+
+			if tx.FireflyID != "" {
+				sb.WriteString(fmt.Sprintf(" [FF #%s]", tx.FireflyID))
+			}
+
+			if tx.Transaction != nil {
+				amount, currency := tx.Transaction.SourceAmount, tx.Transaction.SourceCurrency
+				if amount.IsZero() {
+					amount, currency = tx.Transaction.DestinationAmount, tx.Transaction.DestinationCurrency
+				}
+
+				sb.WriteString(fmt.Sprintf(" %s %v%v %s",
+					tx.Transaction.Date.Format("2006-01-02"),
+					amount.StringFixed(2),
+					currency,
+					tx.Transaction.Description,
+				))
+			}
+
+			if tx.Error != "" {
+				sb.WriteString(fmt.Sprintf("\nERROR: %s", tx.Error))
+			}
+		}
+
+		sb.WriteString("\n====================\n")
+	}
+
+	return sb.String()
+}
+
+func (p *Printer) commitStatusIcon(status database.CommitStatus) string {
+	switch status {
+	case database.CommitStatusCommitted:
+		return "✅"
+	case database.CommitStatusFailed:
+		return "❌"
+	case database.CommitStatusAcknowledged:
+		return "✨"
+	case database.CommitStatusUndone:
+		return "↩️"
+	default:
+		return "❔"
+	}
+}
+
+func (p *Printer) Stat(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	st := countStat(mappedTx, errArr)
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Total transactions: %v", st.Total))
+	sb.WriteString(fmt.Sprintf("\nOk: %v 🔥", st.Ok))
+
+	sb.WriteString(fmt.Sprintf("\nErrors: %v 🚒", st.Errors))
+	sb.WriteString(fmt.Sprintf("\nUnsupported operations: %v 🚯", st.Unsupported))
+
+	sb.WriteString(fmt.Sprintf("\nDuplicates: %v ✨", st.Duplicates))
+
+	if st.ProbableDuplicates > 0 {
+		sb.WriteString(fmt.Sprintf("\nProbable duplicates: %v 👯", st.ProbableDuplicates))
+	}
 
-	if okCount == len(mappedTx) {
+	if st.Skipped > 0 {
+		sb.WriteString(fmt.Sprintf("\nSkipped: %v ⏭", st.Skipped))
+	}
+
+	if st.Ok == st.Total {
 		sb.WriteString("\n\nAll transactions are ok! 🎉")
 	}
 
 	return sb.String()
 }
 
+// Error is the reply to a command which failed.
+func (p *Printer) Error(
+	_ context.Context,
+	command string,
+	err error,
+) string {
+	return fmt.Sprintf("Failed to process command: %v\n Error: %v", command, err)
+}
+
+// Page is the footer of an interactive /dry page.
+func (p *Printer) Page(
+	_ context.Context,
+	page int,
+	pages int,
+) string {
+	return fmt.Sprintf("Page %v/%v, send /dry %v for the next one.", page, pages, page+1)
+}
+
 func (p *Printer) FancyPrintTx(tx *firefly.MappedTransaction, sb *strings.Builder) {
 	if tx.IsCommitted {
 		sb.WriteString("Committed: ✅\n")
@@ -165,6 +402,10 @@ func (p *Printer) FancyPrintTx(tx *firefly.MappedTransaction, sb *strings.Builde
 	if tx.Error != nil {
 		if errors.Is(tx.Error, common.ErrDuplicate) {
 			sb.WriteString("Duplicate: ✨\n")
+		} else if errors.Is(tx.Error, common.ErrSkipped) {
+			sb.WriteString("Skipped: ⏭\n")
+		} else if tx.ProbableDuplicate != nil {
+			sb.WriteString(fmt.Sprintf("Probable duplicate: 👯 %.0f%%\n", tx.ProbableDuplicate.Confidence*100))
 		} else {
 			sb.WriteString("Has Error: ❌\n")
 		}
@@ -203,6 +444,30 @@ func (p *Printer) FancyPrintTx(tx *firefly.MappedTransaction, sb *strings.Builde
 	sb.WriteString("\n")
 
 	sb.WriteString(fmt.Sprintf("\nDescription: %s", tx.Original.Description))
+
+	if tx.Transaction != nil && tx.Transaction.CategoryName != "" {
+		sb.WriteString(fmt.Sprintf("\nCategory [FF]: %s", tx.Transaction.CategoryName))
+	}
+	if tx.Transaction != nil && tx.Transaction.BudgetName != "" {
+		sb.WriteString(fmt.Sprintf("\nBudget [FF]: %s", tx.Transaction.BudgetName))
+	}
+	if tx.Transaction != nil && len(tx.Transaction.Tags) > 0 {
+		sb.WriteString(fmt.Sprintf("\nTags [FF]: %s", strings.Join(tx.Transaction.Tags, ", ")))
+	}
+	if len(tx.MatchedRules) > 0 {
+		sb.WriteString(fmt.Sprintf("\nRules: %s", strings.Join(tx.MatchedRules, ", ")))
+	}
+	if tx.ProbableDuplicate != nil {
+		sb.WriteString(fmt.Sprintf("\nExisting [FF #%s]: %s %s %s",
+			tx.ProbableDuplicate.FireflyID,
+			tx.ProbableDuplicate.Date.Format("2006-01-02"),
+			tx.ProbableDuplicate.Amount,
+			tx.ProbableDuplicate.Description,
+		))
+	}
+	if tx.Decision != nil && tx.Decision.Action != database.DecisionActionNone {
+		sb.WriteString(fmt.Sprintf("\nDecision: %s", tx.Decision.Action))
+	}
 	//sb.WriteString(fmt.Sprintf("\nDuplication Key: %s", strings.Join(tx.Original.DeduplicationKeys, "")))
 
 	if tx.Error != nil {
@@ -7,12 +7,15 @@ import (
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/printer"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
 )
 
 func TestPrinter_Commit(t *testing.T) {
@@ -70,6 +73,44 @@ func TestPrinter_Duplicates(t *testing.T) {
 	assert.Contains(t, result, "Duplicate: ✨")
 }
 
+func TestPrinter_ProbableDuplicates(t *testing.T) {
+	p := printer.NewPrinter()
+
+	mappedTx := []*firefly.MappedTransaction{
+		{
+			Original: &database.Transaction{
+				TransactionSource: "Bank",
+				Date:              time.Now(),
+			},
+			Error: common.ErrProbableDuplicate,
+			ProbableDuplicate: &firefly.ProbableDuplicate{
+				FireflyID:   "10",
+				Date:        time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC),
+				Amount:      "3.50",
+				Description: "Manual coffee",
+				Confidence:  0.8734,
+			},
+		},
+		{
+			Original: &database.Transaction{
+				TransactionSource: "Bank",
+				Date:              time.Now(),
+			},
+		},
+	}
+
+	result := p.Duplicates(context.Background(), mappedTx, nil)
+
+	assert.Contains(t, result, "Probable duplicate: 👯 87%")
+	assert.Contains(t, result, "Existing [FF #10]: 2024-05-09 3.50 Manual coffee")
+
+	stat := p.Stat(context.Background(), mappedTx, nil)
+
+	assert.Contains(t, stat, "Errors: 0 🚒")
+	assert.Contains(t, stat, "Probable duplicates: 1 👯")
+	assert.Equal(t, "No errors.", p.Errors(context.Background(), mappedTx, nil))
+}
+
 func TestPrinter_Errors(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		p := printer.NewPrinter()
@@ -165,6 +206,213 @@ func TestPrinter_Errors(t *testing.T) {
 	})
 }
 
+func TestPrinter_Undo(t *testing.T) {
+	t.Run("nothing to undo", func(t *testing.T) {
+		p := printer.NewPrinter()
+
+		assert.Equal(t, "Nothing to undo.", p.Undo(context.Background(), nil, nil))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		p := printer.NewPrinter()
+		batch := &database.CommitBatch{
+			CreatedAt: time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC),
+			Transactions: []*database.CommitBatchTransaction{
+				{FireflyID: "1", MessageIDs: []string{"a", "b"}},
+				{MessageIDs: []string{"c"}},
+			},
+		}
+
+		result := p.Undo(context.Background(), batch, nil)
+
+		assert.Contains(t, result, "Commit from 2026-10-17 10:00")
+		assert.Contains(t, result, "Firefly transactions: 1")
+		assert.Contains(t, result, "Messages: 3")
+		assert.Contains(t, result, "Commit is undone!")
+	})
+
+	t.Run("with errors", func(t *testing.T) {
+		p := printer.NewPrinter()
+
+		result := p.Undo(context.Background(), &database.CommitBatch{}, []error{errors.New("delete failed")})
+
+		assert.Contains(t, result, "Error: delete failed")
+		assert.Contains(t, result, "partially undone")
+	})
+}
+
+func TestPrinter_History(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		p := printer.NewPrinter()
+
+		assert.Equal(t, "No commits yet.", p.History(context.Background(), nil))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		p := printer.NewPrinter()
+		date := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+		result := p.History(context.Background(), []*database.CommitBatch{
+			{
+				CreatedAt: date,
+				UndoneAt:  lo.ToPtr(date.Add(time.Hour)),
+				Transactions: []*database.CommitBatchTransaction{
+					{
+						FireflyID: "42",
+						Status:    database.CommitStatusCommitted,
+						Transaction: &database.Transaction{
+							Date:           date,
+							Description:    "Coffee",
+							SourceAmount:   decimal.RequireFromString("12.5"),
+							SourceCurrency: "PLN",
+						},
+					},
+					{
+						Status: database.CommitStatusFailed,
+						Error:  "failed to commit transaction",
+					},
+				},
+			},
+		})
+
+		assert.Contains(t, result, "Commit from 2026-10-17 10:00 (undone 2026-10-17 11:00)")
+		assert.Contains(t, result, "✅ committed [FF #42] 2026-10-17 12.50PLN Coffee")
+		assert.Contains(t, result, "❌ failed")
+		assert.Contains(t, result, "ERROR: failed to commit transaction")
+	})
+}
+
+func TestPrinter_Balances(t *testing.T) {
+	p := printer.NewPrinter()
+
+	res := p.Balances(context.TODO(), []*firefly.BalanceCheck{
+		{
+			Balance:        &database.Balance{Account: "4*67", Amount: decimal.RequireFromString("1.86"), Currency: "USD"},
+			AccountName:    "Privat USD",
+			FireflyBalance: decimal.RequireFromString("1.86"),
+		},
+		{
+			Balance:        &database.Balance{Account: "mono_UAH", Amount: decimal.RequireFromString("120"), Currency: "UAH"},
+			AccountName:    "Mono",
+			FireflyBalance: decimal.RequireFromString("100"),
+		},
+		{
+			Balance: &database.Balance{Account: "zen_EUR", Amount: decimal.RequireFromString("1"), Currency: "EUR"},
+			Error:   errors.New("account with IBAN zen_EUR not found"),
+		},
+	})
+
+	assert.Equal(t, "Balances:"+
+		"\n✅ Privat USD (4*67): 1.86USD"+
+		"\n⚠️ Mono (mono_UAH): bank 120.00UAH, firefly 100.00UAH, drift -20.00UAH"+
+		"\n❔ zen_EUR: account with IBAN zen_EUR not found", res)
+}
+
+func TestPrinter_AccountMappings(t *testing.T) {
+	p := printer.NewPrinter()
+
+	assert.Equal(t, "No account mappings. Add one with /map <raw> <firefly-account>",
+		p.AccountMappings(context.TODO(), nil, nil))
+
+	res := p.AccountMappings(context.TODO(), []*database.AccountMapping{
+		{Pattern: "5168**1234", FireflyAccountID: "1"},
+		{Pattern: "revolut_*", FireflyAccountID: "404"},
+	}, []*firefly.Account{
+		{Id: "1", Attributes: firefly.AccountAttributes{Name: "Privat card"}},
+	})
+
+	assert.Equal(t, "Account mappings:"+
+		"\n🔗 5168**1234 => Privat card [FF #1]"+
+		"\n❔ revolut_* => [FF #404] not found", res)
+}
+
+func TestPrinter_Reconcile(t *testing.T) {
+	p := printer.NewPrinter()
+
+	date := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	bank := func(amount string, description string) *firefly.MappedTransaction {
+		return &firefly.MappedTransaction{
+			Original:    &database.Transaction{Date: date},
+			Transaction: &firefly.Transaction{Amount: amount, CurrencyCode: "EUR", Description: description},
+		}
+	}
+	row := func(id string, amount string, description string) *reconcile.Row {
+		return &reconcile.Row{
+			GroupID:     id,
+			Date:        date.AddDate(0, 0, 1),
+			Amount:      decimal.RequireFromString(amount),
+			Transaction: &firefly.Transaction{CurrencyCode: "EUR", Description: description},
+		}
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		res := p.Reconcile(context.TODO(), &reconcile.Result{
+			Start:       date,
+			End:         date.AddDate(0, 0, 30),
+			Matched:     []*reconcile.Match{{Bank: bank("3.50", "Coffee"), Firefly: row("10", "3.50", "Coffee")}},
+			BankOnly:    []*firefly.MappedTransaction{bank("800.00", "Rent")},
+			FireflyOnly: []*reconcile.Row{row("13", "99", "Cash")},
+			Mismatches: []*reconcile.Match{
+				{Bank: bank("42.10", "Market"), Firefly: row("11", "42", "Market"), AmountDiffers: true},
+			},
+			Errors: []error{errors.New("account not found")},
+		})
+
+		assert.Equal(t, "Reconcile 2024-05-01 - 2024-05-31"+
+			"\nMatched: 1 ✅"+
+			"\nBank only: 1 🏦"+
+			"\nFirefly only: 1 🔥"+
+			"\nMismatches: 1 ⚠️"+
+			"\nErrors: 1 🚒"+
+			"\n\nMissing in Firefly:"+
+			"\n2024-05-01 800.00EUR Rent"+
+			"\n\nMissing in bank statement:"+
+			"\n2024-05-02 99.00EUR Cash [FF #13]"+
+			"\n\nMismatches:"+
+			"\namount differs:"+
+			"\n  bank: 2024-05-01 42.10EUR Market"+
+			"\n  firefly: 2024-05-02 42.00EUR Market [FF #11]"+
+			"\nError: account not found", res)
+	})
+
+	t.Run("reconciled", func(t *testing.T) {
+		res := p.Reconcile(context.TODO(), &reconcile.Result{
+			Start:   date,
+			End:     date,
+			Matched: []*reconcile.Match{{Bank: bank("3.50", "Coffee"), Firefly: row("10", "3.50", "Coffee")}},
+		})
+
+		assert.Contains(t, res, "Statement is reconciled! 🎉")
+	})
+}
+
+func TestPrinter_Transaction(t *testing.T) {
+	p := printer.NewPrinter()
+
+	tx := &firefly.MappedTransaction{
+		Original: &database.Transaction{
+			TransactionSource: "Bank",
+			Date:              time.Now(),
+			Description:       "Coffee",
+		},
+		Transaction: &firefly.Transaction{CategoryName: "Eating out"},
+		Error:       common.ErrSkipped,
+		Decision:    &database.Decision{Action: database.DecisionActionSkip, CategoryName: "Eating out"},
+	}
+
+	res := p.Transaction(context.TODO(), tx)
+
+	assert.True(t, strings.HasPrefix(res, "Skipped: ⏭\n"))
+	assert.Contains(t, res, "Category [FF]: Eating out")
+	assert.Contains(t, res, "Decision: skip")
+
+	stat := p.Stat(context.TODO(), []*firefly.MappedTransaction{tx}, nil)
+
+	assert.Contains(t, stat, "Errors: 0 🚒")
+	assert.Contains(t, stat, "Skipped: 1 ⏭")
+	assert.NotContains(t, p.Dry(context.TODO(), []*firefly.MappedTransaction{tx}, nil), "Coffee")
+}
+
 func TestPrinter_Stat(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		p := printer.NewPrinter()
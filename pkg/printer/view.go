@@ -0,0 +1,385 @@
+package printer
+
+import (
+	"github.com/cockroachdb/errors"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
+)
+
+const (
+	StatusOk                = "ok"
+	StatusCommitted         = "committed"
+	StatusDuplicate         = "duplicate"
+	StatusProbableDuplicate = "probable_duplicate"
+	StatusSkipped           = "skipped"
+	StatusUnsupported       = "unsupported"
+	StatusError             = "error"
+)
+
+// stat counts transactions by status, errors include the parse errors.
+type stat struct {
+	Total              int `json:"total"`
+	Ok                 int `json:"ok"`
+	Errors             int `json:"errors"`
+	Unsupported        int `json:"unsupported"`
+	Duplicates         int `json:"duplicates"`
+	ProbableDuplicates int `json:"probableDuplicates"`
+	Skipped            int `json:"skipped"`
+}
+
+func countStat(mappedTx []*firefly.MappedTransaction, errArr []error) stat {
+	st := stat{
+		Total:  len(mappedTx),
+		Errors: len(errArr),
+	}
+
+	for _, tx := range mappedTx {
+		switch transactionStatus(tx) {
+		case StatusOk, StatusCommitted:
+			st.Ok += 1
+		case StatusDuplicate:
+			st.Duplicates += 1
+		case StatusUnsupported:
+			st.Unsupported += 1
+		case StatusProbableDuplicate:
+			st.ProbableDuplicates += 1
+		case StatusSkipped:
+			st.Skipped += 1
+		default:
+			st.Errors += 1
+		}
+	}
+
+	return st
+}
+
+func transactionStatus(tx *firefly.MappedTransaction) string {
+	switch {
+	case tx.Error == nil && tx.IsCommitted:
+		return StatusCommitted
+	case tx.Error == nil:
+		return StatusOk
+	case errors.Is(tx.Error, common.ErrDuplicate):
+		return StatusDuplicate
+	case errors.Is(tx.Error, common.ErrOperationNotSupported):
+		return StatusUnsupported
+	case errors.Is(tx.Error, common.ErrProbableDuplicate):
+		return StatusProbableDuplicate
+	case errors.Is(tx.Error, common.ErrSkipped):
+		return StatusSkipped
+	default:
+		return StatusError
+	}
+}
+
+// isListed reports whether /dry and /commit list the transaction, duplicates, unsupported and skipped are only counted.
+func isListed(tx *firefly.MappedTransaction) bool {
+	return !errors.Is(tx.Error, common.ErrDuplicate) && !errors.Is(tx.Error, common.ErrOperationNotSupported) &&
+		!errors.Is(tx.Error, common.ErrSkipped)
+}
+
+func isDuplicate(tx *firefly.MappedTransaction) bool {
+	return errors.Is(tx.Error, common.ErrDuplicate) || errors.Is(tx.Error, common.ErrProbableDuplicate)
+}
+
+func isError(tx *firefly.MappedTransaction) bool {
+	return tx.Error != nil && !isDuplicate(tx) && !errors.Is(tx.Error, common.ErrSkipped)
+}
+
+func filterTransactions(
+	mappedTx []*firefly.MappedTransaction,
+	filter func(tx *firefly.MappedTransaction) bool,
+) []*firefly.MappedTransaction {
+	var filtered []*firefly.MappedTransaction
+
+	for _, tx := range mappedTx {
+		if filter(tx) {
+			filtered = append(filtered, tx)
+		}
+	}
+
+	return filtered
+}
+
+// transactionView is the flat form of a mapped transaction used by the json and csv printers.
+type transactionView struct {
+	Status              string   `json:"status"`
+	Source              string   `json:"source"`
+	Date                string   `json:"date"`
+	Type                string   `json:"type"`
+	SourceAmount        string   `json:"sourceAmount"`
+	SourceCurrency      string   `json:"sourceCurrency"`
+	SourceAccount       string   `json:"sourceAccount"`
+	DestinationAmount   string   `json:"destinationAmount"`
+	DestinationCurrency string   `json:"destinationCurrency"`
+	DestinationAccount  string   `json:"destinationAccount"`
+	Description         string   `json:"description"`
+	Counterparty        string   `json:"counterparty"`
+	FireflyType         string   `json:"fireflyType"`
+	FireflySource       string   `json:"fireflySource"`
+	FireflyDestination  string   `json:"fireflyDestination"`
+	Category            string   `json:"category"`
+	Budget              string   `json:"budget"`
+	Tags                []string `json:"tags"`
+	Rules               []string `json:"rules"`
+	FireflyID           string   `json:"fireflyId"`
+	ExistingFireflyID   string   `json:"existingFireflyId"`
+	Decision            string   `json:"decision"`
+	Error               string   `json:"error"`
+}
+
+var transactionViewHeader = []string{
+	"status", "source", "date", "type",
+	"source_amount", "source_currency", "source_account",
+	"destination_amount", "destination_currency", "destination_account",
+	"description", "counterparty",
+	"firefly_type", "firefly_source", "firefly_destination",
+	"category", "budget", "tags", "rules",
+	"firefly_id", "existing_firefly_id", "decision", "error",
+}
+
+func newTransactionView(tx *firefly.MappedTransaction) transactionView {
+	view := transactionView{
+		Status:    transactionStatus(tx),
+		Rules:     tx.MatchedRules,
+		FireflyID: tx.FireflyID,
+	}
+
+	if original := tx.Original; original != nil {
+		view.Source = string(original.TransactionSource)
+		view.Date = original.Date.Format("2006-01-02 15:04")
+		view.Type = transactionTypeName(original)
+		view.SourceCurrency = original.SourceCurrency
+		view.SourceAccount = original.SourceAccount
+		view.DestinationCurrency = original.DestinationCurrency
+		view.DestinationAccount = original.DestinationAccount
+		view.Description = original.Description
+		view.Counterparty = original.Counterparty
+
+		if !original.SourceAmount.IsZero() {
+			view.SourceAmount = original.SourceAmount.StringFixed(2)
+		}
+
+		if !original.DestinationAmount.IsZero() {
+			view.DestinationAmount = original.DestinationAmount.StringFixed(2)
+		}
+	}
+
+	if fireflyTx := tx.Transaction; fireflyTx != nil {
+		view.FireflyType = fireflyTx.Type
+		view.FireflySource = fireflyTx.SourceName
+		view.FireflyDestination = fireflyTx.DestinationName
+		view.Category = fireflyTx.CategoryName
+		view.Budget = fireflyTx.BudgetName
+		view.Tags = fireflyTx.Tags
+	}
+
+	if tx.ProbableDuplicate != nil {
+		view.ExistingFireflyID = tx.ProbableDuplicate.FireflyID
+	}
+
+	if tx.Decision != nil {
+		view.Decision = string(tx.Decision.Action)
+	}
+
+	if tx.Error != nil {
+		view.Error = tx.Error.Error()
+	}
+
+	return view
+}
+
+// transactionsView is a transaction list with the stat of all transactions and the parse errors.
+type transactionsView struct {
+	Stat         stat              `json:"stat"`
+	Transactions []transactionView `json:"transactions"`
+	Errors       []string          `json:"errors"`
+}
+
+func newTransactionsView(
+	listed []*firefly.MappedTransaction,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) transactionsView {
+	view := transactionsView{
+		Stat:         countStat(mappedTx, errArr),
+		Transactions: []transactionView{},
+		Errors:       errorStrings(errArr),
+	}
+
+	for _, tx := range listed {
+		view.Transactions = append(view.Transactions, newTransactionView(tx))
+	}
+
+	return view
+}
+
+type balanceView struct {
+	Account        string `json:"account"`
+	AccountName    string `json:"accountName"`
+	Currency       string `json:"currency"`
+	BankBalance    string `json:"bankBalance"`
+	FireflyBalance string `json:"fireflyBalance"`
+	Drift          string `json:"drift"`
+	Error          string `json:"error"`
+}
+
+func newBalanceViews(checks []*firefly.BalanceCheck) []balanceView {
+	views := []balanceView{}
+
+	for _, check := range checks {
+		view := balanceView{
+			Account:     check.Balance.Account,
+			AccountName: check.AccountName,
+			Currency:    check.Balance.Currency,
+			BankBalance: check.Balance.Amount.StringFixed(2),
+		}
+
+		if check.Error != nil {
+			view.Error = check.Error.Error()
+		} else {
+			view.FireflyBalance = check.FireflyBalance.StringFixed(2)
+			view.Drift = check.Drift().StringFixed(2)
+		}
+
+		views = append(views, view)
+	}
+
+	return views
+}
+
+// reconcileRowView is a firefly transaction of a reconcile result, bank transactions use it in csv.
+type reconcileRowView struct {
+	FireflyID   string `json:"fireflyId"`
+	Date        string `json:"date"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	Description string `json:"description"`
+}
+
+type reconcileMatchView struct {
+	Bank          transactionView  `json:"bank"`
+	BankRow       reconcileRowView `json:"-"`
+	Firefly       reconcileRowView `json:"firefly"`
+	AmountDiffers bool             `json:"amountDiffers"`
+	DateDiffers   bool             `json:"dateDiffers"`
+}
+
+type reconcileView struct {
+	Start        string               `json:"start"`
+	End          string               `json:"end"`
+	Matched      int                  `json:"matched"`
+	BankOnly     []transactionView    `json:"bankOnly"`
+	BankOnlyRows []reconcileRowView   `json:"-"`
+	FireflyOnly  []reconcileRowView   `json:"fireflyOnly"`
+	Mismatches   []reconcileMatchView `json:"mismatches"`
+	Errors       []string             `json:"errors"`
+}
+
+func newReconcileView(result *reconcile.Result) reconcileView {
+	view := reconcileView{
+		Start:       result.Start.Format("2006-01-02"),
+		End:         result.End.Format("2006-01-02"),
+		Matched:     len(result.Matched),
+		BankOnly:    []transactionView{},
+		FireflyOnly: []reconcileRowView{},
+		Mismatches:  []reconcileMatchView{},
+		Errors:      errorStrings(result.Errors),
+	}
+
+	for _, tx := range result.BankOnly {
+		view.BankOnly = append(view.BankOnly, newTransactionView(tx))
+		view.BankOnlyRows = append(view.BankOnlyRows, newReconcileBankRowView(tx))
+	}
+
+	for _, row := range result.FireflyOnly {
+		view.FireflyOnly = append(view.FireflyOnly, newReconcileRowView(row))
+	}
+
+	for _, match := range result.Mismatches {
+		view.Mismatches = append(view.Mismatches, reconcileMatchView{
+			Bank:          newTransactionView(match.Bank),
+			BankRow:       newReconcileBankRowView(match.Bank),
+			Firefly:       newReconcileRowView(match.Firefly),
+			AmountDiffers: match.AmountDiffers,
+			DateDiffers:   match.DateDiffers,
+		})
+	}
+
+	return view
+}
+
+func newReconcileRowView(row *reconcile.Row) reconcileRowView {
+	return reconcileRowView{
+		FireflyID:   row.GroupID,
+		Date:        row.Date.Format("2006-01-02"),
+		Amount:      row.Amount.StringFixed(2),
+		Currency:    row.Transaction.CurrencyCode,
+		Description: row.Transaction.Description,
+	}
+}
+
+func newReconcileBankRowView(tx *firefly.MappedTransaction) reconcileRowView {
+	return reconcileRowView{
+		Date:        tx.Original.Date.Format("2006-01-02"),
+		Amount:      tx.Transaction.Amount,
+		Currency:    tx.Transaction.CurrencyCode,
+		Description: tx.Transaction.Description,
+	}
+}
+
+type accountMappingView struct {
+	Pattern            string `json:"pattern"`
+	FireflyAccountID   string `json:"fireflyAccountId"`
+	FireflyAccountName string `json:"fireflyAccountName"`
+}
+
+func newAccountMappingViews(
+	mappings []*database.AccountMapping,
+	accounts []*firefly.Account,
+) []accountMappingView {
+	names := map[string]string{}
+	for _, acc := range accounts {
+		names[acc.Id] = acc.Attributes.Name
+	}
+
+	views := []accountMappingView{}
+
+	for _, mapping := range mappings {
+		views = append(views, accountMappingView{
+			Pattern:            mapping.Pattern,
+			FireflyAccountID:   mapping.FireflyAccountID,
+			FireflyAccountName: names[mapping.FireflyAccountID],
+		})
+	}
+
+	return views
+}
+
+func errorStrings(errArr []error) []string {
+	resp := []string{}
+
+	for _, err := range errArr {
+		resp = append(resp, err.Error())
+	}
+
+	return resp
+}
+
+func transactionTypeName(tx *database.Transaction) string {
+	switch tx.Type {
+	case database.TransactionTypeIncome:
+		return "income"
+	case database.TransactionTypeExpense:
+		return "expense"
+	case database.TransactionTypeInternalTransfer:
+		return "internal_transfer"
+	case database.TransactionTypeRemoteTransfer:
+		return "remote_transfer"
+	default:
+		return "unknown"
+	}
+}
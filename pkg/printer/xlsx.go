@@ -0,0 +1,69 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/tealeg/xlsx"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+)
+
+const xlsxMimeType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+var xlsxNumericColumns = map[string]bool{
+	"source_amount":      true,
+	"destination_amount": true,
+}
+
+// XLSX renders /dry results as a spreadsheet with the csv columns, amounts are numeric cells.
+type XLSX struct {
+}
+
+func NewXLSX() *XLSX {
+	return &XLSX{}
+}
+
+func (p *XLSX) Document(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) (notifications.Document, error) {
+	file := xlsx.NewFile()
+
+	sheet, err := file.AddSheet("dry")
+	if err != nil {
+		return notifications.Document{}, errors.Wrap(err, "failed to add sheet")
+	}
+
+	for i, values := range transactionRows(mappedTx, errArr) {
+		row := sheet.AddRow()
+
+		for col, value := range values {
+			cell := row.AddCell()
+
+			if i > 0 && xlsxNumericColumns[transactionViewHeader[col]] && value != "" {
+				if amount, parseErr := strconv.ParseFloat(value, 64); parseErr == nil {
+					cell.SetFloatWithFormat(amount, "0.00")
+					continue
+				}
+			}
+
+			cell.SetString(value)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = file.Write(&buf); err != nil {
+		return notifications.Document{}, errors.Wrap(err, "failed to write xlsx")
+	}
+
+	return notifications.Document{
+		Name:     "dry.xlsx",
+		MimeType: xlsxMimeType,
+		Data:     buf.Bytes(),
+	}, nil
+}
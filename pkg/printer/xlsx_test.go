@@ -0,0 +1,34 @@
+package printer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tealeg/xlsx"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/printer"
+)
+
+func TestXLSX_Document(t *testing.T) {
+	document, err := printer.NewXLSX().Document(context.Background(), formatTestTransactions(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "dry.xlsx", document.Name)
+
+	file, err := xlsx.OpenBinary(document.Data)
+	assert.NoError(t, err)
+	assert.Len(t, file.Sheets, 1)
+
+	rows := file.Sheets[0].Rows
+	assert.Len(t, rows, 4)
+	assert.Equal(t, "status", rows[0].Cells[0].String())
+	assert.Equal(t, "source_amount", rows[0].Cells[4].String())
+
+	amount := rows[1].Cells[4]
+	assert.Equal(t, xlsx.CellTypeNumeric, amount.Type())
+
+	value, err := amount.Float()
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, value)
+	assert.Equal(t, "Coffee <to go>", rows[1].Cells[10].String())
+}
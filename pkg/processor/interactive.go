@@ -0,0 +1,305 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+)
+
+const (
+	interactivePageSize = 10
+
+	callbackCommit      = "commit"
+	callbackSkip        = "skip"
+	callbackDuplicate   = "duplicate"
+	callbackCategory    = "category"
+	callbackSetCategory = "setcategory"
+	callbackBack        = "back"
+)
+
+// applyDecisions assigns decision keys and applies the decisions stored on the original messages.
+func (p *Processor) applyDecisions(mapped []*firefly.MappedTransaction) {
+	occurrences := map[string]int{}
+
+	for _, tx := range mapped {
+		if tx.Original == nil || tx.Original.OriginalMessage == nil {
+			continue
+		}
+
+		base := strings.Join([]string{
+			tx.Original.OriginalMessage.ID,
+			tx.Original.Date.UTC().Format(time.RFC3339),
+			tx.Original.SourceAmount.String(),
+			tx.Original.DestinationAmount.String(),
+			tx.Original.SourceAccount,
+			tx.Original.Description,
+		}, "|")
+
+		hash := sha256.Sum256([]byte(base + "|" + strconv.Itoa(occurrences[base]))) // same rows in one file
+		occurrences[base] += 1
+
+		tx.DecisionKey = hex.EncodeToString(hash[:6])
+
+		decision, ok := tx.Original.OriginalMessage.Decisions[tx.DecisionKey]
+		if !ok {
+			continue
+		}
+
+		tx.Decision = decision
+
+		if decision.CategoryName != "" && tx.Transaction != nil {
+			tx.Transaction.CategoryName = decision.CategoryName
+		}
+
+		switch decision.Action {
+		case database.DecisionActionCommit:
+			if errors.Is(tx.Error, common.ErrProbableDuplicate) { // flagged only when there was no other error
+				tx.Error = nil
+			}
+		case database.DecisionActionSkip:
+			tx.Error = errors.Join(tx.Error, common.ErrSkipped)
+		case database.DecisionActionDuplicate:
+			tx.Error = errors.Join(tx.Error, common.ErrDuplicate)
+		}
+	}
+}
+
+// interactiveDry sends the summary and a page of pending transactions, each with inline buttons.
+func (p *Processor) interactiveDry(
+	ctx context.Context,
+	message Message,
+	mappedTx []*firefly.MappedTransaction,
+	errArr []error,
+) error {
+	page := 1
+
+	if fields := strings.Fields(message.Content); len(fields) > 1 {
+		parsed, err := strconv.Atoi(fields[1])
+		if err != nil || parsed <= 0 {
+			return errors.Newf("invalid page %s", fields[1])
+		}
+
+		page = parsed
+	}
+
+	if err := p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID,
+		p.cfg.Printer.Stat(ctx, mappedTx, errArr)); err != nil {
+		return err
+	}
+
+	pending := lo.Filter(mappedTx, func(tx *firefly.MappedTransaction, _ int) bool {
+		return !errors.Is(tx.Error, common.ErrDuplicate) && !errors.Is(tx.Error, common.ErrOperationNotSupported)
+	})
+
+	chunks := lo.Chunk(pending, interactivePageSize)
+	if page > len(chunks) {
+		return nil
+	}
+
+	for _, tx := range chunks[page-1] {
+		text := p.cfg.Printer.Transaction(ctx, tx)
+
+		if tx.DecisionKey == "" {
+			if err := p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, text); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := p.cfg.NotificationSvc.SendKeyboard(ctx, message.ChatID, text, p.decisionKeyboard(tx)); err != nil {
+			return err
+		}
+	}
+
+	if page < len(chunks) {
+		return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID,
+			p.cfg.Printer.Page(ctx, page, len(chunks)))
+	}
+
+	return nil
+}
+
+func (p *Processor) decisionKeyboard(tx *firefly.MappedTransaction) notifications.Keyboard {
+	return notifications.Keyboard{
+		{
+			{Text: "✅ Commit", CallbackData: callbackCommit + ":" + tx.DecisionKey},
+			{Text: "⏭ Skip", CallbackData: callbackSkip + ":" + tx.DecisionKey},
+		},
+		{
+			{Text: "✨ Duplicate", CallbackData: callbackDuplicate + ":" + tx.DecisionKey},
+			{Text: "🏷 Category", CallbackData: callbackCategory + ":" + tx.DecisionKey},
+		},
+	}
+}
+
+func (p *Processor) categoryKeyboard(
+	tx *firefly.MappedTransaction,
+	categories []*firefly.Category,
+) notifications.Keyboard {
+	var keyboard notifications.Keyboard
+
+	for _, chunk := range lo.Chunk(categories, 2) {
+		var row []notifications.InlineButton
+
+		for _, category := range chunk {
+			row = append(row, notifications.InlineButton{
+				Text:         category.Attributes.Name,
+				CallbackData: callbackSetCategory + ":" + tx.DecisionKey + ":" + category.Id,
+			})
+		}
+
+		keyboard = append(keyboard, row)
+	}
+
+	return append(keyboard, []notifications.InlineButton{
+		{Text: "↩️ Back", CallbackData: callbackBack + ":" + tx.DecisionKey},
+	})
+}
+
+// ProcessCallback handles inline button presses of messages sent by an interactive /dry.
+func (p *Processor) ProcessCallback(
+	ctx context.Context,
+	callback Callback,
+) error {
+	var answer string
+
+	err := authorize(callback.Configuration, callback.UserID, common.RoleImporter, "changing decisions")
+	if err == nil {
+		answer, err = p.handleCallback(ctx, callback)
+	}
+
+	if err != nil {
+		answer = fmt.Sprintf("Failed to process: %v", err)
+	}
+
+	if answerErr := p.cfg.NotificationSvc.AnswerCallback(ctx, callback.ID, answer); answerErr != nil {
+		zerolog.Ctx(ctx).Error().Err(answerErr).Msg("failed to answer callback")
+	}
+
+	return nil
+}
+
+func (p *Processor) handleCallback(
+	ctx context.Context,
+	callback Callback,
+) (string, error) {
+	parts := strings.Split(callback.Data, ":")
+	if len(parts) < 2 {
+		return "", errors.Newf("invalid callback data %s", callback.Data)
+	}
+
+	action, key := parts[0], parts[1]
+
+	tx, err := p.findPending(ctx, callback, key)
+	if err != nil {
+		return "", err
+	}
+
+	if tx == nil {
+		return "Transaction is not pending anymore.", nil
+	}
+
+	switch action {
+	case callbackCategory:
+		categories, listErr := p.cfg.FireflySvc.ListCategories(ctx)
+		if listErr != nil {
+			return "", listErr
+		}
+
+		return "", p.cfg.NotificationSvc.EditMessage(ctx, callback.ChatID, callback.MessageID,
+			p.cfg.Printer.Transaction(ctx, tx), p.categoryKeyboard(tx, categories))
+	case callbackBack:
+		return "", p.cfg.NotificationSvc.EditMessage(ctx, callback.ChatID, callback.MessageID,
+			p.cfg.Printer.Transaction(ctx, tx), p.decisionKeyboard(tx))
+	}
+
+	decision := database.Decision{} // only the pressed button is stored, other fields are merged by the repo
+	var answer string
+
+	switch action {
+	case callbackCommit:
+		decision.Action = database.DecisionActionCommit
+		answer = "Will be committed."
+	case callbackSkip:
+		decision.Action = database.DecisionActionSkip
+		answer = "Will be skipped."
+	case callbackDuplicate:
+		decision.Action = database.DecisionActionDuplicate
+		answer = "Marked as duplicate."
+	case callbackSetCategory:
+		if len(parts) < 3 {
+			return "", errors.Newf("invalid callback data %s", callback.Data)
+		}
+
+		categories, listErr := p.cfg.FireflySvc.ListCategories(ctx)
+		if listErr != nil {
+			return "", listErr
+		}
+
+		category, ok := lo.Find(categories, func(item *firefly.Category) bool {
+			return item.Id == parts[2]
+		})
+		if !ok {
+			return "", errors.Newf("category %s not found", parts[2])
+		}
+
+		decision.CategoryName = category.Attributes.Name
+		answer = fmt.Sprintf("Category: %s", category.Attributes.Name)
+	default:
+		return "", errors.Newf("unknown callback action %s", action)
+	}
+
+	decision.UpdatedAt = time.Now().UTC()
+
+	if err = p.cfg.Repo.UpdateDecision(ctx, callback.TransactionSource, tx.Original.OriginalMessage.ID,
+		key, &decision); err != nil {
+		return "", err
+	}
+
+	if tx, err = p.findPending(ctx, callback, key); err != nil { // render with the new decision
+		return "", err
+	}
+
+	if tx == nil {
+		return answer, nil
+	}
+
+	return answer, p.cfg.NotificationSvc.EditMessage(ctx, callback.ChatID, callback.MessageID,
+		p.cfg.Printer.Transaction(ctx, tx), p.decisionKeyboard(tx))
+}
+
+// findPending looks the transaction up in the stored parse results of the pending messages and applies
+// their decisions, duplicate keys are not checked again.
+func (p *Processor) findPending(
+	ctx context.Context,
+	callback Callback,
+	key string,
+) (*firefly.MappedTransaction, error) {
+	mappedTx, _, err := p.parseLatestMessages(ctx, callback.TransactionSource, callback.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	p.flagProbableDuplicates(mappedTx)
+	p.applyDecisions(mappedTx)
+
+	tx, _ := lo.Find(mappedTx, func(item *firefly.MappedTransaction) bool {
+		return item.DecisionKey == key
+	})
+
+	return tx, nil
+}
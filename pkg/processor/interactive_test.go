@@ -0,0 +1,320 @@
+package processor_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
+	parser2 "github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+)
+
+type interactiveEnv struct {
+	pr        *processor.Processor
+	notifySvc *MockNotificationSvc
+	printer   *MockPrinter
+	repo      *MockRepo
+	ffSvc     *MockFirefly
+	message   *database.Message
+}
+
+// newInteractiveEnv serves one pending message with two transactions, decisions and parse results persist on the message.
+func newInteractiveEnv(t *testing.T) *interactiveEnv {
+	env := &interactiveEnv{
+		notifySvc: NewMockNotificationSvc(gomock.NewController(t)),
+		printer:   NewMockPrinter(gomock.NewController(t)),
+		repo:      NewMockRepo(gomock.NewController(t)),
+		ffSvc:     NewMockFirefly(gomock.NewController(t)),
+		message: &database.Message{
+			ID:                "message-id",
+			ChatID:            1234,
+			MessageID:         1,
+			TransactionSource: database.Paribas,
+		},
+	}
+
+	prParser := NewMockParser(gomock.NewController(t))
+
+	env.pr = processor.NewProcessor(&processor.Config{
+		NotificationSvc:  env.notifySvc,
+		Printer:          env.printer,
+		Repo:             env.repo,
+		FireflySvc:       env.ffSvc,
+		DuplicateCleaner: NewMockDuplicateCleaner(gomock.NewController(t)),
+		Parsers: map[database.TransactionSource]processor.Parser{
+			database.Paribas: prParser,
+		},
+	})
+
+	env.repo.EXPECT().GetLatestMessages(gomock.Any(), database.Paribas).
+		Return([]*database.Message{env.message}, nil).AnyTimes()
+	env.repo.EXPECT().UpdateMessages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, messages []*database.Message) error {
+			for _, msg := range messages {
+				*env.message = *msg
+			}
+
+			return nil
+		}).AnyTimes()
+	env.repo.EXPECT().UpdateDecision(gomock.Any(), database.Paribas, env.message.ID, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			ctx context.Context,
+			_ database.TransactionSource,
+			_ string,
+			key string,
+			update *database.Decision,
+		) error {
+			decision := lo.FromPtr(env.message.Decisions[key]) // merged the way storage backends do
+			if update.Action != database.DecisionActionNone {
+				decision.Action = update.Action
+			}
+
+			if update.CategoryName != "" {
+				decision.CategoryName = update.CategoryName
+			}
+
+			decision.UpdatedAt = update.UpdatedAt
+			env.message.Decisions = lo.Assign(env.message.Decisions, map[string]*database.Decision{key: &decision})
+
+			return nil
+		}).AnyTimes()
+	env.repo.EXPECT().UpdateParsed(gomock.Any(), database.Paribas, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ database.TransactionSource, parsed map[string]*database.ParsedMessage) error {
+			env.message.Parsed = parsed[env.message.ID]
+
+			return nil
+		}).AnyTimes()
+
+	prParser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, raw []*parser2.Record) ([]*database.Transaction, error) {
+			date := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+			return []*database.Transaction{
+				{
+					Description:     "Coffee",
+					Date:            date,
+					SourceAmount:    decimal.RequireFromString("3.50"),
+					OriginalMessage: raw[0].Message,
+				},
+				{
+					Description:     "Coffee",
+					Date:            date,
+					SourceAmount:    decimal.RequireFromString("3.50"),
+					OriginalMessage: raw[0].Message,
+				},
+			}, nil
+		}).MaxTimes(1) // button presses reuse the stored parse result
+	env.ffSvc.EXPECT().MapTransactions(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, transactions []*database.Transaction) ([]*firefly.MappedTransaction, error) {
+			var mapped []*firefly.MappedTransaction
+
+			for _, tx := range transactions {
+				mapped = append(mapped, &firefly.MappedTransaction{
+					Original: tx,
+					Transaction: &firefly.Transaction{
+						Type:        "withdrawal",
+						Amount:      tx.SourceAmount.StringFixed(2),
+						Description: tx.Description,
+					},
+				})
+			}
+
+			return mapped, nil
+		}).MaxTimes(1)
+
+	return env
+}
+
+// dry runs an interactive /dry and returns the decision keys of sent transactions.
+func (env *interactiveEnv) dry(t *testing.T) []string {
+	var keys []string
+
+	env.printer.EXPECT().Stat(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("stat")
+	env.notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), "stat").
+		Return(nil)
+	env.printer.EXPECT().Transaction(gomock.Any(), gomock.Any()).
+		Return("tx").Times(2)
+	env.notifySvc.EXPECT().SendKeyboard(gomock.Any(), int64(1234), "tx", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, chatID int64, text string, keyboard notifications.Keyboard) error {
+			assert.Len(t, keyboard, 2)
+
+			key := strings.TrimPrefix(keyboard[0][0].CallbackData, "commit:")
+			assert.Len(t, key, 12)
+			assert.Equal(t, "skip:"+key, keyboard[0][1].CallbackData)
+			assert.Equal(t, "duplicate:"+key, keyboard[1][0].CallbackData)
+			assert.Equal(t, "category:"+key, keyboard[1][1].CallbackData)
+
+			keys = append(keys, key)
+
+			return nil
+		}).Times(2)
+
+	assert.NoError(t, env.pr.ProcessMessage(context.TODO(), processor.Message{
+		ChatID:            1234,
+		TransactionSource: database.Paribas,
+		Content:           "/dry",
+		Configuration:     common.ChatConfiguration{Interactive: true},
+	}))
+
+	return keys
+}
+
+func (env *interactiveEnv) press(t *testing.T, data string, answer string) {
+	env.notifySvc.EXPECT().AnswerCallback(gomock.Any(), "callback-id", answer).
+		Return(nil)
+
+	assert.NoError(t, env.pr.ProcessCallback(context.TODO(), processor.Callback{
+		ID:                "callback-id",
+		ChatID:            1234,
+		MessageID:         99,
+		Data:              data,
+		TransactionSource: database.Paribas,
+	}))
+}
+
+func TestInteractiveSkip(t *testing.T) {
+	env := newInteractiveEnv(t)
+
+	keys := env.dry(t)
+	if !assert.Len(t, keys, 2) {
+		return
+	}
+
+	assert.NotEqual(t, keys[0], keys[1]) // same rows of one file get own keys
+
+	env.printer.EXPECT().Transaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, tx *firefly.MappedTransaction) string {
+			assert.ErrorIs(t, tx.Error, common.ErrSkipped)
+			assert.Equal(t, database.DecisionActionSkip, tx.Decision.Action)
+
+			return "skipped tx"
+		})
+	env.notifySvc.EXPECT().EditMessage(gomock.Any(), int64(1234), int64(99), "skipped tx", gomock.Any()).
+		Return(nil)
+
+	env.press(t, "skip:"+keys[0], "Will be skipped.")
+
+	assert.Equal(t, database.DecisionActionSkip, env.message.Decisions[keys[0]].Action)
+
+	env.ffSvc.EXPECT().CreateTransactions(gomock.Any(), gomock.Any(), false).
+		Return(&firefly.TransactionGroup{Id: "42"}, nil) // only the second one
+	env.repo.EXPECT().SaveCommitBatch(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, batch *database.CommitBatch) error {
+			statuses := map[database.CommitStatus]int{}
+			for _, tx := range batch.Transactions {
+				statuses[tx.Status] += 1
+			}
+
+			assert.Equal(t, map[database.CommitStatus]int{
+				database.CommitStatusAcknowledged: 1,
+				database.CommitStatusCommitted:    1,
+			}, statuses)
+
+			return nil
+		})
+	env.notifySvc.EXPECT().React(gomock.Any(), int64(1234), int64(1), gomock.Any()).
+		Return(nil)
+	env.printer.EXPECT().Commit(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("committed")
+	env.notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), "committed").
+		Return(nil)
+
+	assert.NoError(t, env.pr.ProcessMessage(context.TODO(), processor.Message{
+		ChatID:            1234,
+		TransactionSource: database.Paribas,
+		Content:           "/commit",
+	}))
+}
+
+func TestInteractiveCategory(t *testing.T) {
+	env := newInteractiveEnv(t)
+
+	keys := env.dry(t)
+	if !assert.Len(t, keys, 2) {
+		return
+	}
+
+	categories := []*firefly.Category{
+		{Id: "1", Attributes: firefly.CategoryAttributes{Name: "Groceries"}},
+		{Id: "2", Attributes: firefly.CategoryAttributes{Name: "Eating out"}},
+	}
+
+	env.ffSvc.EXPECT().ListCategories(gomock.Any()).
+		Return(categories, nil).Times(2)
+	env.printer.EXPECT().Transaction(gomock.Any(), gomock.Any()).
+		Return("tx")
+	env.notifySvc.EXPECT().EditMessage(gomock.Any(), int64(1234), int64(99), "tx", notifications.Keyboard{
+		{
+			{Text: "Groceries", CallbackData: "setcategory:" + keys[1] + ":1"},
+			{Text: "Eating out", CallbackData: "setcategory:" + keys[1] + ":2"},
+		},
+		{
+			{Text: "↩️ Back", CallbackData: "back:" + keys[1]},
+		},
+	}).Return(nil)
+
+	env.press(t, "category:"+keys[1], "")
+
+	env.printer.EXPECT().Transaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, tx *firefly.MappedTransaction) string {
+			assert.NoError(t, tx.Error)
+			assert.Equal(t, "Eating out", tx.Transaction.CategoryName)
+
+			return "categorised tx"
+		})
+	env.notifySvc.EXPECT().EditMessage(gomock.Any(), int64(1234), int64(99), "categorised tx", gomock.Any()).
+		Return(nil)
+
+	env.press(t, "setcategory:"+keys[1]+":2", "Category: Eating out")
+
+	assert.Equal(t, "Eating out", env.message.Decisions[keys[1]].CategoryName)
+	assert.Nil(t, env.message.Decisions[keys[0]])
+
+	env.printer.EXPECT().Transaction(gomock.Any(), gomock.Any()).
+		Return("committed tx")
+	env.notifySvc.EXPECT().EditMessage(gomock.Any(), int64(1234), int64(99), "committed tx", gomock.Any()).
+		Return(nil)
+
+	env.press(t, "commit:"+keys[1], "Will be committed.")
+
+	assert.Equal(t, database.DecisionActionCommit, env.message.Decisions[keys[1]].Action)
+	assert.Equal(t, "Eating out", env.message.Decisions[keys[1]].CategoryName)
+}
+
+func TestInteractiveCallbackErrors(t *testing.T) {
+	env := newInteractiveEnv(t)
+
+	env.press(t, "skip:unknown", "Transaction is not pending anymore.")
+	env.press(t, "invalid", "Failed to process: invalid callback data invalid")
+}
+
+func TestInteractivePermissions(t *testing.T) {
+	env := newInteractiveEnv(t)
+
+	env.notifySvc.EXPECT().AnswerCallback(gomock.Any(), "callback-id",
+		"Failed to process: permission denied: changing decisions requires the importer role").
+		Return(nil)
+
+	assert.NoError(t, env.pr.ProcessCallback(context.TODO(), processor.Callback{
+		ID:                "callback-id",
+		ChatID:            1234,
+		MessageID:         99,
+		UserID:            "2",
+		Data:              "skip:unknown",
+		TransactionSource: database.Paribas,
+		Configuration: common.ChatConfiguration{
+			Users: map[string]common.Role{"1": common.RoleImporter, "2": common.RoleViewer},
+		},
+	}))
+}
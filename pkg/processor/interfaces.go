@@ -2,10 +2,13 @@ package processor
 
 import (
 	"context"
+	"time"
 
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
 	parser2 "github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
 )
 
 //go:generate mockgen -destination interfaces_mocks_test.go -package processor_test -source=interfaces.go
@@ -15,6 +18,19 @@ type Repo interface {
 	GetLatestMessages(ctx context.Context, source database.TransactionSource) ([]*database.Message, error)
 	Clear(ctx context.Context, transactionSource database.TransactionSource) error
 	UpdateMessages(ctx context.Context, message []*database.Message) error
+	// UpdateParsed stores the parse results of pending messages, processed and deleted messages stay untouched.
+	UpdateParsed(ctx context.Context, source database.TransactionSource, parsed map[string]*database.ParsedMessage) error
+	// UpdateDecision merges the non-empty fields of the decision into the stored one of a pending message.
+	UpdateDecision(
+		ctx context.Context,
+		source database.TransactionSource,
+		messageID string,
+		key string,
+		decision *database.Decision,
+	) error
+	GetMessages(ctx context.Context, source database.TransactionSource, ids []string) ([]*database.Message, error)
+	SaveCommitBatch(ctx context.Context, batch *database.CommitBatch) error
+	GetCommitBatches(ctx context.Context, source database.TransactionSource, limit int) ([]*database.CommitBatch, error)
 }
 
 type Printer interface {
@@ -47,6 +63,59 @@ type Printer interface {
 		mappedTx []*firefly.MappedTransaction,
 		errArr []error,
 	) string
+
+	Undo(
+		_ context.Context,
+		batch *database.CommitBatch,
+		errArr []error,
+	) string
+
+	History(
+		_ context.Context,
+		batches []*database.CommitBatch,
+	) string
+
+	Balances(
+		_ context.Context,
+		checks []*firefly.BalanceCheck,
+	) string
+
+	Reconcile(
+		_ context.Context,
+		result *reconcile.Result,
+	) string
+
+	Transaction(
+		_ context.Context,
+		tx *firefly.MappedTransaction,
+	) string
+
+	AccountMappings(
+		_ context.Context,
+		mappings []*database.AccountMapping,
+		accounts []*firefly.Account,
+	) string
+
+	Error(
+		_ context.Context,
+		command string,
+		err error,
+	) string
+
+	Page(
+		_ context.Context,
+		page int,
+		pages int,
+	) string
+}
+
+// DocumentPrinter renders transactions as a file, large /dry results are sent as a document.
+type DocumentPrinter interface {
+	Document(
+		ctx context.Context,
+		mappedTx []*firefly.MappedTransaction,
+		errArr []error,
+	) (notifications.Document, error)
 }
 
 type Parser interface {
@@ -64,6 +133,7 @@ type Parser interface {
 
 type Firefly interface {
 	ListAccounts(ctx context.Context) ([]*firefly.Account, error)
+	ListCategories(ctx context.Context) ([]*firefly.Category, error)
 	MapTransactions(
 		ctx context.Context,
 		transactions []*database.Transaction,
@@ -72,7 +142,21 @@ type Firefly interface {
 		ctx context.Context,
 		tx *firefly.Transaction,
 		errorOnDuplicate bool,
-	) (*firefly.Transaction, error)
+	) (*firefly.TransactionGroup, error)
+	DeleteTransaction(
+		ctx context.Context,
+		id string,
+	) error
+	CheckBalances(
+		ctx context.Context,
+		balances []*database.Balance,
+	) ([]*firefly.BalanceCheck, error)
+	ListAccountTransactions(
+		ctx context.Context,
+		accountID string,
+		start time.Time,
+		end time.Time,
+	) ([]*firefly.TransactionGroup, error)
 }
 
 type NotificationSvc interface {
@@ -90,6 +174,34 @@ type NotificationSvc interface {
 	) error
 
 	GetFile(ctx context.Context, fileID string) ([]byte, error)
+
+	SendDocument(
+		ctx context.Context,
+		chatID int64,
+		document notifications.Document,
+		caption string,
+	) error
+
+	SendKeyboard(
+		ctx context.Context,
+		chatID int64,
+		text string,
+		keyboard notifications.Keyboard,
+	) error
+
+	EditMessage(
+		ctx context.Context,
+		chatID int64,
+		messageID int64,
+		text string,
+		keyboard notifications.Keyboard,
+	) error
+
+	AnswerCallback(
+		ctx context.Context,
+		callbackID string,
+		text string,
+	) error
 }
 
 type DuplicateCleaner interface {
@@ -104,5 +216,24 @@ type DuplicateCleaner interface {
 		key string,
 		txSource database.TransactionSource,
 	) error
+
+	RemoveDuplicateKeys(
+		ctx context.Context,
+		keys []string,
+		txSource database.TransactionSource,
+	) error
 	HashKey(string) string
 }
+
+type Rules interface {
+	Apply(
+		ctx context.Context,
+		mappedTx []*firefly.MappedTransaction,
+	)
+}
+
+type AccountMapper interface {
+	Mappings(ctx context.Context) ([]*database.AccountMapping, error)
+	Set(ctx context.Context, pattern string, fireflyAccountID string) error
+	Delete(ctx context.Context, pattern string) error
+}
@@ -0,0 +1,335 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+)
+
+const (
+	parsedVersion = "1" // bump when parsing or mapping changes, stored results of older versions are re-parsed
+	// parsedTTL bounds how long stored results are reused, firefly accounts and transactions may change meanwhile.
+	parsedTTL = 15 * time.Minute
+)
+
+// parsedErrorKinds are the well known errors kept when a mapping error is stored.
+var parsedErrorKinds = map[string]error{
+	"notSupported": common.ErrOperationNotSupported,
+}
+
+// storedError is a restored mapping error, it still matches the well known errors it matched when stored.
+type storedError struct {
+	msg   string
+	kinds []error
+}
+
+func (e *storedError) Error() string {
+	return e.msg
+}
+
+func (e *storedError) Is(target error) bool {
+	return lo.Contains(e.kinds, target)
+}
+
+// parseLatestMessages returns the parsed and mapped pending messages of the source. The result is stored with
+// the messages and reused while the pending messages, chat currency and account mappings stay the same.
+func (p *Processor) parseLatestMessages(
+	ctx context.Context,
+	transactionSource database.TransactionSource,
+	cfg common.ChatConfiguration,
+) ([]*firefly.MappedTransaction, []error, error) {
+	messages, err := p.cfg.Repo.GetLatestMessages(ctx, transactionSource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parser, ok := p.cfg.Parsers[transactionSource]
+	if !ok {
+		return nil, nil, errors.Newf("parser for source %v not found", transactionSource)
+	}
+
+	fingerprint, err := p.parsedFingerprint(ctx, transactionSource, cfg, messages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if mapped, errArr, ok := p.storedParsed(messages, fingerprint); ok {
+		return mapped, errArr, nil
+	}
+
+	mapped, parseErrors, err := p.parseMessages(ctx, parser, cfg, messages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if storeErr := p.storeParsed(ctx, transactionSource, messages, fingerprint, mapped, parseErrors); storeErr != nil {
+		zerolog.Ctx(ctx).Error().Err(storeErr).Msg("failed to store parsed messages")
+	}
+
+	var errArr []error
+	for _, msg := range messages {
+		errArr = append(errArr, parseErrors[msg.ID]...)
+	}
+
+	return mapped, errArr, nil
+}
+
+func (p *Processor) parsedFingerprint(
+	ctx context.Context,
+	transactionSource database.TransactionSource,
+	cfg common.ChatConfiguration,
+	messages []*database.Message,
+) (string, error) {
+	hash := sha256.New()
+
+	write := func(values ...string) {
+		for _, v := range values {
+			hash.Write([]byte(v))
+			hash.Write([]byte{0})
+		}
+	}
+
+	write(parsedVersion, string(transactionSource), cfg.Currency)
+
+	messageIDs := lo.Map(messages, func(item *database.Message, _ int) string {
+		return item.ID
+	})
+	sort.Strings(messageIDs)
+	write(messageIDs...)
+
+	if p.cfg.AccountMapper != nil {
+		mappings, err := p.cfg.AccountMapper.Mappings(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		for _, mapping := range mappings {
+			write(mapping.Pattern, mapping.FireflyAccountID)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// storedParsed restores the mapped transactions stored with the messages, ok is false when any of them is
+// missing or out of date.
+func (p *Processor) storedParsed(
+	messages []*database.Message,
+	fingerprint string,
+) ([]*firefly.MappedTransaction, []error, bool) {
+	if len(messages) == 0 {
+		return nil, nil, false
+	}
+
+	byID := map[string]*database.Message{}
+	now := time.Now().UTC()
+
+	for _, msg := range messages {
+		if msg.Parsed == nil || msg.Parsed.Fingerprint != fingerprint || now.Sub(msg.Parsed.ParsedAt) > parsedTTL {
+			return nil, nil, false
+		}
+
+		byID[msg.ID] = msg
+	}
+
+	var stored []*database.ParsedTransaction
+	var owners []*database.Message
+	var errArr []error
+
+	for _, msg := range messages {
+		for _, parseErr := range msg.Parsed.Errors {
+			errArr = append(errArr, errors.New(parseErr))
+		}
+
+		for _, parsedTx := range msg.Parsed.Transactions {
+			stored = append(stored, parsedTx)
+			owners = append(owners, msg)
+		}
+	}
+
+	mapped := make([]*firefly.MappedTransaction, 0, len(stored))
+
+	for i, parsedTx := range stored {
+		tx, err := p.restoreParsed(parsedTx, owners[i], byID)
+		if err != nil {
+			return nil, nil, false
+		}
+
+		mapped = append(mapped, tx)
+	}
+
+	positions := map[*firefly.MappedTransaction]int{}
+	for i, tx := range mapped {
+		positions[tx] = stored[i].Position
+	}
+
+	sort.SliceStable(mapped, func(i, j int) bool {
+		return positions[mapped[i]] < positions[mapped[j]]
+	})
+
+	return mapped, errArr, true
+}
+
+func (p *Processor) restoreParsed(
+	parsedTx *database.ParsedTransaction,
+	owner *database.Message,
+	byID map[string]*database.Message,
+) (*firefly.MappedTransaction, error) {
+	if parsedTx.Transaction == nil || len(parsedTx.DuplicateMessageIDs) != len(parsedTx.Transaction.DuplicateTransactions) {
+		return nil, errors.New("stored transaction is incomplete")
+	}
+
+	original := *parsedTx.Transaction // stored result stays untouched
+	original.OriginalMessage = owner
+	original.DuplicateTransactions = nil
+
+	for i, dup := range parsedTx.Transaction.DuplicateTransactions {
+		msg, ok := byID[parsedTx.DuplicateMessageIDs[i]]
+		if !ok {
+			return nil, errors.Newf("message %s is not pending", parsedTx.DuplicateMessageIDs[i])
+		}
+
+		dupCopy := *dup
+		dupCopy.OriginalMessage = msg
+		original.DuplicateTransactions = append(original.DuplicateTransactions, &dupCopy)
+	}
+
+	tx := &firefly.MappedTransaction{
+		Original:     &original,
+		MatchedRules: parsedTx.MatchedRules,
+	}
+
+	if len(parsedTx.FireflyPayload) > 0 {
+		if err := json.Unmarshal(parsedTx.FireflyPayload, &tx.Transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(parsedTx.ProbableDuplicate) > 0 {
+		if err := json.Unmarshal(parsedTx.ProbableDuplicate, &tx.ProbableDuplicate); err != nil {
+			return nil, err
+		}
+	}
+
+	if parsedTx.Error != "" {
+		storedErr := &storedError{msg: parsedTx.Error}
+
+		for _, kind := range parsedTx.ErrorKinds {
+			if sentinel, ok := parsedErrorKinds[kind]; ok {
+				storedErr.kinds = append(storedErr.kinds, sentinel)
+			}
+		}
+
+		tx.Error = storedErr
+	}
+
+	return tx, nil
+}
+
+// storeParsed saves the mapped transactions with the messages they originate from. Only the parse result is
+// written, decisions and commits made meanwhile are kept.
+func (p *Processor) storeParsed(
+	ctx context.Context,
+	transactionSource database.TransactionSource,
+	messages []*database.Message,
+	fingerprint string,
+	mapped []*firefly.MappedTransaction,
+	parseErrors map[string][]error,
+) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	parsed := map[string]*database.ParsedMessage{}
+	now := time.Now().UTC()
+
+	for _, msg := range messages {
+		parsed[msg.ID] = &database.ParsedMessage{
+			Fingerprint: fingerprint,
+			ParsedAt:    now,
+		}
+
+		for _, parseErr := range parseErrors[msg.ID] {
+			parsed[msg.ID].Errors = append(parsed[msg.ID].Errors, parseErr.Error())
+		}
+	}
+
+	for i, tx := range mapped {
+		parsedTx, err := p.newParsedTransaction(i, tx)
+		if err != nil {
+			return err
+		}
+
+		target, ok := parsed[tx.Original.OriginalMessage.ID]
+		if !ok {
+			return errors.Newf("message %s is not pending", tx.Original.OriginalMessage.ID)
+		}
+
+		target.Transactions = append(target.Transactions, parsedTx)
+	}
+
+	return p.cfg.Repo.UpdateParsed(ctx, transactionSource, parsed)
+}
+
+func (p *Processor) newParsedTransaction(
+	position int,
+	tx *firefly.MappedTransaction,
+) (*database.ParsedTransaction, error) {
+	if tx.Original == nil || tx.Original.OriginalMessage == nil {
+		return nil, errors.New("transaction has no original message")
+	}
+
+	original := *tx.Original
+	original.ParsingError = nil // kept in Error
+
+	parsedTx := &database.ParsedTransaction{
+		Position:     position,
+		Transaction:  &original,
+		MatchedRules: tx.MatchedRules,
+	}
+
+	for _, dup := range tx.Original.DuplicateTransactions {
+		if dup.OriginalMessage == nil {
+			return nil, errors.New("duplicate transaction has no original message")
+		}
+
+		parsedTx.DuplicateMessageIDs = append(parsedTx.DuplicateMessageIDs, dup.OriginalMessage.ID)
+	}
+
+	if tx.Error != nil {
+		parsedTx.Error = tx.Error.Error()
+
+		for kind, sentinel := range parsedErrorKinds {
+			if errors.Is(tx.Error, sentinel) {
+				parsedTx.ErrorKinds = append(parsedTx.ErrorKinds, kind)
+			}
+		}
+	}
+
+	var err error
+
+	if tx.Transaction != nil {
+		if parsedTx.FireflyPayload, err = json.Marshal(tx.Transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	if tx.ProbableDuplicate != nil {
+		if parsedTx.ProbableDuplicate, err = json.Marshal(tx.ProbableDuplicate); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsedTx, nil
+}
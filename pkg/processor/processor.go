@@ -3,7 +3,8 @@ package processor
 import (
 	"context"
 	"encoding/hex"
-	"fmt"
+	"encoding/json"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,12 +19,15 @@ import (
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
 	parser2 "github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
 )
 
 const (
 	reactionAccepted  = "🤝"
 	reactionCommitted = "🍾"
 	failedToCommit    = "🤬"
+
+	defaultHistoryLimit = 5
 )
 
 type Processor struct {
@@ -37,6 +41,13 @@ type Config struct {
 	FireflySvc       Firefly
 	DuplicateCleaner DuplicateCleaner
 	Printer          Printer
+	Rules            Rules // optional
+	// DuplicateWindow enables the search of probable duplicates among firefly transactions within ± the window.
+	DuplicateWindow time.Duration // optional
+	AccountMapper   AccountMapper // optional
+	// Document receives /dry results with more than DocumentThreshold transactions, sent as an attachment.
+	Document          DocumentPrinter // optional
+	DocumentThreshold int             // optional
 }
 
 func NewProcessor(
@@ -59,8 +70,22 @@ func (p *Processor) ProcessMessage(
 	lower := strings.ToLower(message.Content)
 	var err error
 
-	trimmed := strings.Split(lower, "@")
-	switch trimmed[0] {
+	var command string
+	if fields := strings.Fields(lower); len(fields) > 0 { // commands may carry arguments, e.g. /history 10
+		command = strings.Split(fields[0], "@")[0]
+	}
+
+	action := command
+	if !strings.HasPrefix(action, "/") { // not a command, the message is stored as a statement
+		action = "uploading statements"
+	}
+
+	if err = authorize(message.Configuration, message.UserID, commandRole(command, lower), action); err != nil {
+		p.SendErrorMessage(ctx, err, message)
+		return nil
+	}
+
+	switch command {
 	case "/dry":
 		err = p.DryRun(ctx, message)
 	case "/stat":
@@ -73,6 +98,14 @@ func (p *Processor) ProcessMessage(
 		err = p.Commit(ctx, message)
 	case "/clear":
 		err = p.Clear(ctx, message)
+	case "/undo":
+		err = p.Undo(ctx, message)
+	case "/history":
+		err = p.History(ctx, message)
+	case "/reconcile":
+		err = p.Reconcile(ctx, message)
+	case "/map":
+		err = p.Map(ctx, message)
 	default:
 		err = p.AddMessage(ctx, message)
 	}
@@ -85,21 +118,98 @@ func (p *Processor) ProcessMessage(
 	return err
 }
 
+// commandRole returns the role required by the command, messages which are not commands are uploads.
+func commandRole(command string, content string) common.Role {
+	switch command {
+	case "/dry", "/stat", "/errors", "/duplicates", "/history", "/reconcile":
+		return common.RoleViewer
+	case "/commit":
+		return common.RoleImporter
+	case "/clear", "/undo":
+		return common.RoleAdmin
+	case "/map":
+		if len(strings.Fields(content)) > 1 { // changes mappings
+			return common.RoleAdmin
+		}
+
+		return common.RoleViewer
+	default:
+		return common.RoleImporter
+	}
+}
+
+func authorize(cfg common.ChatConfiguration, userID string, required common.Role, action string) error {
+	if cfg.UserRole(userID).Allows(required) {
+		return nil
+	}
+
+	return errors.Mark(errors.Newf("permission denied: %s requires the %s role", action, required),
+		common.ErrPermissionDenied)
+}
+
 func (p *Processor) AddMessage(
 	ctx context.Context,
 	message Message,
 ) error {
-	var targetMessages []database.Message
+	var fileData []byte
 
 	if message.FileID != "" {
-		fileData, fileErr := p.cfg.NotificationSvc.GetFile(ctx, message.FileID)
+		var fileErr error
+
+		fileData, fileErr = p.cfg.NotificationSvc.GetFile(ctx, message.FileID)
 		if fileErr != nil {
 			return errors.Wrapf(fileErr, "failed to get file")
 		}
+	}
+
+	if _, err := p.StoreMessage(ctx, message, fileData); err != nil {
+		return err
+	}
+
+	if err := p.cfg.NotificationSvc.React(ctx, message.ChatID, message.MessageID, reactionAccepted); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to react to message")
+	}
+
+	return nil
+}
+
+// StoreMessage adds the message as pending, fileData is split by the parser of the source when set.
+// Returns the number of stored messages.
+func (p *Processor) StoreMessage(
+	ctx context.Context,
+	message Message,
+	fileData []byte,
+) (int, error) {
+	targetMessages, err := p.NewMessages(ctx, message, fileData)
+	if err != nil {
+		return 0, err
+	}
 
-		splitted, err := p.cfg.Parsers[message.TransactionSource].SplitExcel(ctx, fileData)
+	if err = p.StoreMessages(ctx, targetMessages); err != nil {
+		return 0, err
+	}
+
+	return len(targetMessages), nil
+}
+
+// NewMessages returns the pending messages of the message without storing them, fileData is split by the parser
+// of the source when set.
+func (p *Processor) NewMessages(
+	ctx context.Context,
+	message Message,
+	fileData []byte,
+) ([]database.Message, error) {
+	var targetMessages []database.Message
+
+	if fileData != nil {
+		parser, ok := p.cfg.Parsers[message.TransactionSource]
+		if !ok {
+			return nil, errors.Newf("parser for source %v not found", message.TransactionSource)
+		}
+
+		splitted, err := parser.SplitExcel(ctx, fileData)
 		if err != nil {
-			return errors.Wrapf(err, "failed to split file")
+			return nil, errors.Wrapf(err, "failed to split file")
 		}
 
 		for _, s := range splitted {
@@ -130,16 +240,41 @@ func (p *Processor) AddMessage(
 		})
 	}
 
-	err := p.cfg.Repo.AddMessage(ctx, targetMessages)
-	if err != nil {
-		return err
-	}
+	return targetMessages, nil
+}
 
-	if err = p.cfg.NotificationSvc.React(ctx, message.ChatID, message.MessageID, reactionAccepted); err != nil {
-		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to react to message")
+// StoreMessages adds the messages as pending.
+func (p *Processor) StoreMessages(ctx context.Context, messages []database.Message) error {
+	return p.cfg.Repo.AddMessage(ctx, messages)
+}
+
+// SupportsSource reports whether a parser is registered for the source.
+func (p *Processor) SupportsSource(source database.TransactionSource) bool {
+	_, ok := p.cfg.Parsers[source]
+
+	return ok
+}
+
+// PendingMessages returns the stored messages of the source which are not committed yet.
+func (p *Processor) PendingMessages(
+	ctx context.Context,
+	source database.TransactionSource,
+) ([]*database.Message, error) {
+	return p.cfg.Repo.GetLatestMessages(ctx, source)
+}
+
+// PendingTransactions maps the pending messages without committing them,
+// transactions hidden by the chat configuration are dropped.
+func (p *Processor) PendingTransactions(
+	ctx context.Context,
+	message Message,
+) ([]*firefly.MappedTransaction, []error, error) {
+	mappedTx, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource, message.Configuration)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return nil
+	return p.visibleTransactions(mappedTx, message.Configuration), errArr, nil
 }
 
 func (p *Processor) Clear(ctx context.Context, message Message) error {
@@ -147,76 +282,148 @@ func (p *Processor) Clear(ctx context.Context, message Message) error {
 }
 
 func (p *Processor) DryRun(ctx context.Context, message Message) error {
-	mappedTx, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource)
+	visible, errArr, err := p.PendingTransactions(ctx, message)
 	if err != nil {
 		p.SendErrorMessage(ctx, err, message)
 
 		return nil
 	}
 
-	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Dry(ctx, mappedTx, errArr))
+	if message.Configuration.Interactive {
+		return p.interactiveDry(ctx, message, visible, errArr)
+	}
+
+	if p.cfg.Document != nil && len(visible) > p.cfg.DocumentThreshold {
+		document, docErr := p.cfg.Document.Document(ctx, visible, errArr)
+		if docErr != nil {
+			return docErr
+		}
+
+		return p.cfg.NotificationSvc.SendDocument(ctx, message.ChatID, document,
+			p.cfg.Printer.Stat(ctx, visible, errArr))
+	}
+
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Dry(ctx, visible, errArr))
 }
 
 func (p *Processor) Stat(ctx context.Context, message Message) error {
-	mappedTx, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource)
+	visible, errArr, err := p.PendingTransactions(ctx, message)
 	if err != nil {
 		p.SendErrorMessage(ctx, err, message)
 
 		return nil
 	}
 
-	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Stat(ctx, mappedTx, errArr))
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Stat(ctx, visible, errArr))
 }
 
 func (p *Processor) Errors(ctx context.Context, message Message) error {
-	mappedTx, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource)
+	visible, errArr, err := p.PendingTransactions(ctx, message)
 	if err != nil {
 		p.SendErrorMessage(ctx, err, message)
 
 		return nil
 	}
 
-	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Errors(ctx, mappedTx, errArr))
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Errors(ctx, visible, errArr))
 }
 
 func (p *Processor) Duplicates(ctx context.Context, message Message) error {
-	mappedTx, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource)
+	mappedTx, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource, message.Configuration)
 	if err != nil {
 		p.SendErrorMessage(ctx, err, message)
 
 		return nil
 	}
 
-	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Duplicates(ctx, mappedTx, errArr))
+	cfg := message.Configuration
+	cfg.SkipDuplicates = false // explicitly requested
+	visible := p.visibleTransactions(mappedTx, cfg)
+
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Duplicates(ctx, visible, errArr))
+}
+
+// visibleTransactions drops the transactions the chat opted out of seeing.
+func (p *Processor) visibleTransactions(
+	mappedTx []*firefly.MappedTransaction,
+	cfg common.ChatConfiguration,
+) []*firefly.MappedTransaction {
+	if !cfg.SkipDuplicates && !cfg.SkipIncomeError {
+		return mappedTx
+	}
+
+	var visible []*firefly.MappedTransaction
+
+	for _, tx := range mappedTx {
+		if cfg.SkipDuplicates && errors.Is(tx.Error, common.ErrDuplicate) {
+			continue
+		}
+
+		if cfg.SkipIncomeError && errors.Is(tx.Error, common.ErrOperationNotSupported) {
+			continue
+		}
+
+		visible = append(visible, tx)
+	}
+
+	return visible
+}
+
+// IsAcknowledged reports whether the transaction should be marked processed without committing.
+func (p *Processor) IsAcknowledged(
+	tx *firefly.MappedTransaction,
+	cfg common.ChatConfiguration,
+) bool {
+	if errors.Is(tx.Error, common.ErrDuplicate) || errors.Is(tx.Error, common.ErrSkipped) {
+		return true
+	}
+
+	return cfg.SkipIncomeError && errors.Is(tx.Error, common.ErrOperationNotSupported)
 }
 
 func (p *Processor) ProcessLatestMessages(
 	ctx context.Context,
 	transactionSource database.TransactionSource,
+	cfg common.ChatConfiguration,
 ) ([]*firefly.MappedTransaction, []error, error) {
-	messages, err := p.cfg.Repo.GetLatestMessages(ctx, transactionSource)
+	mappedTransactions, parseErrorsArr, err := p.parseLatestMessages(ctx, transactionSource, cfg)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	var parseErrorsArr []error
-
-	parser, ok := p.cfg.Parsers[transactionSource]
-	if !ok {
-		return nil, nil, errors.Newf("parser for source %v not found", transactionSource)
+	if err = p.checkDuplicates(ctx, mappedTransactions, transactionSource); err != nil {
+		return nil, nil, err
 	}
 
+	p.flagProbableDuplicates(mappedTransactions)
+	p.applyDecisions(mappedTransactions)
+
+	return mappedTransactions, parseErrorsArr, nil
+}
+
+// parseMessages parses and maps the messages, duplicate keys and decisions are not applied.
+func (p *Processor) parseMessages(
+	ctx context.Context,
+	parser Parser,
+	cfg common.ChatConfiguration,
+	messages []*database.Message,
+) ([]*firefly.MappedTransaction, map[string][]error, error) {
+	parseErrors := map[string][]error{}
+
 	var dataToProcess []*parser2.Record
 	for _, message := range messages {
 		rec := &parser2.Record{
-			Message: message,
-			Data:    []byte(message.Content),
+			Message:  message,
+			Data:     []byte(message.Content),
+			Currency: cfg.Currency,
 		}
 
 		if message.TransactionSource == database.Paribas {
+			var err error
+
 			rec.Data, err = hex.DecodeString(message.Content)
 			if err != nil {
-				parseErrorsArr = append(parseErrorsArr, errors.Wrapf(err, "failed to decode hex"))
+				parseErrors[message.ID] = append(parseErrors[message.ID], errors.Wrapf(err, "failed to decode hex"))
 				continue
 			}
 		}
@@ -234,11 +441,90 @@ func (p *Processor) ProcessLatestMessages(
 		return nil, nil, err
 	}
 
-	if err = p.checkDuplicates(ctx, mappedTransactions, transactionSource); err != nil {
-		return nil, nil, err
+	if p.cfg.Rules != nil {
+		p.cfg.Rules.Apply(ctx, mappedTransactions)
 	}
 
-	return mappedTransactions, parseErrorsArr, nil
+	if p.cfg.DuplicateWindow > 0 {
+		if err = p.checkProbableDuplicates(ctx, mappedTransactions); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return mappedTransactions, parseErrors, nil
+}
+
+// checkProbableDuplicates finds existing firefly transactions which look like the mapped ones,
+// e.g. entered manually or imported from another source.
+func (p *Processor) checkProbableDuplicates(
+	ctx context.Context,
+	mapped []*firefly.MappedTransaction,
+) error {
+	var pending []*firefly.MappedTransaction
+
+	for _, tx := range mapped {
+		if tx.Error != nil || tx.Transaction == nil {
+			continue
+		}
+
+		pending = append(pending, tx)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rows, err := p.listFireflyRows(ctx, pending, p.cfg.DuplicateWindow)
+	if err != nil {
+		return err
+	}
+
+	reconcile.FindDuplicates(pending, rows, p.cfg.DuplicateWindow)
+
+	return nil
+}
+
+// flagProbableDuplicates marks transactions with a probable duplicate, unless they already failed or are known duplicates.
+func (p *Processor) flagProbableDuplicates(mapped []*firefly.MappedTransaction) {
+	for _, tx := range mapped {
+		if tx.ProbableDuplicate == nil {
+			continue
+		}
+
+		if tx.Error != nil {
+			tx.ProbableDuplicate = nil
+			continue
+		}
+
+		tx.Error = errors.Join(tx.Error, common.ErrProbableDuplicate)
+	}
+}
+
+// listFireflyRows returns firefly transactions of the accounts of bank transactions, the period is extended by margin.
+func (p *Processor) listFireflyRows(
+	ctx context.Context,
+	bank []*firefly.MappedTransaction,
+	margin time.Duration,
+) ([]*reconcile.Row, error) {
+	start, end := reconcile.Period(bank)
+
+	var groups []*firefly.TransactionGroup
+
+	for _, account := range reconcile.Accounts(bank) {
+		accountGroups, err := p.cfg.FireflySvc.ListAccountTransactions(
+			ctx,
+			account,
+			start.Add(-margin),
+			end.Add(margin),
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list transactions of account %s", account)
+		}
+
+		groups = append(groups, accountGroups...)
+	}
+
+	return reconcile.NewRows(groups), nil
 }
 
 func (p *Processor) checkDuplicates(
@@ -294,23 +580,69 @@ func (p *Processor) checkDuplicates(
 }
 
 func (p *Processor) Commit(ctx context.Context, message Message) error {
-	transactions, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource)
+	transactions, errArr, err := p.CommitPending(ctx, message)
 	if err != nil {
 		p.SendErrorMessage(ctx, err, message)
 		return err
 	}
 
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID,
+		p.CommitSummary(ctx, message.Configuration, transactions, errArr))
+}
+
+// CommitSummary prints the committed transactions followed by the balance drift of their accounts.
+func (p *Processor) CommitSummary(
+	ctx context.Context,
+	cfg common.ChatConfiguration,
+	transactions []*firefly.MappedTransaction,
+	errArr []error,
+) string {
+	summary := p.cfg.Printer.Commit(ctx, p.visibleTransactions(transactions, cfg), errArr)
+
+	if balances := p.latestBalances(transactions); len(balances) > 0 {
+		checks, checkErr := p.cfg.FireflySvc.CheckBalances(ctx, balances)
+		if checkErr != nil {
+			zerolog.Ctx(ctx).Error().Err(checkErr).Msg("failed to check balances")
+		} else {
+			summary += "\n" + p.cfg.Printer.Balances(ctx, checks)
+		}
+	}
+
+	return summary
+}
+
+// CommitPending commits the pending transactions of the source and returns all of them with their results.
+func (p *Processor) CommitPending(
+	ctx context.Context,
+	message Message,
+) ([]*firefly.MappedTransaction, []error, error) {
+	transactions, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource, message.Configuration)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	pool := workerpool.New(3)
 	var commitResults []*CommitResult
 	var mut sync.Mutex
 
 	var messagesToUpdate []*database.Message
 
+	batch := &database.CommitBatch{
+		ID:                uuid.NewString(),
+		TransactionSource: message.TransactionSource,
+		ChatID:            message.ChatID,
+		CreatedAt:         time.Now().UTC(),
+	}
+
 	for _, tx := range transactions {
-		if errors.Is(tx.Error, common.ErrDuplicate) { // do not commit duplicates, but mark them
+		if p.IsAcknowledged(tx, message.Configuration) { // do not commit duplicates, but mark them
 			tx.Original.OriginalMessage.IsProcessed = true
 			tx.Original.OriginalMessage.ProcessedAt = lo.ToPtr(time.Now().UTC())
+			tx.Original.OriginalMessage.Parsed = nil // reused by pending messages only
 			messagesToUpdate = append(messagesToUpdate, tx.Original.OriginalMessage)
+			batchTx := p.newCommitBatchTransaction(tx, database.CommitStatusAcknowledged)
+			batchTx.MessageIDs = []string{tx.Original.OriginalMessage.ID}
+			batch.Transactions = append(batch.Transactions, batchTx)
 			continue
 		}
 
@@ -330,6 +662,7 @@ func (p *Processor) Commit(ctx context.Context, message Message) error {
 	pool.StopWait()
 
 	var finalErr error
+	batchTransactions := map[*firefly.MappedTransaction]*database.CommitBatchTransaction{}
 
 	for _, tx := range commitResults {
 		if tx.Msg.IsProcessed {
@@ -344,17 +677,38 @@ func (p *Processor) Commit(ctx context.Context, message Message) error {
 					p.cfg.DuplicateCleaner.AddDuplicateKey(ctx, key, tx.Msg.TransactionSource),
 				)
 			}
+
+			batchTx, ok := batchTransactions[tx.Tx]
+			if !ok {
+				status := database.CommitStatusCommitted
+				if !tx.Tx.IsCommitted {
+					status = database.CommitStatusFailed
+				}
+
+				batchTx = p.newCommitBatchTransaction(tx.Tx, status)
+				batchTx.DuplicateKeys = extractedDuplicationKeys
+				batchTransactions[tx.Tx] = batchTx
+				batch.Transactions = append(batch.Transactions, batchTx)
+			}
+
+			batchTx.MessageIDs = append(batchTx.MessageIDs, tx.Msg.ID)
 		}
 	}
 
 	if err = p.cfg.Repo.UpdateMessages(ctx, messagesToUpdate); err != nil {
-		return err
+		return nil, nil, err
+	}
+
+	if len(batch.Transactions) > 0 {
+		if err = p.cfg.Repo.SaveCommitBatch(ctx, batch); err != nil { // commit already happened, /undo will miss it
+			zerolog.Ctx(ctx).Error().Err(err).Msg("failed to save commit batch")
+		}
 	}
 
 	updatedMessages := map[int64]struct{}{}
 
 	for _, upd := range commitResults {
-		if _, ok := updatedMessages[upd.Msg.MessageID]; ok {
+		if _, ok := updatedMessages[upd.Msg.MessageID]; ok || upd.Msg.ChatID == 0 { // uploaded over the api
 			continue
 		}
 
@@ -369,7 +723,234 @@ func (p *Processor) Commit(ctx context.Context, message Message) error {
 		updatedMessages[upd.Msg.MessageID] = struct{}{}
 	}
 
-	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Commit(ctx, transactions, errArr))
+	if finalErr != nil { // transactions are already in firefly
+		zerolog.Ctx(ctx).Error().Err(finalErr).Msg("failed to add duplicate keys")
+	}
+
+	return transactions, errArr, nil
+}
+
+// latestBalances returns the last bank reported balance per account of transactions present in firefly.
+func (p *Processor) latestBalances(transactions []*firefly.MappedTransaction) []*database.Balance {
+	var accounts []string
+	latest := map[string]*database.Transaction{}
+
+	for _, tx := range transactions {
+		if tx.Original == nil || (!tx.IsCommitted && !errors.Is(tx.Error, common.ErrDuplicate)) {
+			continue
+		}
+
+		for _, original := range append([]*database.Transaction{tx.Original}, tx.Original.DuplicateTransactions...) {
+			if original.Balance == nil {
+				continue
+			}
+
+			current, ok := latest[original.Balance.Account]
+			if !ok {
+				accounts = append(accounts, original.Balance.Account)
+			}
+
+			if !ok || !original.Date.Before(current.Date) {
+				latest[original.Balance.Account] = original
+			}
+		}
+	}
+
+	var balances []*database.Balance
+	for _, account := range accounts {
+		balances = append(balances, latest[account].Balance)
+	}
+
+	return balances
+}
+
+func (p *Processor) newCommitBatchTransaction(
+	tx *firefly.MappedTransaction,
+	status database.CommitStatus,
+) *database.CommitBatchTransaction {
+	batchTx := &database.CommitBatchTransaction{
+		FireflyID: tx.FireflyID,
+		Status:    status,
+	}
+
+	if tx.Error != nil {
+		batchTx.Error = tx.Error.Error()
+	}
+
+	if tx.Original != nil {
+		original := *tx.Original
+		original.DuplicateTransactions = nil // their messages are tracked in MessageIDs
+		batchTx.Transaction = &original
+	}
+
+	if tx.Transaction != nil {
+		payload, err := json.Marshal(tx.Transaction)
+		if err == nil {
+			batchTx.FireflyPayload = payload
+		}
+	}
+
+	return batchTx
+}
+
+// History lists the latest commit batches, /history accepts an optional limit.
+func (p *Processor) History(ctx context.Context, message Message) error {
+	limit := defaultHistoryLimit
+
+	if fields := strings.Fields(message.Content); len(fields) > 1 {
+		parsed, err := strconv.Atoi(fields[1])
+		if err != nil || parsed <= 0 {
+			return errors.Newf("invalid history limit %s", fields[1])
+		}
+
+		limit = parsed
+	}
+
+	batches, err := p.cfg.Repo.GetCommitBatches(ctx, message.TransactionSource, limit)
+	if err != nil {
+		return err
+	}
+
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.History(ctx, batches))
+}
+
+// Undo reverts the latest commit batch of the source: firefly transactions are deleted,
+// duplicate keys removed and the messages become pending again.
+func (p *Processor) Undo(ctx context.Context, message Message) error {
+	batches, err := p.cfg.Repo.GetCommitBatches(ctx, message.TransactionSource, 0)
+	if err != nil {
+		return err
+	}
+
+	batch, ok := lo.Find(batches, func(item *database.CommitBatch) bool {
+		return item.UndoneAt == nil
+	})
+	if !ok {
+		return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Undo(ctx, nil, nil))
+	}
+
+	var errArr []error
+	var messageIDs []string
+
+	for _, tx := range batch.Transactions {
+		if tx.Status == database.CommitStatusUndone { // done by a previous partial /undo
+			continue
+		}
+
+		if tx.FireflyID != "" {
+			if deleteErr := p.cfg.FireflySvc.DeleteTransaction(ctx, tx.FireflyID); deleteErr != nil {
+				errArr = append(errArr, errors.Wrapf(deleteErr, "failed to delete transaction %s", tx.FireflyID))
+				continue
+			}
+		}
+
+		if keyErr := p.cfg.DuplicateCleaner.RemoveDuplicateKeys(
+			ctx,
+			tx.DuplicateKeys,
+			batch.TransactionSource,
+		); keyErr != nil {
+			errArr = append(errArr, errors.Wrapf(keyErr, "failed to remove duplicate keys"))
+			continue
+		}
+
+		tx.Status = database.CommitStatusUndone
+		messageIDs = append(messageIDs, tx.MessageIDs...)
+	}
+
+	messages, err := p.cfg.Repo.GetMessages(ctx, batch.TransactionSource, lo.Uniq(messageIDs))
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range messages {
+		msg.IsProcessed = false
+		msg.ProcessedAt = nil
+	}
+
+	if err = p.cfg.Repo.UpdateMessages(ctx, messages); err != nil {
+		return err
+	}
+
+	if len(errArr) == 0 { // keep the batch open, so /undo can retry the failed part
+		batch.UndoneAt = lo.ToPtr(time.Now().UTC())
+	}
+
+	if err = p.cfg.Repo.SaveCommitBatch(ctx, batch); err != nil {
+		return err
+	}
+
+	updatedMessages := map[int64]struct{}{}
+
+	for _, msg := range messages {
+		if _, exists := updatedMessages[msg.MessageID]; exists {
+			continue
+		}
+
+		if notifyErr := p.cfg.NotificationSvc.React(ctx,
+			msg.ChatID,
+			msg.MessageID,
+			reactionAccepted,
+		); notifyErr != nil {
+			zerolog.Ctx(ctx).Error().Err(notifyErr).Msg("failed to react to message")
+		}
+
+		updatedMessages[msg.MessageID] = struct{}{}
+	}
+
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Undo(ctx, batch, errArr))
+}
+
+// Reconcile diffs pending transactions against firefly transactions of the same accounts and period,
+// /reconcile accepts an optional date tolerance in days.
+func (p *Processor) Reconcile(ctx context.Context, message Message) error {
+	opts := reconcile.DefaultOptions()
+
+	if fields := strings.Fields(message.Content); len(fields) > 1 {
+		days, err := strconv.Atoi(fields[1])
+		if err != nil || days < 0 {
+			return errors.Newf("invalid date tolerance %s", fields[1])
+		}
+
+		opts.DateTolerance = time.Duration(days) * 24 * time.Hour
+		opts.MismatchWindow = max(opts.MismatchWindow, opts.DateTolerance)
+	}
+
+	mappedTx, errArr, err := p.ProcessLatestMessages(ctx, message.TransactionSource, message.Configuration)
+	if err != nil {
+		return err
+	}
+
+	var bank []*firefly.MappedTransaction
+
+	for _, tx := range mappedTx {
+		if errors.Is(tx.Error, common.ErrOperationNotSupported) || errors.Is(tx.Error, common.ErrSkipped) {
+			continue
+		}
+
+		if tx.Transaction == nil || (tx.Error != nil &&
+			!errors.Is(tx.Error, common.ErrDuplicate) && !errors.Is(tx.Error, common.ErrProbableDuplicate)) {
+			if tx.Error != nil { // not mapped without a reason, nothing to report
+				errArr = append(errArr, tx.Error)
+			}
+
+			continue
+		}
+
+		bank = append(bank, tx)
+	}
+
+	var rows []*reconcile.Row
+
+	if len(bank) > 0 {
+		if rows, err = p.listFireflyRows(ctx, bank, opts.MismatchWindow); err != nil {
+			return err
+		}
+	}
+
+	result := reconcile.Reconcile(bank, rows, opts)
+	result.Errors = errArr
+
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID, p.cfg.Printer.Reconcile(ctx, result))
 }
 
 func (p *Processor) ExtractDuplicationKeys(tx *database.Transaction) []string {
@@ -418,12 +999,15 @@ func (p *Processor) CommitTransaction(
 		return nil
 	}
 
-	if _, err := p.cfg.FireflySvc.CreateTransactions(
+	created, err := p.cfg.FireflySvc.CreateTransactions(
 		ctx,
 		transaction.Transaction,
 		len(transaction.Original.DeduplicationKeys) > 0,
-	); err != nil {
+	)
+	if err != nil {
 		transaction.Error = errors.Join(transaction.Error, errors.Wrapf(err, "failed to commit transaction"))
+	} else if created != nil {
+		transaction.FireflyID = created.Id
 	}
 
 	reaction := reactionCommitted
@@ -455,6 +1039,7 @@ func (p *Processor) CommitTransaction(
 	for _, upd := range toUpdate {
 		upd.Msg.ProcessedAt = &now
 		upd.Msg.IsProcessed = true
+		upd.Msg.Parsed = nil
 	}
 
 	return toUpdate
@@ -462,7 +1047,70 @@ func (p *Processor) CommitTransaction(
 
 func (p *Processor) SendErrorMessage(ctx context.Context, err error, message Message) {
 	if err = p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID,
-		fmt.Sprintf("Failed to process command: %v\n Error: %v", message.Content, err)); err != nil {
+		p.cfg.Printer.Error(ctx, message.Content, err)); err != nil {
 		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to send message")
 	}
 }
+
+// Map manages account mappings: /map lists them, /map <raw> <firefly-account> adds one and /map <raw> - removes it.
+// The firefly account is either an id or a name.
+func (p *Processor) Map(ctx context.Context, message Message) error {
+	if p.cfg.AccountMapper == nil {
+		return errors.New("account mapping is not configured")
+	}
+
+	fields := strings.Fields(message.Content)
+	if len(fields) == 2 {
+		return errors.New("usage: /map <raw> <firefly-account>")
+	}
+
+	accounts, err := p.cfg.FireflySvc.ListAccounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(fields) == 3 && fields[2] == "-":
+		err = p.cfg.AccountMapper.Delete(ctx, fields[1])
+	case len(fields) > 2:
+		acc, findErr := findAccount(accounts, strings.Join(fields[2:], " "))
+		if findErr != nil {
+			return findErr
+		}
+
+		err = p.cfg.AccountMapper.Set(ctx, fields[1], acc.Id)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	mappings, err := p.cfg.AccountMapper.Mappings(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.cfg.NotificationSvc.SendMessage(ctx, message.ChatID,
+		p.cfg.Printer.AccountMappings(ctx, mappings, accounts))
+}
+
+func findAccount(accounts []*firefly.Account, idOrName string) (*firefly.Account, error) {
+	if acc, ok := lo.Find(accounts, func(item *firefly.Account) bool {
+		return item.Id == idOrName
+	}); ok {
+		return acc, nil
+	}
+
+	byName := lo.Filter(accounts, func(item *firefly.Account, _ int) bool {
+		return strings.EqualFold(item.Attributes.Name, idOrName)
+	})
+
+	switch len(byName) {
+	case 0:
+		return nil, errors.Newf("firefly account %s not found", idOrName)
+	case 1:
+		return byName[0], nil
+	default:
+		return nil, errors.Newf("multiple firefly accounts named %s, use the id", idOrName)
+	}
+}
@@ -2,15 +2,25 @@ package processor_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/golang/mock/gomock"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/notifications"
 	parser2 "github.com/skynet2/firefly-iii-privatbank-importer/pkg/parser"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/printer"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
 )
 
 func TestAddNewMessage(t *testing.T) {
@@ -170,8 +180,9 @@ func TestDuplicateMessage(t *testing.T) {
 			}, nil)
 
 		fireflySvc.EXPECT().CreateTransactions(gomock.Any(), fireflyTxs[0].Transaction, true).
-			Return(&firefly.Transaction{}, nil)
+			Return(&firefly.TransactionGroup{}, nil)
 
+		expectStoreParsed(t, repo)
 		repo.EXPECT().UpdateMessages(gomock.Any(), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, messages []*database.Message) error {
 				assert.Len(t, messages, 2)
@@ -189,6 +200,9 @@ func TestDuplicateMessage(t *testing.T) {
 		notificationSvc.EXPECT().React(gomock.Any(), int64(1234), int64(4321), "🍾").
 			Return(nil)
 
+		repo.EXPECT().SaveCommitBatch(gomock.Any(), gomock.Any()).
+			Return(nil)
+
 		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, i int64, s string) error {
 				assert.Contains(t, s, "All Ok")
@@ -268,6 +282,7 @@ func TestDuplicateMessage(t *testing.T) {
 				},
 			}, nil)
 
+		expectStoreParsed(t, repo)
 		repo.EXPECT().UpdateMessages(gomock.Any(), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, messages []*database.Message) error {
 				assert.Len(t, messages, 2)
@@ -282,6 +297,9 @@ func TestDuplicateMessage(t *testing.T) {
 		repo.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
 			Return(messages, nil)
 
+		repo.EXPECT().SaveCommitBatch(gomock.Any(), gomock.Any()).
+			Return(nil)
+
 		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, i int64, s string) error {
 				assert.Contains(t, s, "All ok")
@@ -371,10 +389,28 @@ func TestProcessorCommit(t *testing.T) {
 			}, nil)
 
 		fireflySvc.EXPECT().CreateTransactions(gomock.Any(), fireflyTxs[0].Transaction, true).
-			Return(&firefly.Transaction{}, nil)
+			Return(&firefly.TransactionGroup{Id: "ff-1"}, nil)
 		fireflySvc.EXPECT().CreateTransactions(gomock.Any(), fireflyTxs[1].Transaction, false).
-			Return(&firefly.Transaction{}, nil)
+			Return(&firefly.TransactionGroup{Id: "ff-2"}, nil)
+
+		repo.EXPECT().SaveCommitBatch(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, batch *database.CommitBatch) error {
+				assert.Equal(t, int64(111), batch.ChatID)
+				assert.Equal(t, database.PrivatBank, batch.TransactionSource)
+				assert.ElementsMatch(t, []string{"ff-1", "ff-2"}, lo.Map(batch.Transactions,
+					func(item *database.CommitBatchTransaction, _ int) string {
+						return item.FireflyID
+					}))
+
+				for _, tx := range batch.Transactions {
+					assert.Equal(t, database.CommitStatusCommitted, tx.Status)
+					assert.NotNil(t, tx.Transaction)
+					assert.Len(t, tx.MessageIDs, 1)
+				}
+				return nil
+			})
 
+		expectStoreParsed(t, repo)
 		repo.EXPECT().UpdateMessages(gomock.Any(), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, messages []*database.Message) error {
 				assert.Len(t, messages, 2)
@@ -400,6 +436,94 @@ func TestProcessorCommit(t *testing.T) {
 			ChatID:            111,
 		}))
 	})
+
+	t.Run("balance drift", func(t *testing.T) {
+		repo := NewMockRepo(gomock.NewController(t))
+		parser := NewMockParser(gomock.NewController(t))
+		fireflySvc := NewMockFirefly(gomock.NewController(t))
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		dedup := NewMockDuplicateCleaner(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			Repo:             repo,
+			DuplicateCleaner: dedup,
+			NotificationSvc:  notificationSvc,
+			FireflySvc:       fireflySvc,
+			Printer:          mockPrinter,
+			Parsers: map[database.TransactionSource]processor.Parser{
+				database.PrivatBank: parser,
+			},
+		})
+
+		messages := []*database.Message{
+			{ID: "1", ChatID: 1234, MessageID: 1, TransactionSource: database.PrivatBank},
+			{ID: "2", ChatID: 1234, MessageID: 2, TransactionSource: database.PrivatBank},
+			{ID: "3", ChatID: 1234, MessageID: 3, TransactionSource: database.PrivatBank},
+		}
+
+		now := time.Now().UTC()
+		latestBalance := &database.Balance{Account: "4*67", Amount: decimal.NewFromInt(10), Currency: "USD"}
+
+		resultTxs := []*database.Transaction{
+			{
+				OriginalMessage: messages[0],
+				Date:            now,
+				Balance:         latestBalance,
+			},
+			{
+				OriginalMessage: messages[1],
+				Date:            now.Add(-time.Hour),
+				Balance:         &database.Balance{Account: "4*67", Amount: decimal.NewFromInt(15), Currency: "USD"},
+			},
+			{
+				OriginalMessage: messages[2],
+				Date:            now,
+				Balance:         &database.Balance{Account: "4*99", Amount: decimal.NewFromInt(1), Currency: "USD"},
+			},
+		}
+
+		repo.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).Return(messages, nil)
+		parser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).Return(resultTxs, nil)
+		fireflySvc.EXPECT().MapTransactions(gomock.Any(), resultTxs).
+			Return(lo.Map(resultTxs, func(item *database.Transaction, _ int) *firefly.MappedTransaction {
+				return &firefly.MappedTransaction{
+					Original:    item,
+					Transaction: &firefly.Transaction{Description: item.OriginalMessage.ID},
+				}
+			}), nil)
+
+		fireflySvc.EXPECT().CreateTransactions(gomock.Any(), gomock.Any(), false).
+			DoAndReturn(func(ctx context.Context, tx *firefly.Transaction, _ bool) (*firefly.TransactionGroup, error) {
+				if tx.Description == "3" { // failed commits are not checked
+					return nil, errors.New("boom")
+				}
+
+				return &firefly.TransactionGroup{Id: "ff-" + tx.Description}, nil
+			}).Times(3)
+
+		expectStoreParsed(t, repo)
+		repo.EXPECT().UpdateMessages(gomock.Any(), gomock.Any()).Return(nil)
+		repo.EXPECT().SaveCommitBatch(gomock.Any(), gomock.Any()).Return(nil)
+		notificationSvc.EXPECT().React(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil).AnyTimes()
+
+		checks := []*firefly.BalanceCheck{{Balance: latestBalance, FireflyBalance: decimal.NewFromInt(12)}}
+
+		fireflySvc.EXPECT().CheckBalances(gomock.Any(), []*database.Balance{latestBalance}).
+			Return(checks, nil)
+
+		mockPrinter.EXPECT().Commit(gomock.Any(), gomock.Any(), gomock.Any()).Return("All ok")
+		mockPrinter.EXPECT().Balances(gomock.Any(), checks).Return("Balances: drift")
+
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "All ok\nBalances: drift").
+			Return(nil)
+
+		assert.NoError(t, srv.Commit(context.TODO(), processor.Message{
+			TransactionSource: database.PrivatBank,
+			ChatID:            111,
+		}))
+	})
 }
 
 func TestDryRun(t *testing.T) {
@@ -441,6 +565,55 @@ func TestDryRun(t *testing.T) {
 		}))
 	})
 
+	t.Run("document", func(t *testing.T) {
+		notifySvc := NewMockNotificationSvc(gomock.NewController(t))
+		printerSvc := NewMockPrinter(gomock.NewController(t))
+		documentSvc := NewMockDocumentPrinter(gomock.NewController(t))
+		repoSvc := NewMockRepo(gomock.NewController(t))
+		prParser := NewMockParser(gomock.NewController(t))
+		ffSvc := NewMockFirefly(gomock.NewController(t))
+
+		pr := processor.NewProcessor(&processor.Config{
+			NotificationSvc:   notifySvc,
+			Printer:           printerSvc,
+			Document:          documentSvc,
+			DocumentThreshold: 1,
+			Repo:              repoSvc,
+			FireflySvc:        ffSvc,
+			Parsers: map[database.TransactionSource]processor.Parser{
+				database.PrivatBank: prParser,
+			},
+		})
+
+		mappedTx := []*firefly.MappedTransaction{
+			{Original: &database.Transaction{}, Transaction: &firefly.Transaction{}},
+			{Original: &database.Transaction{}, Transaction: &firefly.Transaction{}},
+		}
+		document := notifications.Document{Name: "dry.csv", Data: []byte("csv")}
+
+		prParser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).
+			Return([]*database.Transaction{}, nil)
+		ffSvc.EXPECT().MapTransactions(gomock.Any(), gomock.Any()).
+			Return(mappedTx, nil)
+
+		repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
+			Return([]*database.Message{}, nil)
+
+		documentSvc.EXPECT().Document(gomock.Any(), mappedTx, gomock.Any()).
+			Return(document, nil)
+		printerSvc.EXPECT().Stat(gomock.Any(), mappedTx, gomock.Any()).
+			Return("Total transactions: 2")
+
+		notifySvc.EXPECT().SendDocument(gomock.Any(), int64(1234), document, "Total transactions: 2").
+			Return(nil)
+
+		assert.NoError(t, pr.ProcessMessage(context.Background(), processor.Message{
+			ChatID:            1234,
+			TransactionSource: database.PrivatBank,
+			Content:           "/dry",
+		}))
+	})
+
 	t.Run("fail", func(t *testing.T) {
 		notifySvc := NewMockNotificationSvc(gomock.NewController(t))
 		printerSvc := NewMockPrinter(gomock.NewController(t))
@@ -456,6 +629,11 @@ func TestDryRun(t *testing.T) {
 		repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
 			Return([]*database.Message{}, nil)
 
+		printerSvc.EXPECT().Error(gomock.Any(), "/dry", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, err error) string {
+				return err.Error()
+			})
+
 		notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, i int64, s string) error {
 				assert.Contains(t, s, "parser for source privatbank not found")
@@ -525,6 +703,11 @@ func TestStat(t *testing.T) {
 		repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
 			Return([]*database.Message{}, nil)
 
+		printerSvc.EXPECT().Error(gomock.Any(), "/stat", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, err error) string {
+				return err.Error()
+			})
+
 		notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, i int64, s string) error {
 				assert.Contains(t, s, "parser for source privatbank not found")
@@ -594,6 +777,11 @@ func TestErrors(t *testing.T) {
 		repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
 			Return([]*database.Message{}, nil)
 
+		printerSvc.EXPECT().Error(gomock.Any(), "/errors", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, err error) string {
+				return err.Error()
+			})
+
 		notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, i int64, s string) error {
 				assert.Contains(t, s, "parser for source privatbank not found")
@@ -663,6 +851,11 @@ func TestDuplicates(t *testing.T) {
 		repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
 			Return([]*database.Message{}, nil)
 
+		printerSvc.EXPECT().Error(gomock.Any(), "/duplicates", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, err error) string {
+				return err.Error()
+			})
+
 		notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), gomock.Any()).
 			DoAndReturn(func(ctx context.Context, i int64, s string) error {
 				assert.Contains(t, s, "parser for source privatbank not found")
@@ -677,3 +870,982 @@ func TestDuplicates(t *testing.T) {
 		}))
 	})
 }
+
+func TestChatConfiguration(t *testing.T) {
+	t.Run("skip income error on commit", func(t *testing.T) {
+		repo := NewMockRepo(gomock.NewController(t))
+		parser := NewMockParser(gomock.NewController(t))
+		fireflySvc := NewMockFirefly(gomock.NewController(t))
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		dedup := NewMockDuplicateCleaner(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			Repo:             repo,
+			DuplicateCleaner: dedup,
+			NotificationSvc:  notificationSvc,
+			FireflySvc:       fireflySvc,
+			Printer:          mockPrinter,
+			Parsers: map[database.TransactionSource]processor.Parser{
+				database.Revolut: parser,
+			},
+		})
+
+		messages := []*database.Message{
+			{
+				ChatID:            1234,
+				MessageID:         4321,
+				TransactionSource: database.Revolut,
+			},
+		}
+		resultTxs := []*database.Transaction{
+			{
+				OriginalMessage: messages[0],
+				ParsingError:    errors.WithStack(common.ErrOperationNotSupported),
+			},
+		}
+
+		repo.EXPECT().GetLatestMessages(gomock.Any(), database.Revolut).
+			Return(messages, nil)
+		parser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).
+			Return(resultTxs, nil)
+		fireflySvc.EXPECT().MapTransactions(gomock.Any(), resultTxs).
+			Return([]*firefly.MappedTransaction{
+				{
+					Original: resultTxs[0],
+					Error:    resultTxs[0].ParsingError,
+				},
+			}, nil)
+
+		expectStoreParsed(t, repo)
+		repo.EXPECT().UpdateMessages(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, messages []*database.Message) error {
+				assert.Len(t, messages, 1)
+				assert.True(t, messages[0].IsProcessed)
+				return nil
+			})
+
+		mockPrinter.EXPECT().Commit(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, mappedTx []*firefly.MappedTransaction, errArr []error) string {
+				assert.Empty(t, mappedTx)
+				return "All ok"
+			})
+
+		repo.EXPECT().SaveCommitBatch(gomock.Any(), gomock.Any()).
+			Return(nil)
+
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "All ok").
+			Return(nil)
+
+		assert.NoError(t, srv.Commit(context.TODO(), processor.Message{
+			TransactionSource: database.Revolut,
+			ChatID:            111,
+			Configuration: common.ChatConfiguration{
+				Source:          database.Revolut,
+				SkipIncomeError: true,
+			},
+		}))
+	})
+
+	t.Run("skip duplicates on dry", func(t *testing.T) {
+		repo := NewMockRepo(gomock.NewController(t))
+		parser := NewMockParser(gomock.NewController(t))
+		fireflySvc := NewMockFirefly(gomock.NewController(t))
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		dedup := NewMockDuplicateCleaner(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			Repo:             repo,
+			DuplicateCleaner: dedup,
+			NotificationSvc:  notificationSvc,
+			FireflySvc:       fireflySvc,
+			Printer:          mockPrinter,
+			Parsers: map[database.TransactionSource]processor.Parser{
+				database.PrivatBank: parser,
+			},
+		})
+
+		resultTxs := []*database.Transaction{
+			{
+				DeduplicationKeys: []string{"111"},
+			},
+			{
+				DeduplicationKeys: []string{"222"},
+			},
+		}
+
+		repo.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
+			Return([]*database.Message{}, nil)
+		parser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).
+			Return(resultTxs, nil)
+		fireflySvc.EXPECT().MapTransactions(gomock.Any(), resultTxs).
+			Return([]*firefly.MappedTransaction{
+				{
+					Original: resultTxs[0],
+				},
+				{
+					Original: resultTxs[1],
+				},
+			}, nil)
+
+		dedup.EXPECT().GetDuplicates(gomock.Any(), []string{"111", "222"}, database.PrivatBank).
+			Return(map[string]struct{}{
+				"111": {},
+			}, nil)
+		dedup.EXPECT().HashKey(gomock.Any()).DoAndReturn(func(key string) string {
+			return key
+		}).Times(2)
+
+		mockPrinter.EXPECT().Dry(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, mappedTx []*firefly.MappedTransaction, errArr []error) string {
+				assert.Len(t, mappedTx, 1)
+				assert.Equal(t, resultTxs[1], mappedTx[0].Original)
+				return "dry"
+			})
+
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "dry").
+			Return(nil)
+
+		assert.NoError(t, srv.ProcessMessage(context.TODO(), processor.Message{
+			TransactionSource: database.PrivatBank,
+			ChatID:            111,
+			Content:           "/dry",
+			Configuration: common.ChatConfiguration{
+				Source:         database.PrivatBank,
+				SkipDuplicates: true,
+			},
+		}))
+	})
+}
+
+func TestUndo(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		repo := NewMockRepo(gomock.NewController(t))
+		fireflySvc := NewMockFirefly(gomock.NewController(t))
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		dedup := NewMockDuplicateCleaner(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			Repo:             repo,
+			DuplicateCleaner: dedup,
+			NotificationSvc:  notificationSvc,
+			FireflySvc:       fireflySvc,
+			Printer:          mockPrinter,
+		})
+
+		undone := &database.CommitBatch{
+			ID:       "undone",
+			UndoneAt: lo.ToPtr(time.Now()),
+		}
+		latest := &database.CommitBatch{
+			ID:                "latest",
+			TransactionSource: database.PrivatBank,
+			Transactions: []*database.CommitBatchTransaction{
+				{
+					FireflyID:     "ff-1",
+					MessageIDs:    []string{"msg-1", "msg-2"},
+					DuplicateKeys: []string{"1234"},
+				},
+				{
+					MessageIDs: []string{"msg-3"},
+				},
+			},
+		}
+
+		repo.EXPECT().GetCommitBatches(gomock.Any(), database.PrivatBank, 0).
+			Return([]*database.CommitBatch{undone, latest}, nil)
+
+		fireflySvc.EXPECT().DeleteTransaction(gomock.Any(), "ff-1").
+			Return(nil)
+
+		dedup.EXPECT().RemoveDuplicateKeys(gomock.Any(), []string{"1234"}, database.PrivatBank).
+			Return(nil)
+		dedup.EXPECT().RemoveDuplicateKeys(gomock.Any(), nil, database.PrivatBank).
+			Return(nil)
+
+		messages := []*database.Message{
+			{ID: "msg-1", ChatID: 1234, MessageID: 1, IsProcessed: true, ProcessedAt: lo.ToPtr(time.Now())},
+			{ID: "msg-2", ChatID: 1234, MessageID: 1, IsProcessed: true, ProcessedAt: lo.ToPtr(time.Now())},
+			{ID: "msg-3", ChatID: 1234, MessageID: 2, IsProcessed: true, ProcessedAt: lo.ToPtr(time.Now())},
+		}
+
+		repo.EXPECT().GetMessages(gomock.Any(), database.PrivatBank, []string{"msg-1", "msg-2", "msg-3"}).
+			Return(messages, nil)
+
+		repo.EXPECT().UpdateMessages(gomock.Any(), messages).
+			DoAndReturn(func(ctx context.Context, messages []*database.Message) error {
+				for _, msg := range messages {
+					assert.False(t, msg.IsProcessed)
+					assert.Nil(t, msg.ProcessedAt)
+				}
+				return nil
+			})
+
+		repo.EXPECT().SaveCommitBatch(gomock.Any(), latest).
+			DoAndReturn(func(ctx context.Context, batch *database.CommitBatch) error {
+				assert.NotNil(t, batch.UndoneAt)
+				return nil
+			})
+
+		notificationSvc.EXPECT().React(gomock.Any(), int64(1234), int64(1), "🤝").
+			Return(nil)
+		notificationSvc.EXPECT().React(gomock.Any(), int64(1234), int64(2), "🤝").
+			Return(nil)
+
+		mockPrinter.EXPECT().Undo(gomock.Any(), latest, nil).
+			Return("Undone")
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "Undone").
+			Return(nil)
+
+		assert.NoError(t, srv.ProcessMessage(context.TODO(), processor.Message{
+			Content:           "/undo",
+			TransactionSource: database.PrivatBank,
+			ChatID:            111,
+		}))
+	})
+
+	t.Run("nothing to undo", func(t *testing.T) {
+		repo := NewMockRepo(gomock.NewController(t))
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			Repo:            repo,
+			NotificationSvc: notificationSvc,
+			Printer:         mockPrinter,
+		})
+
+		repo.EXPECT().GetCommitBatches(gomock.Any(), database.PrivatBank, 0).
+			Return([]*database.CommitBatch{{ID: "undone", UndoneAt: lo.ToPtr(time.Now())}}, nil)
+
+		mockPrinter.EXPECT().Undo(gomock.Any(), nil, nil).
+			Return("Nothing to undo.")
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "Nothing to undo.").
+			Return(nil)
+
+		assert.NoError(t, srv.Undo(context.TODO(), processor.Message{
+			TransactionSource: database.PrivatBank,
+			ChatID:            111,
+		}))
+	})
+
+	t.Run("failed delete keeps batch open", func(t *testing.T) {
+		repo := NewMockRepo(gomock.NewController(t))
+		fireflySvc := NewMockFirefly(gomock.NewController(t))
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		dedup := NewMockDuplicateCleaner(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			Repo:             repo,
+			DuplicateCleaner: dedup,
+			NotificationSvc:  notificationSvc,
+			FireflySvc:       fireflySvc,
+			Printer:          mockPrinter,
+		})
+
+		batch := &database.CommitBatch{
+			ID:                "latest",
+			TransactionSource: database.PrivatBank,
+			Transactions: []*database.CommitBatchTransaction{
+				{
+					FireflyID:     "ff-1",
+					MessageIDs:    []string{"msg-1"},
+					DuplicateKeys: []string{"1234"},
+					Status:        database.CommitStatusCommitted,
+				},
+				{
+					FireflyID:     "ff-0",
+					MessageIDs:    []string{"msg-0"},
+					DuplicateKeys: []string{"4321"},
+					Status:        database.CommitStatusUndone, // previous /undo attempt
+				},
+			},
+		}
+
+		repo.EXPECT().GetCommitBatches(gomock.Any(), database.PrivatBank, 0).
+			Return([]*database.CommitBatch{batch}, nil)
+
+		fireflySvc.EXPECT().DeleteTransaction(gomock.Any(), "ff-1").
+			Return(errors.New("firefly is down"))
+
+		repo.EXPECT().GetMessages(gomock.Any(), database.PrivatBank, []string{}).
+			Return(nil, nil)
+		repo.EXPECT().UpdateMessages(gomock.Any(), gomock.Any()).
+			Return(nil)
+		repo.EXPECT().SaveCommitBatch(gomock.Any(), batch).
+			DoAndReturn(func(ctx context.Context, batch *database.CommitBatch) error {
+				assert.Nil(t, batch.UndoneAt)
+				assert.Equal(t, database.CommitStatusCommitted, batch.Transactions[0].Status)
+				return nil
+			})
+
+		mockPrinter.EXPECT().Undo(gomock.Any(), batch, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, batch *database.CommitBatch, errArr []error) string {
+				assert.Len(t, errArr, 1)
+				assert.Nil(t, batch.UndoneAt)
+				return "Partial"
+			})
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "Partial").
+			Return(nil)
+
+		assert.NoError(t, srv.Undo(context.TODO(), processor.Message{
+			TransactionSource: database.PrivatBank,
+			ChatID:            111,
+		}))
+	})
+}
+
+func TestHistory(t *testing.T) {
+	t.Run("default limit", func(t *testing.T) {
+		repo := NewMockRepo(gomock.NewController(t))
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			Repo:            repo,
+			NotificationSvc: notificationSvc,
+			Printer:         mockPrinter,
+		})
+
+		batches := []*database.CommitBatch{{ID: "batch"}}
+
+		repo.EXPECT().GetCommitBatches(gomock.Any(), database.PrivatBank, 5).
+			Return(batches, nil)
+		mockPrinter.EXPECT().History(gomock.Any(), batches).
+			Return("history")
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "history").
+			Return(nil)
+
+		assert.NoError(t, srv.ProcessMessage(context.TODO(), processor.Message{
+			Content:           "/history@importer_bot",
+			TransactionSource: database.PrivatBank,
+			ChatID:            111,
+		}))
+	})
+
+	t.Run("custom limit", func(t *testing.T) {
+		repo := NewMockRepo(gomock.NewController(t))
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			Repo:            repo,
+			NotificationSvc: notificationSvc,
+			Printer:         mockPrinter,
+		})
+
+		repo.EXPECT().GetCommitBatches(gomock.Any(), database.PrivatBank, 20).
+			Return(nil, nil)
+		mockPrinter.EXPECT().History(gomock.Any(), nil).
+			Return("No commits yet.")
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "No commits yet.").
+			Return(nil)
+
+		assert.NoError(t, srv.ProcessMessage(context.TODO(), processor.Message{
+			Content:           "/history 20",
+			TransactionSource: database.PrivatBank,
+			ChatID:            111,
+		}))
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+
+		srv := processor.NewProcessor(&processor.Config{
+			NotificationSvc: notificationSvc,
+			Printer:         printer.NewPrinter(),
+		})
+
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, chatID int64, text string) error {
+				assert.Contains(t, text, "invalid history limit abc")
+				return nil
+			})
+
+		assert.NoError(t, srv.ProcessMessage(context.TODO(), processor.Message{
+			Content:           "/history abc",
+			TransactionSource: database.PrivatBank,
+			ChatID:            111,
+		}))
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		notifySvc := NewMockNotificationSvc(gomock.NewController(t))
+		printerSvc := NewMockPrinter(gomock.NewController(t))
+		repoSvc := NewMockRepo(gomock.NewController(t))
+		prParser := NewMockParser(gomock.NewController(t))
+		ffSvc := NewMockFirefly(gomock.NewController(t))
+		duplicateSvc := NewMockDuplicateCleaner(gomock.NewController(t))
+
+		pr := processor.NewProcessor(&processor.Config{
+			NotificationSvc:  notifySvc,
+			Printer:          printerSvc,
+			Repo:             repoSvc,
+			FireflySvc:       ffSvc,
+			DuplicateCleaner: duplicateSvc,
+			Parsers: map[database.TransactionSource]processor.Parser{
+				database.PrivatBank: prParser,
+			},
+		})
+
+		date := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+		matched := &firefly.MappedTransaction{
+			Original: &database.Transaction{Date: date},
+			Transaction: &firefly.Transaction{
+				Type:        "withdrawal",
+				Amount:      "3.50",
+				Description: "Coffee",
+				SourceID:    "1",
+			},
+		}
+		missing := &firefly.MappedTransaction{
+			Original: &database.Transaction{Date: date},
+			Transaction: &firefly.Transaction{
+				Type:        "withdrawal",
+				Amount:      "800.00",
+				Description: "Rent",
+				SourceID:    "1",
+			},
+		}
+		unmapped := &firefly.MappedTransaction{
+			Original: &database.Transaction{Date: date},
+			Error:    errors.New("account not found"),
+		}
+		unsupported := &firefly.MappedTransaction{
+			Original: &database.Transaction{Date: date},
+			Error:    common.ErrOperationNotSupported,
+		}
+		withoutPayload := &firefly.MappedTransaction{
+			Original: &database.Transaction{Date: date},
+		}
+
+		repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
+			Return([]*database.Message{}, nil)
+		prParser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).
+			Return([]*database.Transaction{}, nil)
+		ffSvc.EXPECT().MapTransactions(gomock.Any(), gomock.Any()).
+			Return([]*firefly.MappedTransaction{matched, missing, unmapped, unsupported, withoutPayload}, nil)
+
+		ffSvc.EXPECT().ListAccountTransactions(gomock.Any(), "1",
+			time.Date(2024, 4, 26, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 5, 24, 0, 0, 0, 0, time.UTC),
+		).Return([]*firefly.TransactionGroup{
+			{
+				Id: "10",
+				Attributes: firefly.TransactionGroupAttributes{
+					Transactions: []*firefly.Transaction{
+						{
+							Type:        "withdrawal",
+							Date:        "2024-05-11T00:00:00+00:00",
+							Amount:      "3.50",
+							Description: "Coffee",
+							SourceID:    "1",
+						},
+					},
+				},
+			},
+		}, nil)
+
+		printerSvc.EXPECT().Reconcile(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, result *reconcile.Result) string {
+				if assert.Len(t, result.Matched, 1) {
+					assert.Equal(t, matched, result.Matched[0].Bank)
+					assert.Equal(t, "10", result.Matched[0].Firefly.GroupID)
+				}
+
+				assert.Equal(t, []*firefly.MappedTransaction{missing}, result.BankOnly)
+				assert.Empty(t, result.FireflyOnly)
+				assert.Equal(t, []error{unmapped.Error}, result.Errors)
+
+				return "reconciled"
+			})
+
+		notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), "reconciled").
+			Return(nil)
+
+		assert.NoError(t, pr.ProcessMessage(context.Background(), processor.Message{
+			ChatID:            1234,
+			TransactionSource: database.PrivatBank,
+			Content:           "/reconcile 1",
+		}))
+	})
+
+	t.Run("invalid tolerance", func(t *testing.T) {
+		notifySvc := NewMockNotificationSvc(gomock.NewController(t))
+
+		pr := processor.NewProcessor(&processor.Config{
+			NotificationSvc: notifySvc,
+			Printer:         printer.NewPrinter(),
+		})
+
+		notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, chatID int64, text string) error {
+				assert.Contains(t, text, "invalid date tolerance abc")
+				return nil
+			})
+
+		assert.NoError(t, pr.ProcessMessage(context.Background(), processor.Message{
+			ChatID:            1234,
+			TransactionSource: database.PrivatBank,
+			Content:           "/reconcile abc",
+		}))
+	})
+}
+
+func TestProbableDuplicates(t *testing.T) {
+	notifySvc := NewMockNotificationSvc(gomock.NewController(t))
+	printerSvc := NewMockPrinter(gomock.NewController(t))
+	repoSvc := NewMockRepo(gomock.NewController(t))
+	prParser := NewMockParser(gomock.NewController(t))
+	ffSvc := NewMockFirefly(gomock.NewController(t))
+
+	pr := processor.NewProcessor(&processor.Config{
+		NotificationSvc: notifySvc,
+		Printer:         printerSvc,
+		Repo:            repoSvc,
+		FireflySvc:      ffSvc,
+		Parsers: map[database.TransactionSource]processor.Parser{
+			database.PrivatBank: prParser,
+		},
+		DuplicateWindow: 2 * 24 * time.Hour,
+	})
+
+	date := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	existing := &firefly.MappedTransaction{
+		Original: &database.Transaction{Date: date},
+		Transaction: &firefly.Transaction{
+			Type:        "withdrawal",
+			Amount:      "3.50",
+			Description: "Coffee",
+			SourceID:    "1",
+		},
+	}
+	fresh := &firefly.MappedTransaction{
+		Original: &database.Transaction{Date: date},
+		Transaction: &firefly.Transaction{
+			Type:        "withdrawal",
+			Amount:      "800.00",
+			Description: "Rent",
+			SourceID:    "1",
+		},
+	}
+
+	repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.PrivatBank).
+		Return([]*database.Message{}, nil)
+	prParser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).
+		Return([]*database.Transaction{}, nil)
+	ffSvc.EXPECT().MapTransactions(gomock.Any(), gomock.Any()).
+		Return([]*firefly.MappedTransaction{existing, fresh}, nil)
+
+	ffSvc.EXPECT().ListAccountTransactions(gomock.Any(), "1",
+		time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC),
+	).Return([]*firefly.TransactionGroup{
+		{
+			Id: "10",
+			Attributes: firefly.TransactionGroupAttributes{
+				Transactions: []*firefly.Transaction{
+					{
+						Type:        "withdrawal",
+						Date:        "2024-05-09T00:00:00+00:00",
+						Amount:      "3.50",
+						Description: "Manual coffee",
+						SourceID:    "1",
+					},
+				},
+			},
+		},
+	}, nil)
+
+	printerSvc.EXPECT().Duplicates(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, mappedTx []*firefly.MappedTransaction, errArr []error) string {
+			assert.ErrorIs(t, existing.Error, common.ErrProbableDuplicate)
+			if assert.NotNil(t, existing.ProbableDuplicate) {
+				assert.Equal(t, "10", existing.ProbableDuplicate.FireflyID)
+			}
+
+			assert.NoError(t, fresh.Error)
+			assert.Nil(t, fresh.ProbableDuplicate)
+
+			return "duplicates"
+		})
+
+	notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), "duplicates").
+		Return(nil)
+
+	assert.NoError(t, pr.ProcessMessage(context.Background(), processor.Message{
+		ChatID:            1234,
+		TransactionSource: database.PrivatBank,
+		Content:           "/duplicates",
+	}))
+}
+
+func TestMap(t *testing.T) {
+	accounts := []*firefly.Account{
+		{Id: "1", Attributes: firefly.AccountAttributes{Name: "Privat card"}},
+		{Id: "2", Attributes: firefly.AccountAttributes{Name: "Revolut EUR"}},
+	}
+	mappings := []*database.AccountMapping{{Pattern: "revolut_*", FireflyAccountID: "2"}}
+
+	newEnv := func(t *testing.T) (*processor.Processor, *MockAccountMapper, *MockNotificationSvc) {
+		notificationSvc := NewMockNotificationSvc(gomock.NewController(t))
+		mockPrinter := NewMockPrinter(gomock.NewController(t))
+		ffSvc := NewMockFirefly(gomock.NewController(t))
+		mapper := NewMockAccountMapper(gomock.NewController(t))
+
+		ffSvc.EXPECT().ListAccounts(gomock.Any()).Return(accounts, nil).AnyTimes()
+		mapper.EXPECT().Mappings(gomock.Any()).Return(mappings, nil).AnyTimes()
+		mockPrinter.EXPECT().AccountMappings(gomock.Any(), mappings, accounts).
+			Return("mappings").AnyTimes()
+		mockPrinter.EXPECT().Error(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, err error) string {
+				return err.Error()
+			}).AnyTimes()
+
+		return processor.NewProcessor(&processor.Config{
+			NotificationSvc: notificationSvc,
+			Printer:         mockPrinter,
+			FireflySvc:      ffSvc,
+			AccountMapper:   mapper,
+		}), mapper, notificationSvc
+	}
+
+	send := func(srv *processor.Processor, content string) {
+		assert.NoError(t, srv.ProcessMessage(context.TODO(), processor.Message{
+			Content:           content,
+			TransactionSource: database.Revolut,
+			ChatID:            111,
+		}))
+	}
+
+	t.Run("list", func(t *testing.T) {
+		srv, _, notificationSvc := newEnv(t)
+
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "mappings").Return(nil)
+
+		send(srv, "/map")
+	})
+
+	t.Run("set by name", func(t *testing.T) {
+		srv, mapper, notificationSvc := newEnv(t)
+
+		mapper.EXPECT().Set(gomock.Any(), "revolut_EUR", "2").Return(nil)
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "mappings").Return(nil)
+
+		send(srv, "/map revolut_EUR revolut eur")
+	})
+
+	t.Run("set by id", func(t *testing.T) {
+		srv, mapper, notificationSvc := newEnv(t)
+
+		mapper.EXPECT().Set(gomock.Any(), "5168**1234", "1").Return(nil)
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "mappings").Return(nil)
+
+		send(srv, "/map 5168**1234 1")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		srv, mapper, notificationSvc := newEnv(t)
+
+		mapper.EXPECT().Delete(gomock.Any(), "revolut_*").Return(nil)
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), "mappings").Return(nil)
+
+		send(srv, "/map revolut_* -")
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		srv, _, notificationSvc := newEnv(t)
+
+		notificationSvc.EXPECT().SendMessage(gomock.Any(), int64(111), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, chatID int64, text string) error {
+				assert.Contains(t, text, "firefly account Mono not found")
+
+				return nil
+			})
+
+		send(srv, "/map mono_UAH Mono")
+	})
+}
+
+func TestStoreMessage(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		repoSvc := NewMockRepo(gomock.NewController(t))
+		prParser := NewMockParser(gomock.NewController(t))
+
+		pr := processor.NewProcessor(&processor.Config{
+			Repo: repoSvc,
+			Parsers: map[database.TransactionSource]processor.Parser{
+				database.Revolut: prParser,
+			},
+		})
+
+		prParser.EXPECT().SplitExcel(gomock.Any(), []byte("file-content")).
+			Return([][]byte{[]byte("a"), []byte("b")}, nil)
+
+		repoSvc.EXPECT().AddMessage(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, messages []database.Message) error {
+				assert.Len(t, messages, 2)
+				assert.Equal(t, "statement.csv", messages[0].FileID)
+				assert.EqualValues(t, 0, messages[0].ChatID)
+
+				return nil
+			})
+
+		assert.True(t, pr.SupportsSource(database.Revolut))
+
+		count, err := pr.StoreMessage(context.Background(), processor.Message{
+			TransactionSource: database.Revolut,
+			FileID:            "statement.csv",
+		}, []byte("file-content"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		pr := processor.NewProcessor(&processor.Config{
+			Parsers: map[database.TransactionSource]processor.Parser{},
+		})
+
+		assert.False(t, pr.SupportsSource(database.Revolut))
+
+		_, err := pr.StoreMessage(context.Background(), processor.Message{
+			TransactionSource: database.Revolut,
+		}, []byte("file-content"))
+		assert.ErrorContains(t, err, "parser for source revolut not found")
+	})
+}
+
+func TestPendingTransactions(t *testing.T) {
+	repoSvc := NewMockRepo(gomock.NewController(t))
+	prParser := NewMockParser(gomock.NewController(t))
+	ffSvc := NewMockFirefly(gomock.NewController(t))
+
+	pr := processor.NewProcessor(&processor.Config{
+		Repo:       repoSvc,
+		FireflySvc: ffSvc,
+		Parsers: map[database.TransactionSource]processor.Parser{
+			database.Revolut: prParser,
+		},
+	})
+
+	supported := &firefly.MappedTransaction{Original: &database.Transaction{}}
+	income := &firefly.MappedTransaction{Original: &database.Transaction{}, Error: common.ErrOperationNotSupported}
+
+	repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.Revolut).
+		Return([]*database.Message{}, nil)
+	prParser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).
+		Return([]*database.Transaction{}, nil)
+	ffSvc.EXPECT().MapTransactions(gomock.Any(), gomock.Any()).
+		Return([]*firefly.MappedTransaction{supported, income}, nil)
+	transactions, errArr, err := pr.PendingTransactions(context.Background(), processor.Message{
+		TransactionSource: database.Revolut,
+		Configuration:     common.ChatConfiguration{SkipIncomeError: true},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, errArr)
+	assert.Equal(t, []*firefly.MappedTransaction{supported}, transactions)
+}
+
+func TestPermissions(t *testing.T) {
+	cfg := common.ChatConfiguration{
+		Source: database.PrivatBank,
+		Users: map[string]common.Role{
+			"1":              common.RoleViewer,
+			"2":              common.RoleImporter,
+			"@a:example.org": common.RoleAdmin,
+		},
+	}
+
+	newEnv := func(t *testing.T) (*processor.Processor, *MockNotificationSvc, *MockRepo) {
+		notifySvc := NewMockNotificationSvc(gomock.NewController(t))
+		repoSvc := NewMockRepo(gomock.NewController(t))
+
+		return processor.NewProcessor(&processor.Config{
+			NotificationSvc: notifySvc,
+			Printer:         printer.NewPrinter(),
+			Repo:            repoSvc,
+			Parsers:         map[database.TransactionSource]processor.Parser{},
+		}), notifySvc, repoSvc
+	}
+
+	send := func(t *testing.T, pr *processor.Processor, userID string, content string) {
+		assert.NoError(t, pr.ProcessMessage(context.Background(), processor.Message{
+			ChatID:            1234,
+			UserID:            userID,
+			TransactionSource: database.PrivatBank,
+			Content:           content,
+			Configuration:     cfg,
+		}))
+	}
+
+	denied := []struct {
+		userID  string
+		content string
+		reason  string
+	}{
+		{"1", "/commit", "permission denied: /commit requires the importer role"},
+		{"1", "some notification", "permission denied: uploading statements requires the importer role"},
+		{"2", "/clear", "permission denied: /clear requires the admin role"},
+		{"2", "/undo", "permission denied: /undo requires the admin role"},
+		{"2", "/map revolut_* 2", "permission denied: /map requires the admin role"},
+		{"4", "/stat", "permission denied: /stat requires the viewer role"},
+		{"", "/stat", "permission denied: /stat requires the viewer role"},
+	}
+
+	for _, tc := range denied {
+		t.Run(fmt.Sprintf("%s %s", tc.userID, tc.content), func(t *testing.T) {
+			pr, notifySvc, _ := newEnv(t)
+
+			notifySvc.EXPECT().SendMessage(gomock.Any(), int64(1234), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, chatID int64, text string) error {
+					assert.Contains(t, text, tc.reason)
+
+					return nil
+				})
+
+			send(t, pr, tc.userID, tc.content)
+		})
+	}
+
+	t.Run("admin", func(t *testing.T) {
+		pr, _, repoSvc := newEnv(t)
+
+		repoSvc.EXPECT().Clear(gomock.Any(), database.PrivatBank).Return(nil)
+
+		send(t, pr, "@a:example.org", "/clear")
+	})
+
+	t.Run("chat without users", func(t *testing.T) {
+		pr, _, repoSvc := newEnv(t)
+
+		repoSvc.EXPECT().Clear(gomock.Any(), database.PrivatBank).Return(nil)
+
+		assert.NoError(t, pr.ProcessMessage(context.Background(), processor.Message{
+			ChatID:            1234,
+			UserID:            "4",
+			TransactionSource: database.PrivatBank,
+			Content:           "/clear",
+			Configuration:     common.ChatConfiguration{Source: database.PrivatBank},
+		}))
+	})
+}
+
+// expectStoreParsed expects the parse results to be stored with the pending messages, before they are committed.
+func expectStoreParsed(t *testing.T, repo *MockRepo) {
+	repo.EXPECT().UpdateParsed(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ database.TransactionSource, parsed map[string]*database.ParsedMessage) error {
+			assert.NotEmpty(t, parsed)
+
+			for _, result := range parsed {
+				assert.NotNil(t, result)
+			}
+
+			return nil
+		})
+}
+
+func TestStoredParse(t *testing.T) {
+	repoSvc := NewMockRepo(gomock.NewController(t))
+	prParser := NewMockParser(gomock.NewController(t))
+	ffSvc := NewMockFirefly(gomock.NewController(t))
+	dedup := NewMockDuplicateCleaner(gomock.NewController(t))
+
+	pr := processor.NewProcessor(&processor.Config{
+		Repo:             repoSvc,
+		FireflySvc:       ffSvc,
+		DuplicateCleaner: dedup,
+		Parsers: map[database.TransactionSource]processor.Parser{
+			database.Revolut: prParser,
+		},
+	})
+
+	messages := []*database.Message{
+		{ID: "msg-1", TransactionSource: database.Revolut},
+		{ID: "msg-2", TransactionSource: database.Revolut},
+	}
+
+	var stored []*database.Message // as a storage backend returns them
+
+	repoSvc.EXPECT().UpdateParsed(gomock.Any(), database.Revolut, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ database.TransactionSource, parsed map[string]*database.ParsedMessage) error {
+			var updated []*database.Message
+
+			for _, msg := range messages {
+				msgCopy := *msg
+				msgCopy.Parsed = parsed[msg.ID]
+				updated = append(updated, &msgCopy)
+			}
+
+			data, err := json.Marshal(updated)
+			assert.NoError(t, err)
+
+			stored = nil
+			assert.NoError(t, json.Unmarshal(data, &stored))
+
+			return nil
+		}).AnyTimes()
+	dedup.EXPECT().GetDuplicates(gomock.Any(), gomock.Any(), database.Revolut).
+		Return(map[string]struct{}{"dup": {}}, nil).AnyTimes()
+	dedup.EXPECT().HashKey(gomock.Any()).DoAndReturn(func(key string) string {
+		return key
+	}).AnyTimes()
+
+	merged := &database.Transaction{OriginalMessage: messages[1], Description: "merged"}
+	parsed := []*database.Transaction{
+		{OriginalMessage: messages[1], Description: "second", DuplicateTransactions: []*database.Transaction{merged}},
+		{OriginalMessage: messages[0], Description: "first"},
+		{OriginalMessage: messages[0], Description: "duplicate", DeduplicationKeys: []string{"dup"}},
+	}
+
+	prParser.EXPECT().ParseMessages(gomock.Any(), gomock.Any()).Return(parsed, nil).Times(2)
+	ffSvc.EXPECT().MapTransactions(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, transactions []*database.Transaction) ([]*firefly.MappedTransaction, error) {
+			return []*firefly.MappedTransaction{
+				{Original: transactions[0], Transaction: &firefly.Transaction{Description: "second"}},
+				{Original: transactions[1], Error: errors.Mark(errors.New("income"), common.ErrOperationNotSupported)},
+				{Original: transactions[2], Transaction: &firefly.Transaction{Description: "duplicate"}},
+			}, nil
+		}).Times(2)
+
+	repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.Revolut).Return(messages, nil)
+
+	message := processor.Message{TransactionSource: database.Revolut}
+
+	fresh, _, err := pr.PendingTransactions(context.Background(), message)
+	assert.NoError(t, err)
+
+	repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.Revolut).
+		DoAndReturn(func(context.Context, database.TransactionSource) ([]*database.Message, error) {
+			return stored, nil
+		})
+
+	restored, _, err := pr.PendingTransactions(context.Background(), message)
+	assert.NoError(t, err)
+
+	if assert.Len(t, restored, 3) {
+		for i, tx := range restored {
+			assert.Equal(t, fresh[i].Original.Description, tx.Original.Description)
+			assert.Equal(t, fresh[i].Original.OriginalMessage.ID, tx.Original.OriginalMessage.ID)
+			assert.Equal(t, fresh[i].DecisionKey, tx.DecisionKey)
+			assert.Equal(t, fresh[i].Transaction, tx.Transaction)
+		}
+
+		assert.Equal(t, "msg-2", restored[0].Original.DuplicateTransactions[0].OriginalMessage.ID)
+		assert.ErrorIs(t, restored[1].Error, common.ErrOperationNotSupported)
+		assert.ErrorIs(t, restored[2].Error, common.ErrDuplicate)
+	}
+
+	// a new message was uploaded
+	repoSvc.EXPECT().GetLatestMessages(gomock.Any(), database.Revolut).
+		DoAndReturn(func(context.Context, database.TransactionSource) ([]*database.Message, error) {
+			return append(stored, &database.Message{ID: "msg-3", TransactionSource: database.Revolut}), nil
+		})
+
+	_, _, err = pr.PendingTransactions(context.Background(), message)
+	assert.NoError(t, err)
+}
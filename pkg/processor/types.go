@@ -3,6 +3,7 @@ package processor
 import (
 	"time"
 
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
 	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
 )
@@ -15,8 +16,10 @@ type Message struct {
 	Content           string
 	ForwardedFrom     string
 	MessageID         int64
+	UserID            string // sender id of the transport, checked against the chat users
 	TransactionSource database.TransactionSource
 	FileID            string
+	Configuration     common.ChatConfiguration
 }
 
 type CommitResult struct {
@@ -24,3 +27,14 @@ type CommitResult struct {
 	Msg              *database.Message
 	Tx               *firefly.MappedTransaction
 }
+
+// Callback is a press of an inline button.
+type Callback struct {
+	ID                string
+	ChatID            int64
+	MessageID         int64
+	UserID            string
+	Data              string
+	TransactionSource database.TransactionSource
+	Configuration     common.ChatConfiguration
+}
@@ -0,0 +1,89 @@
+package reconcile
+
+import (
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+)
+
+// FindDuplicates flags bank transactions which have a firefly row on the same account with the same amount and
+// currency within window. Confidence is the average of date closeness and description similarity, every row
+// is assigned to at most one transaction, the most confident pairs win.
+func FindDuplicates(
+	bank []*firefly.MappedTransaction,
+	rows []*Row,
+	window time.Duration,
+) {
+	type candidate struct {
+		tx         *firefly.MappedTransaction
+		row        *Row
+		confidence float64
+	}
+
+	var candidates []*candidate
+
+	for _, tx := range bank {
+		accounts := ownAccounts(tx.Transaction)
+
+		for _, row := range rows {
+			if !row.Amount.Equal(amountOf(tx)) || !sameDirection(tx.Transaction, row.Transaction, accounts) {
+				continue
+			}
+
+			if tx.Transaction.CurrencyCode != "" && row.Transaction.CurrencyCode != "" &&
+				tx.Transaction.CurrencyCode != row.Transaction.CurrencyCode {
+				continue
+			}
+
+			diff := truncateDay(tx.Original.Date).Sub(truncateDay(row.Date)).Abs()
+			if diff > window {
+				continue
+			}
+
+			closeness := 1 - float64(diff)/float64(window+24*time.Hour)
+			similarity := Similarity(tx.Transaction.Description, row.Transaction.Description)
+
+			candidates = append(candidates, &candidate{
+				tx:         tx,
+				row:        row,
+				confidence: (closeness + similarity) / 2,
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].confidence > candidates[j].confidence
+	})
+
+	usedRows := map[*Row]struct{}{}
+
+	for _, c := range candidates {
+		if _, ok := usedRows[c.row]; ok || c.tx.ProbableDuplicate != nil {
+			continue
+		}
+
+		usedRows[c.row] = struct{}{}
+		c.tx.ProbableDuplicate = &firefly.ProbableDuplicate{
+			FireflyID:   c.row.GroupID,
+			Date:        c.row.Date,
+			Amount:      c.row.Amount.StringFixed(2),
+			Description: c.row.Transaction.Description,
+			Confidence:  c.confidence,
+		}
+	}
+}
+
+// sameDirection reports whether the row moves money the same way on one of the accounts.
+func sameDirection(tx *firefly.Transaction, row *firefly.Transaction, accounts []string) bool {
+	switch tx.Type {
+	case "withdrawal":
+		return lo.Contains(accounts, row.SourceID)
+	case "deposit":
+		return lo.Contains(accounts, row.DestinationID)
+	default:
+		return lo.Contains(accounts, row.SourceID) || lo.Contains(accounts, row.DestinationID)
+	}
+}
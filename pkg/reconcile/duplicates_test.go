@@ -0,0 +1,48 @@
+package reconcile_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	coffee := bankTx("2024-05-01", "3.50", "Coffee Corner")
+	secondCoffee := bankTx("2024-05-01", "3.50", "Coffee Corner")
+	market := bankTx("2024-05-03", "42.10", "Super Market 12")
+	refund := bankTx("2024-05-04", "15.00", "Bolt refund")
+	refund.Transaction.Type = "deposit"
+	refund.Transaction.SourceID = ""
+	refund.Transaction.DestinationID = "1"
+	foreign := bankTx("2024-05-05", "10.00", "Museum")
+	foreign.Transaction.CurrencyCode = "USD"
+
+	groups := []*firefly.TransactionGroup{
+		fireflyGroup("10", "2024-05-02", "3.50", "coffee corner"),
+		fireflyGroup("11", "2024-05-09", "42.10", "Super Market"), // outside of the window
+		fireflyGroup("12", "2024-05-04", "15.00", "Bolt refund"),  // withdrawal, refund is a deposit
+		fireflyGroup("13", "2024-05-05", "10.00", "Museum"),       // EUR
+	}
+
+	reconcile.FindDuplicates(
+		[]*firefly.MappedTransaction{coffee, secondCoffee, market, refund, foreign},
+		reconcile.NewRows(groups),
+		3*24*time.Hour,
+	)
+
+	if assert.NotNil(t, coffee.ProbableDuplicate) {
+		assert.Equal(t, "10", coffee.ProbableDuplicate.FireflyID)
+		assert.Equal(t, "3.50", coffee.ProbableDuplicate.Amount)
+		assert.Equal(t, "coffee corner", coffee.ProbableDuplicate.Description)
+		assert.InDelta(t, 0.875, coffee.ProbableDuplicate.Confidence, 0.001)
+	}
+
+	assert.Nil(t, secondCoffee.ProbableDuplicate) // firefly has only one coffee
+	assert.Nil(t, market.ProbableDuplicate)
+	assert.Nil(t, refund.ProbableDuplicate)
+	assert.Nil(t, foreign.ProbableDuplicate)
+}
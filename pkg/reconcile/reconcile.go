@@ -0,0 +1,312 @@
+package reconcile
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+)
+
+const (
+	DefaultDateTolerance  = 3 * 24 * time.Hour
+	DefaultMismatchWindow = 14 * 24 * time.Hour
+	DefaultMinSimilarity  = 0.5
+)
+
+type Options struct {
+	// DateTolerance is the max difference in days of a matched row.
+	DateTolerance time.Duration
+	// MismatchWindow is the max date difference of a row reported as a date mismatch.
+	MismatchWindow time.Duration
+	// MinSimilarity of descriptions, 0..1, required to report a mismatch.
+	MinSimilarity float64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		DateTolerance:  DefaultDateTolerance,
+		MismatchWindow: DefaultMismatchWindow,
+		MinSimilarity:  DefaultMinSimilarity,
+	}
+}
+
+// Row is a single split of a firefly transaction group.
+type Row struct {
+	GroupID     string
+	Transaction *firefly.Transaction
+	Date        time.Time
+	Amount      decimal.Decimal
+}
+
+type Match struct {
+	Bank          *firefly.MappedTransaction
+	Firefly       *Row
+	Similarity    float64
+	AmountDiffers bool
+	DateDiffers   bool
+}
+
+type Result struct {
+	Start       time.Time
+	End         time.Time
+	Matched     []*Match
+	Mismatches  []*Match
+	BankOnly    []*firefly.MappedTransaction
+	FireflyOnly []*Row
+	// Errors of bank transactions which could not be reconciled.
+	Errors []error
+}
+
+// NewRows flattens firefly transaction groups, splits listed by several accounts are kept once.
+func NewRows(groups []*firefly.TransactionGroup) []*Row {
+	var rows []*Row
+	seen := map[string]struct{}{}
+
+	for _, group := range groups {
+		for idx, split := range group.Attributes.Transactions {
+			key := group.Id + "_" + lo.Ternary(split.JournalID != "", split.JournalID, strconv.Itoa(idx))
+			if _, ok := seen[key]; ok {
+				continue
+			}
+
+			seen[key] = struct{}{}
+
+			date, err := time.Parse(time.RFC3339, split.Date)
+			if err != nil {
+				continue
+			}
+
+			amount, err := decimal.NewFromString(split.Amount)
+			if err != nil {
+				continue
+			}
+
+			rows = append(rows, &Row{
+				GroupID:     group.Id,
+				Transaction: split,
+				Date:        date,
+				Amount:      amount.Abs(),
+			})
+		}
+	}
+
+	return rows
+}
+
+// Period returns the first and the last day of bank transactions.
+func Period(bank []*firefly.MappedTransaction) (time.Time, time.Time) {
+	var start, end time.Time
+
+	for _, tx := range bank {
+		date := tx.Original.Date
+		if start.IsZero() || date.Before(start) {
+			start = date
+		}
+
+		if end.IsZero() || date.After(end) {
+			end = date
+		}
+	}
+
+	return truncateDay(start), truncateDay(end)
+}
+
+// Accounts returns firefly account ids of bank transactions.
+func Accounts(bank []*firefly.MappedTransaction) []string {
+	var accounts []string
+
+	for _, tx := range bank {
+		accounts = append(accounts, ownAccounts(tx.Transaction)...)
+	}
+
+	return lo.Uniq(accounts)
+}
+
+// Reconcile pairs bank transactions with firefly rows. Rows with the same amount within DateTolerance are matched,
+// then rows with a similar description where only the amount or only the date differs are reported as mismatches.
+func Reconcile(
+	bank []*firefly.MappedTransaction,
+	rows []*Row,
+	opts Options,
+) *Result {
+	start, end := Period(bank)
+
+	result := &Result{
+		Start: start,
+		End:   end,
+	}
+
+	used := map[*Row]struct{}{}
+	var unmatched []*firefly.MappedTransaction
+
+	for _, tx := range bank {
+		best := findBest(tx, rows, used, func(row *Row, diff time.Duration, _ float64) bool {
+			return row.Amount.Equal(amountOf(tx)) && diff <= opts.DateTolerance
+		})
+		if best == nil {
+			unmatched = append(unmatched, tx)
+			continue
+		}
+
+		used[best.Firefly] = struct{}{}
+		result.Matched = append(result.Matched, best)
+	}
+
+	for _, tx := range unmatched {
+		best := findBest(tx, rows, used, func(row *Row, diff time.Duration, similarity float64) bool {
+			if similarity < opts.MinSimilarity {
+				return false
+			}
+
+			sameAmount := row.Amount.Equal(amountOf(tx))
+
+			return (sameAmount && diff <= opts.MismatchWindow) || (!sameAmount && diff <= opts.DateTolerance)
+		})
+		if best == nil {
+			result.BankOnly = append(result.BankOnly, tx)
+			continue
+		}
+
+		best.AmountDiffers = !best.Firefly.Amount.Equal(amountOf(tx))
+		best.DateDiffers = !best.AmountDiffers
+
+		used[best.Firefly] = struct{}{}
+		result.Mismatches = append(result.Mismatches, best)
+	}
+
+	for _, row := range rows {
+		if _, ok := used[row]; ok {
+			continue
+		}
+
+		if day := truncateDay(row.Date); day.Before(start) || day.After(end) { // fetched only to find mismatches
+			continue
+		}
+
+		result.FireflyOnly = append(result.FireflyOnly, row)
+	}
+
+	sort.SliceStable(result.FireflyOnly, func(i, j int) bool {
+		return result.FireflyOnly[i].Date.Before(result.FireflyOnly[j].Date)
+	})
+
+	return result
+}
+
+func findBest(
+	tx *firefly.MappedTransaction,
+	rows []*Row,
+	used map[*Row]struct{},
+	accept func(row *Row, diff time.Duration, similarity float64) bool,
+) *Match {
+	var best *Match
+	var bestDiff time.Duration
+
+	accounts := ownAccounts(tx.Transaction)
+
+	for _, row := range rows {
+		if _, ok := used[row]; ok {
+			continue
+		}
+
+		if !lo.Contains(accounts, row.Transaction.SourceID) && !lo.Contains(accounts, row.Transaction.DestinationID) {
+			continue
+		}
+
+		if tx.Transaction.CurrencyCode != "" && row.Transaction.CurrencyCode != "" &&
+			tx.Transaction.CurrencyCode != row.Transaction.CurrencyCode {
+			continue
+		}
+
+		diff := truncateDay(tx.Original.Date).Sub(truncateDay(row.Date)).Abs()
+		similarity := Similarity(tx.Transaction.Description, row.Transaction.Description)
+
+		if !accept(row, diff, similarity) {
+			continue
+		}
+
+		if best == nil || similarity > best.Similarity || (similarity == best.Similarity && diff < bestDiff) {
+			best = &Match{
+				Bank:       tx,
+				Firefly:    row,
+				Similarity: similarity,
+			}
+			bestDiff = diff
+		}
+	}
+
+	return best
+}
+
+// Similarity is the dice coefficient of character bigrams, punctuation and case are ignored.
+func Similarity(a string, b string) float64 {
+	left, right := bigrams(a), bigrams(b)
+
+	if len(left) == 0 || len(right) == 0 {
+		return lo.Ternary(normalize(a) == normalize(b), 1.0, 0.0)
+	}
+
+	counts := map[string]int{}
+	for _, bigram := range left {
+		counts[bigram] += 1
+	}
+
+	intersection := 0
+	for _, bigram := range right {
+		if counts[bigram] > 0 {
+			counts[bigram] -= 1
+			intersection += 1
+		}
+	}
+
+	return float64(2*intersection) / float64(len(left)+len(right))
+}
+
+func bigrams(input string) []string {
+	runes := []rune(normalize(input))
+
+	var result []string
+	for i := 0; i+1 < len(runes); i++ {
+		result = append(result, string(runes[i:i+2]))
+	}
+
+	return result
+}
+
+func normalize(input string) string {
+	fields := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+func ownAccounts(tx *firefly.Transaction) []string {
+	switch tx.Type {
+	case "withdrawal":
+		return []string{tx.SourceID}
+	case "deposit":
+		return []string{tx.DestinationID}
+	default:
+		return lo.Compact([]string{tx.SourceID, tx.DestinationID})
+	}
+}
+
+func amountOf(tx *firefly.MappedTransaction) decimal.Decimal {
+	amount, err := decimal.NewFromString(tx.Transaction.Amount)
+	if err != nil {
+		return decimal.Zero
+	}
+
+	return amount.Abs()
+}
+
+func truncateDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
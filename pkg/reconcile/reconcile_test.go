@@ -0,0 +1,143 @@
+package reconcile_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/reconcile"
+)
+
+func bankTx(date string, amount string, description string) *firefly.MappedTransaction {
+	parsed, _ := time.Parse(time.DateOnly, date)
+
+	return &firefly.MappedTransaction{
+		Original: &database.Transaction{
+			Date:        parsed,
+			Description: description,
+		},
+		Transaction: &firefly.Transaction{
+			Type:         "withdrawal",
+			Date:         parsed.Format(time.RFC3339),
+			Amount:       amount,
+			Description:  description,
+			SourceID:     "1",
+			CurrencyCode: "EUR",
+		},
+	}
+}
+
+func fireflyGroup(id string, date string, amount string, description string) *firefly.TransactionGroup {
+	return &firefly.TransactionGroup{
+		Id: id,
+		Attributes: firefly.TransactionGroupAttributes{
+			Transactions: []*firefly.Transaction{
+				{
+					JournalID:     id,
+					Type:          "withdrawal",
+					Date:          date + "T00:00:00+00:00",
+					Amount:        amount,
+					Description:   description,
+					SourceID:      "1",
+					DestinationID: "100",
+					CurrencyCode:  "EUR",
+				},
+			},
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	coffee := bankTx("2024-05-01", "3.50", "Coffee Corner")
+	rent := bankTx("2024-05-02", "800.00", "Rent May")
+	market := bankTx("2024-05-03", "42.10", "Super Market 12")
+	taxi := bankTx("2024-05-04", "15.00", "Bolt taxi")
+	gym := bankTx("2024-05-10", "30.00", "Gym membership")
+
+	groups := []*firefly.TransactionGroup{
+		fireflyGroup("10", "2024-05-02", "3.50", "COFFEE CORNER"),      // matched, date within tolerance
+		fireflyGroup("11", "2024-05-03", "42.00", "Super Market"),      // amount mismatch
+		fireflyGroup("12", "2024-05-12", "15.00", "Bolt taxi"),         // date mismatch
+		fireflyGroup("13", "2024-05-05", "99.00", "Manual cash entry"), // firefly only
+		fireflyGroup("14", "2024-04-01", "99.00", "Out of period"),     // fetched for mismatches only
+		fireflyGroup("10", "2024-05-02", "3.50", "COFFEE CORNER"),      // listed by another account
+		fireflyGroup("15", "2024-05-10", "30.00", "Gym membership"),    // matched
+	}
+
+	result := reconcile.Reconcile(
+		[]*firefly.MappedTransaction{coffee, rent, market, taxi, gym},
+		reconcile.NewRows(groups),
+		reconcile.DefaultOptions(),
+	)
+
+	assert.Equal(t, "2024-05-01", result.Start.Format(time.DateOnly))
+	assert.Equal(t, "2024-05-10", result.End.Format(time.DateOnly))
+
+	if assert.Len(t, result.Matched, 2) {
+		assert.Equal(t, coffee, result.Matched[0].Bank)
+		assert.Equal(t, "10", result.Matched[0].Firefly.GroupID)
+		assert.Equal(t, gym, result.Matched[1].Bank)
+		assert.Equal(t, "15", result.Matched[1].Firefly.GroupID)
+	}
+
+	if assert.Len(t, result.Mismatches, 2) {
+		assert.Equal(t, market, result.Mismatches[0].Bank)
+		assert.Equal(t, "11", result.Mismatches[0].Firefly.GroupID)
+		assert.True(t, result.Mismatches[0].AmountDiffers)
+		assert.False(t, result.Mismatches[0].DateDiffers)
+
+		assert.Equal(t, taxi, result.Mismatches[1].Bank)
+		assert.Equal(t, "12", result.Mismatches[1].Firefly.GroupID)
+		assert.True(t, result.Mismatches[1].DateDiffers)
+	}
+
+	assert.Equal(t, []*firefly.MappedTransaction{rent}, result.BankOnly)
+
+	if assert.Len(t, result.FireflyOnly, 1) {
+		assert.Equal(t, "13", result.FireflyOnly[0].GroupID)
+	}
+}
+
+func TestReconcileOtherAccount(t *testing.T) {
+	coffee := bankTx("2024-05-01", "3.50", "Coffee")
+
+	group := fireflyGroup("10", "2024-05-01", "3.50", "Coffee")
+	group.Attributes.Transactions[0].SourceID = "2"
+
+	result := reconcile.Reconcile(
+		[]*firefly.MappedTransaction{coffee},
+		reconcile.NewRows([]*firefly.TransactionGroup{group}),
+		reconcile.DefaultOptions(),
+	)
+
+	assert.Empty(t, result.Matched)
+	assert.Equal(t, []*firefly.MappedTransaction{coffee}, result.BankOnly)
+	assert.Len(t, result.FireflyOnly, 1)
+}
+
+func TestAccounts(t *testing.T) {
+	deposit := bankTx("2024-05-01", "10", "Salary")
+	deposit.Transaction.Type = "deposit"
+	deposit.Transaction.SourceID = ""
+	deposit.Transaction.DestinationID = "2"
+
+	transfer := bankTx("2024-05-01", "10", "Own transfer")
+	transfer.Transaction.Type = "transfer"
+	transfer.Transaction.DestinationID = "3"
+
+	assert.Equal(t, []string{"1", "2", "3"}, reconcile.Accounts([]*firefly.MappedTransaction{
+		bankTx("2024-05-01", "10", "Coffee"),
+		deposit,
+		transfer,
+	}))
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, reconcile.Similarity("Coffee Corner", "COFFEE, corner!"))
+	assert.Equal(t, 0.0, reconcile.Similarity("abc", "xyz"))
+	assert.Equal(t, 1.0, reconcile.Similarity("", ""))
+	assert.Greater(t, reconcile.Similarity("Super Market 12", "Super Market"), 0.8)
+}
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"time"
 
@@ -15,9 +16,12 @@ import (
 )
 
 const (
-	messagesContainer  = "messages"
-	duplicateContainer = "duplicates"
-	defaultPoolSize    = 10
+	messagesContainer    = "messages"
+	duplicateContainer   = "duplicates"
+	commitBatchContainer = "commitBatches"
+	accountMapContainer  = "accountMappings"
+	accountMapPartition  = "accountMappings" // mappings are shared by all sources
+	defaultPoolSize      = 10
 )
 
 type Cosmo struct {
@@ -57,29 +61,26 @@ func (c *Cosmo) setupContainers() error {
 		return nil
 	}
 
-	_, err := c.cl.CreateContainer(context.Background(), azcosmos.ContainerProperties{
-		ID: messagesContainer,
-		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
-			Paths: []string{"/transactionSource"},
-		},
-	}, &azcosmos.CreateContainerOptions{})
-	if c.ignoreDuplicateErr(err) != nil {
-		return err
-	}
-
-	_, err = c.cl.CreateContainer(context.Background(), azcosmos.ContainerProperties{
-		ID: duplicateContainer,
-		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
-			Paths: []string{"/transactionSource"},
-		},
-	}, &azcosmos.CreateContainerOptions{})
-	if c.ignoreDuplicateErr(err) != nil {
-		return err
+	for _, containerID := range []string{
+		messagesContainer,
+		duplicateContainer,
+		commitBatchContainer,
+		accountMapContainer,
+	} {
+		_, err := c.cl.CreateContainer(context.Background(), azcosmos.ContainerProperties{
+			ID: containerID,
+			PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
+				Paths: []string{"/transactionSource"},
+			},
+		}, &azcosmos.CreateContainerOptions{})
+		if realErr := c.ignoreDuplicateErr(err); realErr != nil {
+			return realErr
+		}
 	}
 
 	c.setupCalled = true
 
-	return c.ignoreDuplicateErr(err)
+	return nil
 }
 
 func (c *Cosmo) ignoreDuplicateErr(err error) error {
@@ -118,6 +119,22 @@ func (c *Cosmo) getDuplicateContainer() (*azcosmos.ContainerClient, error) {
 	return c.cl.NewContainer(duplicateContainer)
 }
 
+func (c *Cosmo) getCommitBatchContainer() (*azcosmos.ContainerClient, error) {
+	if err := c.setupContainers(); err != nil {
+		return nil, err
+	}
+
+	return c.cl.NewContainer(commitBatchContainer)
+}
+
+func (c *Cosmo) getAccountMapContainer() (*azcosmos.ContainerClient, error) {
+	if err := c.setupContainers(); err != nil {
+		return nil, err
+	}
+
+	return c.cl.NewContainer(accountMapContainer)
+}
+
 func (c *Cosmo) AddMessage(ctx context.Context, messages []database.Message) error {
 	if len(messages) == 0 {
 		return nil
@@ -264,6 +281,100 @@ func (c *Cosmo) UpdateMessages(ctx context.Context, messages []*database.Message
 	return err
 }
 
+func (c *Cosmo) UpdateParsed(
+	ctx context.Context,
+	source database.TransactionSource,
+	parsed map[string]*database.ParsedMessage,
+) error {
+	container, err := c.getMessageContainer()
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(string(source))
+
+	pool := workerpool.New(defaultPoolSize)
+	for id, result := range parsed {
+		pool.Submit(func() {
+			patch := azcosmos.PatchOperations{}
+			patch.SetCondition("FROM c WHERE c.isProcessed = false")
+			patch.AppendSet("/parsed", result)
+
+			_, patchErr := backoff.Retry(ctx, func() (azcosmos.ItemResponse, error) {
+				resp, itemErr := container.PatchItem(ctx, partitionKey, id, patch, nil)
+
+				return resp, c.ignoreNotPendingErr(itemErr)
+			}, c.getRetryParams()...)
+
+			if patchErr != nil {
+				err = errors.Join(err, patchErr)
+			}
+		})
+	}
+
+	pool.StopWait()
+
+	return err
+}
+
+// UpdateDecision replaces the message only when it was not changed since it was read, concurrent updates are retried.
+func (c *Cosmo) UpdateDecision(
+	ctx context.Context,
+	source database.TransactionSource,
+	messageID string,
+	key string,
+	decision *database.Decision,
+) error {
+	container, err := c.getMessageContainer()
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(string(source))
+
+	_, err = backoff.Retry(ctx, func() (azcosmos.ItemResponse, error) {
+		resp, readErr := container.ReadItem(ctx, partitionKey, messageID, nil)
+		if readErr != nil {
+			return resp, c.ignoreNotPendingErr(readErr)
+		}
+
+		var msg database.Message
+		if readErr = json.Unmarshal(resp.Value, &msg); readErr != nil {
+			return resp, backoff.Permanent(readErr)
+		}
+
+		if msg.IsProcessed {
+			return resp, nil
+		}
+
+		msg.Decisions = mergeDecision(msg.Decisions, key, decision)
+
+		bytes, readErr := json.Marshal(msg)
+		if readErr != nil {
+			return resp, backoff.Permanent(readErr)
+		}
+
+		return container.ReplaceItem(ctx, partitionKey, messageID, bytes, &azcosmos.ItemOptions{
+			IfMatchEtag: &resp.ETag,
+		})
+	}, c.getRetryParams()...)
+
+	return err
+}
+
+// ignoreNotPendingErr ignores messages deleted by /clear (404) or processed meanwhile (412 failed condition).
+func (c *Cosmo) ignoreNotPendingErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	var azureErr *azcore.ResponseError
+	if errors.As(err, &azureErr) && (azureErr.StatusCode == 404 || azureErr.StatusCode == 412) {
+		return nil
+	}
+
+	return err
+}
+
 type duplicateKey struct {
 	ID                string `json:"id"`
 	CreatedAt         string `json:"createdAt"`
@@ -292,7 +403,9 @@ func (c *Cosmo) AddDuplicateKey(
 	}
 
 	_, err = backoff.Retry(ctx, func() (azcosmos.ItemResponse, error) {
-		return container.CreateItem(ctx, partitionKey, b, nil)
+		resp, itemErr := container.CreateItem(ctx, partitionKey, b, nil)
+
+		return resp, c.ignoreDuplicateErr(itemErr) // key already stored, same as the other backends
 	}, c.getRetryParams()...)
 
 	return err
@@ -342,3 +455,232 @@ func (c *Cosmo) GetDuplicates(
 
 	return existing, nil
 }
+
+func (c *Cosmo) DeleteDuplicateKeys(
+	ctx context.Context,
+	keys []string,
+	source database.TransactionSource,
+) error {
+	container, err := c.getDuplicateContainer()
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(string(source))
+
+	var finalErr error
+
+	for _, key := range keys {
+		_, deleteErr := backoff.Retry(ctx, func() (azcosmos.ItemResponse, error) {
+			resp, itemErr := container.DeleteItem(ctx, partitionKey, key, nil)
+
+			var azureErr *azcore.ResponseError
+			if errors.As(itemErr, &azureErr) && azureErr.StatusCode == 404 { // already removed
+				return resp, nil
+			}
+
+			return resp, itemErr
+		}, c.getRetryParams()...)
+
+		finalErr = errors.Join(finalErr, deleteErr)
+	}
+
+	return finalErr
+}
+
+func (c *Cosmo) GetMessages(
+	ctx context.Context,
+	source database.TransactionSource,
+	ids []string,
+) ([]*database.Message, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	container, err := c.getMessageContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(string(source))
+
+	query := "SELECT * FROM c where ARRAY_CONTAINS(@id, c.id)"
+	pager := container.NewQueryItemsPager(query, partitionKey, &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{
+				Name:  "@id",
+				Value: ids,
+			},
+		},
+	})
+
+	var items []*database.Message
+
+	for pager.More() {
+		response, pageErr := pager.NextPage(ctx)
+		if pageErr != nil {
+			return nil, pageErr
+		}
+
+		for _, bytes := range response.Items {
+			item := database.Message{}
+			if err = json.Unmarshal(bytes, &item); err != nil {
+				return nil, err
+			}
+
+			items = append(items, &item)
+		}
+	}
+
+	return items, nil
+}
+
+func (c *Cosmo) SaveCommitBatch(ctx context.Context, batch *database.CommitBatch) error {
+	container, err := c.getCommitBatchContainer()
+	if err != nil {
+		return err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(string(batch.TransactionSource))
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	_, err = backoff.Retry(ctx, func() (azcosmos.ItemResponse, error) {
+		return container.UpsertItem(ctx, partitionKey, b, nil)
+	}, c.getRetryParams()...)
+
+	return err
+}
+
+func (c *Cosmo) GetCommitBatches(
+	ctx context.Context,
+	source database.TransactionSource,
+	limit int,
+) ([]*database.CommitBatch, error) {
+	container, err := c.getCommitBatchContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	partitionKey := azcosmos.NewPartitionKeyString(string(source))
+
+	query := "SELECT * FROM c order by c.createdAt desc"
+	var parameters []azcosmos.QueryParameter
+
+	if limit > 0 {
+		query += " OFFSET 0 LIMIT @limit"
+		parameters = append(parameters, azcosmos.QueryParameter{
+			Name:  "@limit",
+			Value: limit,
+		})
+	}
+
+	pager := container.NewQueryItemsPager(query, partitionKey, &azcosmos.QueryOptions{
+		QueryParameters: parameters,
+	})
+
+	var items []*database.CommitBatch
+
+	for pager.More() {
+		response, pageErr := pager.NextPage(ctx)
+		if pageErr != nil {
+			return nil, pageErr
+		}
+
+		for _, bytes := range response.Items {
+			item := database.CommitBatch{}
+			if err = json.Unmarshal(bytes, &item); err != nil {
+				return nil, err
+			}
+
+			items = append(items, &item)
+		}
+	}
+
+	return items, nil
+}
+
+// accountMapping wraps the mapping, patterns may contain characters which are not allowed in cosmo ids.
+type accountMapping struct {
+	database.AccountMapping
+	ID                string `json:"id"`
+	TransactionSource string `json:"transactionSource"`
+}
+
+func (c *Cosmo) accountMappingID(pattern string) string {
+	return hex.EncodeToString([]byte(pattern))
+}
+
+func (c *Cosmo) SaveAccountMapping(ctx context.Context, mapping *database.AccountMapping) error {
+	container, err := c.getAccountMapContainer()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(accountMapping{
+		AccountMapping:    *mapping,
+		ID:                c.accountMappingID(mapping.Pattern),
+		TransactionSource: accountMapPartition,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = backoff.Retry(ctx, func() (azcosmos.ItemResponse, error) {
+		return container.UpsertItem(ctx, azcosmos.NewPartitionKeyString(accountMapPartition), b, nil)
+	}, c.getRetryParams()...)
+
+	return err
+}
+
+func (c *Cosmo) GetAccountMappings(ctx context.Context) ([]*database.AccountMapping, error) {
+	container, err := c.getAccountMapContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	pager := container.NewQueryItemsPager("SELECT * FROM c order by c.createdAt desc",
+		azcosmos.NewPartitionKeyString(accountMapPartition), nil)
+
+	var items []*database.AccountMapping
+
+	for pager.More() {
+		response, pageErr := pager.NextPage(ctx)
+		if pageErr != nil {
+			return nil, pageErr
+		}
+
+		for _, bytes := range response.Items {
+			item := accountMapping{}
+			if err = json.Unmarshal(bytes, &item); err != nil {
+				return nil, err
+			}
+
+			items = append(items, &item.AccountMapping)
+		}
+	}
+
+	return items, nil
+}
+
+func (c *Cosmo) DeleteAccountMapping(ctx context.Context, pattern string) error {
+	container, err := c.getAccountMapContainer()
+	if err != nil {
+		return err
+	}
+
+	_, err = backoff.Retry(ctx, func() (azcosmos.ItemResponse, error) {
+		return container.DeleteItem(ctx, azcosmos.NewPartitionKeyString(accountMapPartition),
+			c.accountMappingID(pattern), nil)
+	}, c.getRetryParams()...)
+
+	var azureErr *azcore.ResponseError
+	if errors.As(err, &azureErr) && azureErr.StatusCode == 404 {
+		return nil
+	}
+
+	return err
+}
@@ -1,35 +1,29 @@
 package repo_test
 
-//
-//func TestCosmo(t *testing.T) {
-//	client, err := azcosmos.NewClientFromConnectionString(os.Getenv("COSMO_DB_CONNECTION_STRING"), nil)
-//	if err != nil {
-//		panic(err)
-//	}
-//
-//	local, err := repo.NewCosmo(client, "firefly-importer")
-//	assert.NoError(t, err)
-//
-//	key := hash(time.Now().UTC().Format(time.RFC3339Nano))
-//
-//	ok, err := local.GetDuplicates(context.TODO(), []string{
-//		hash("12323"),
-//		"b",
-//	}, database.Paribas)
-//	assert.NoError(t, err)
-//	assert.NotNil(t, ok)
-//
-//	err = local.AddDuplicateKey(context.TODO(), key, database.Paribas)
-//	assert.NoError(t, err)
-//
-//	ok, err = local.GetDuplicates(context.TODO(), []string{key}, database.Paribas)
-//	assert.NoError(t, err)
-//	assert.NotNil(t, ok)
-//}
-//
-//func hash(bv string) string {
-//	shaImpl := sha512.New()
-//	shaImpl.Write([]byte(bv))
-//
-//	return fmt.Sprintf("%x", shaImpl.Sum(nil))
-//}
+import (
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo/repotest"
+)
+
+func TestCosmo(t *testing.T) {
+	connectionString, ok := os.LookupEnv("COSMO_DB_CONNECTION_STRING")
+	if !ok {
+		t.Skip("COSMO_DB_CONNECTION_STRING is not set")
+	}
+
+	client, err := azcosmos.NewClientFromConnectionString(connectionString, nil)
+	assert.NoError(t, err)
+
+	repotest.Run(t, func(t *testing.T) repotest.Repo {
+		local, cosmoErr := repo.NewCosmo(client, "firefly-importer-test")
+		assert.NoError(t, cosmoErr)
+
+		return local
+	})
+}
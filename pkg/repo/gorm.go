@@ -0,0 +1,321 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+type Gorm struct {
+	db *gorm.DB
+}
+
+func NewGorm(db *gorm.DB) (*Gorm, error) {
+	m := gormigrate.New(db, &gormigrate.Options{
+		TableName:                 "importer_migrations",
+		IDColumnName:              "id",
+		IDColumnSize:              255,
+		UseTransaction:            false,
+		ValidateUnknownMigrations: false,
+	}, getMigrations())
+
+	if err := m.Migrate(); err != nil {
+		return nil, err
+	}
+
+	return &Gorm{
+		db: db,
+	}, nil
+}
+
+func (g *Gorm) AddMessage(ctx context.Context, messages []database.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	records := make([]*messageRecord, 0, len(messages))
+	for _, msg := range messages {
+		record, err := newMessageRecord(&msg)
+		if err != nil {
+			return err
+		}
+
+		records = append(records, record)
+	}
+
+	return g.db.WithContext(ctx).Create(&records).Error
+}
+
+func (g *Gorm) GetLatestMessages(
+	ctx context.Context,
+	transactionSource database.TransactionSource,
+) ([]*database.Message, error) {
+	var records []*messageRecord
+
+	if err := g.db.WithContext(ctx).
+		Where("transaction_source = ? and is_processed = ?", transactionSource, false).
+		Order("created_at desc").
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var items []*database.Message
+	for _, record := range records {
+		msg, err := record.toMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, msg)
+	}
+
+	return items, nil
+}
+
+func (g *Gorm) Clear(ctx context.Context, transactionSource database.TransactionSource) error {
+	return g.db.WithContext(ctx).
+		Where("transaction_source = ? and is_processed = ?", transactionSource, false).
+		Delete(&messageRecord{}).Error
+}
+
+func (g *Gorm) UpdateMessages(ctx context.Context, messages []*database.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	records := make([]*messageRecord, 0, len(messages))
+	for _, msg := range messages {
+		record, err := newMessageRecord(msg)
+		if err != nil {
+			return err
+		}
+
+		records = append(records, record)
+	}
+
+	return g.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&records).Error
+}
+
+func (g *Gorm) UpdateParsed(
+	ctx context.Context,
+	source database.TransactionSource,
+	parsed map[string]*database.ParsedMessage,
+) error {
+	if len(parsed) == 0 {
+		return nil
+	}
+
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, result := range parsed {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+
+			if err = tx.Model(&messageRecord{}).
+				Where("transaction_source = ? and id = ? and is_processed = ?", source, id, false).
+				Update("parsed", string(data)).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (g *Gorm) UpdateDecision(
+	ctx context.Context,
+	source database.TransactionSource,
+	messageID string,
+	key string,
+	decision *database.Decision,
+) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("transaction_source = ? and id = ? and is_processed = ?", source, messageID, false)
+		if tx.Dialector.Name() == "postgres" { // sqlite serializes writers already
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+
+		var records []*messageRecord
+		if err := query.Limit(1).Find(&records).Error; err != nil {
+			return err
+		}
+
+		if len(records) == 0 { // cleared or committed meanwhile
+			return nil
+		}
+
+		msg, err := records[0].toMessage()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(mergeDecision(msg.Decisions, key, decision))
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&messageRecord{}).
+			Where("transaction_source = ? and id = ? and is_processed = ?", source, messageID, false).
+			Update("decisions", string(data)).Error
+	})
+}
+
+func (g *Gorm) AddDuplicateKey(
+	ctx context.Context,
+	key string,
+	source database.TransactionSource,
+) error {
+	return g.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&duplicateKeyRecord{
+			ID:                key,
+			TransactionSource: string(source),
+			CreatedAt:         time.Now().UTC(),
+		}).Error
+}
+
+func (g *Gorm) GetDuplicates(
+	ctx context.Context,
+	keys []string,
+	source database.TransactionSource,
+) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	var existing []string
+
+	if err := g.db.WithContext(ctx).
+		Model(&duplicateKeyRecord{}).
+		Where("transaction_source = ? and id in ?", source, keys).
+		Pluck("id", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func (g *Gorm) DeleteDuplicateKeys(
+	ctx context.Context,
+	keys []string,
+	source database.TransactionSource,
+) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return g.db.WithContext(ctx).
+		Where("transaction_source = ? and id in ?", source, keys).
+		Delete(&duplicateKeyRecord{}).Error
+}
+
+func (g *Gorm) GetMessages(
+	ctx context.Context,
+	source database.TransactionSource,
+	ids []string,
+) ([]*database.Message, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var records []*messageRecord
+
+	if err := g.db.WithContext(ctx).
+		Where("transaction_source = ? and id in ?", source, ids).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var items []*database.Message
+	for _, record := range records {
+		msg, err := record.toMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, msg)
+	}
+
+	return items, nil
+}
+
+func (g *Gorm) SaveCommitBatch(ctx context.Context, batch *database.CommitBatch) error {
+	record, err := newCommitBatchRecord(batch)
+	if err != nil {
+		return err
+	}
+
+	return g.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(record).Error
+}
+
+func (g *Gorm) GetCommitBatches(
+	ctx context.Context,
+	source database.TransactionSource,
+	limit int,
+) ([]*database.CommitBatch, error) {
+	var records []*commitBatchRecord
+
+	query := g.db.WithContext(ctx).
+		Where("transaction_source = ?", source).
+		Order("created_at desc")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var items []*database.CommitBatch
+	for _, record := range records {
+		batch, err := record.toCommitBatch()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, batch)
+	}
+
+	return items, nil
+}
+
+func (g *Gorm) SaveAccountMapping(ctx context.Context, mapping *database.AccountMapping) error {
+	return g.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(newAccountMappingRecord(mapping)).Error
+}
+
+func (g *Gorm) GetAccountMappings(ctx context.Context) ([]*database.AccountMapping, error) {
+	var records []*accountMappingRecord
+
+	if err := g.db.WithContext(ctx).
+		Order("created_at desc").
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var items []*database.AccountMapping
+	for _, record := range records {
+		items = append(items, record.toAccountMapping())
+	}
+
+	return items, nil
+}
+
+func (g *Gorm) DeleteAccountMapping(ctx context.Context, pattern string) error {
+	return g.db.WithContext(ctx).
+		Where("pattern = ?", pattern).
+		Delete(&accountMappingRecord{}).Error
+}
@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// getMigrations keeps the sql portable between postgres and sqlite.
+func getMigrations() []*gormigrate.Migration {
+	return []*gormigrate.Migration{
+		{
+			ID: "2026_10_17_Initial",
+			Migrate: func(db *gorm.DB) error {
+				return execAll(db,
+					`create table if not exists importer_messages
+(
+    id                 varchar(255) not null
+        constraint importer_messages_pk
+            primary key,
+    created_at         timestamp,
+    processed_at       timestamp,
+    is_processed       boolean not null default false,
+    content            text,
+    file_id            varchar(255),
+    chat_id            bigint,
+    message_id         bigint,
+    transaction_source varchar(64) not null
+);`,
+					`create index if not exists importer_messages_source_idx
+    on importer_messages (transaction_source, is_processed, created_at);`,
+					`create table if not exists importer_duplicates
+(
+    id                 varchar(255) not null,
+    transaction_source varchar(64)  not null,
+    created_at         timestamp,
+    constraint importer_duplicates_pk
+        primary key (transaction_source, id)
+);`,
+				)
+			},
+		},
+		{
+			ID: "2026_10_17_CommitBatches",
+			Migrate: func(db *gorm.DB) error {
+				return execAll(db,
+					`create table if not exists importer_commit_batches
+(
+    id                 varchar(255) not null
+        constraint importer_commit_batches_pk
+            primary key,
+    transaction_source varchar(64)  not null,
+    chat_id            bigint,
+    created_at         timestamp,
+    undone_at          timestamp,
+    transactions       text
+);`,
+					`create index if not exists importer_commit_batches_source_idx
+    on importer_commit_batches (transaction_source, created_at);`,
+				)
+			},
+		},
+		{
+			ID: "2026_10_17_MessageDecisions",
+			Migrate: func(db *gorm.DB) error {
+				return execAll(db,
+					`alter table importer_messages add column decisions text;`,
+				)
+			},
+		},
+		{
+			ID: "2026_10_17_AccountMappings",
+			Migrate: func(db *gorm.DB) error {
+				return execAll(db,
+					`create table if not exists importer_account_mappings
+(
+    pattern            varchar(255) not null
+        constraint importer_account_mappings_pk
+            primary key,
+    firefly_account_id varchar(255) not null,
+    created_at         timestamp
+);`,
+				)
+			},
+		},
+		{
+			ID: "2026_10_17_MessageParsed",
+			Migrate: func(db *gorm.DB) error {
+				return execAll(db,
+					`alter table importer_messages add column parsed text;`,
+				)
+			},
+		},
+	}
+}
+
+func execAll(db *gorm.DB, statements ...string) error {
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
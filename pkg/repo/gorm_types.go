@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+type messageRecord struct {
+	ID                string `gorm:"primaryKey"`
+	CreatedAt         time.Time
+	ProcessedAt       *time.Time
+	IsProcessed       bool
+	Content           string
+	FileID            string
+	ChatID            int64
+	MessageID         int64
+	TransactionSource string
+	Decisions         string
+	Parsed            string
+}
+
+func (messageRecord) TableName() string {
+	return "importer_messages"
+}
+
+func newMessageRecord(msg *database.Message) (*messageRecord, error) {
+	var decisions []byte
+
+	if len(msg.Decisions) > 0 {
+		var err error
+		if decisions, err = json.Marshal(msg.Decisions); err != nil {
+			return nil, err
+		}
+	}
+
+	var parsed []byte
+
+	if msg.Parsed != nil {
+		var err error
+		if parsed, err = json.Marshal(msg.Parsed); err != nil {
+			return nil, err
+		}
+	}
+
+	return &messageRecord{
+		ID:                msg.ID,
+		CreatedAt:         msg.CreatedAt.UTC(),
+		ProcessedAt:       msg.ProcessedAt,
+		IsProcessed:       msg.IsProcessed,
+		Content:           msg.Content,
+		FileID:            msg.FileID,
+		ChatID:            msg.ChatID,
+		MessageID:         msg.MessageID,
+		TransactionSource: string(msg.TransactionSource),
+		Decisions:         string(decisions),
+		Parsed:            string(parsed),
+	}, nil
+}
+
+func (m *messageRecord) toMessage() (*database.Message, error) {
+	msg := &database.Message{
+		ID:                m.ID,
+		CreatedAt:         m.CreatedAt,
+		ProcessedAt:       m.ProcessedAt,
+		IsProcessed:       m.IsProcessed,
+		Content:           m.Content,
+		FileID:            m.FileID,
+		ChatID:            m.ChatID,
+		MessageID:         m.MessageID,
+		TransactionSource: database.TransactionSource(m.TransactionSource),
+	}
+
+	if m.Decisions != "" {
+		if err := json.Unmarshal([]byte(m.Decisions), &msg.Decisions); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.Parsed != "" {
+		if err := json.Unmarshal([]byte(m.Parsed), &msg.Parsed); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+type duplicateKeyRecord struct {
+	ID                string `gorm:"primaryKey"`
+	TransactionSource string `gorm:"primaryKey"`
+	CreatedAt         time.Time
+}
+
+func (duplicateKeyRecord) TableName() string {
+	return "importer_duplicates"
+}
+
+type commitBatchRecord struct {
+	ID                string `gorm:"primaryKey"`
+	TransactionSource string
+	ChatID            int64
+	CreatedAt         time.Time
+	UndoneAt          *time.Time
+	Transactions      string
+}
+
+func (commitBatchRecord) TableName() string {
+	return "importer_commit_batches"
+}
+
+func newCommitBatchRecord(batch *database.CommitBatch) (*commitBatchRecord, error) {
+	transactions, err := json.Marshal(batch.Transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &commitBatchRecord{
+		ID:                batch.ID,
+		TransactionSource: string(batch.TransactionSource),
+		ChatID:            batch.ChatID,
+		CreatedAt:         batch.CreatedAt.UTC(),
+		UndoneAt:          batch.UndoneAt,
+		Transactions:      string(transactions),
+	}, nil
+}
+
+func (c *commitBatchRecord) toCommitBatch() (*database.CommitBatch, error) {
+	batch := &database.CommitBatch{
+		ID:                c.ID,
+		TransactionSource: database.TransactionSource(c.TransactionSource),
+		ChatID:            c.ChatID,
+		CreatedAt:         c.CreatedAt,
+		UndoneAt:          c.UndoneAt,
+	}
+
+	if c.Transactions != "" {
+		if err := json.Unmarshal([]byte(c.Transactions), &batch.Transactions); err != nil {
+			return nil, err
+		}
+	}
+
+	return batch, nil
+}
+
+type accountMappingRecord struct {
+	Pattern          string `gorm:"primaryKey"`
+	FireflyAccountID string
+	CreatedAt        time.Time
+}
+
+func (accountMappingRecord) TableName() string {
+	return "importer_account_mappings"
+}
+
+func newAccountMappingRecord(mapping *database.AccountMapping) *accountMappingRecord {
+	return &accountMappingRecord{
+		Pattern:          mapping.Pattern,
+		FireflyAccountID: mapping.FireflyAccountID,
+		CreatedAt:        mapping.CreatedAt.UTC(),
+	}
+}
+
+func (a *accountMappingRecord) toAccountMapping() *database.AccountMapping {
+	return &database.AccountMapping{
+		Pattern:          a.Pattern,
+		FireflyAccountID: a.FireflyAccountID,
+		CreatedAt:        a.CreatedAt,
+	}
+}
@@ -0,0 +1,334 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+type Memory struct {
+	mut        sync.RWMutex
+	messages   map[database.TransactionSource]map[string]database.Message
+	duplicates map[database.TransactionSource]map[string]time.Time
+	batches    map[database.TransactionSource]map[string]database.CommitBatch
+	mappings   map[string]database.AccountMapping
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		messages:   map[database.TransactionSource]map[string]database.Message{},
+		duplicates: map[database.TransactionSource]map[string]time.Time{},
+		batches:    map[database.TransactionSource]map[string]database.CommitBatch{},
+		mappings:   map[string]database.AccountMapping{},
+	}
+}
+
+func (m *Memory) AddMessage(_ context.Context, messages []database.Message) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for _, msg := range messages {
+		m.putMessage(msg)
+	}
+
+	return nil
+}
+
+func (m *Memory) putMessage(msg database.Message) {
+	partition, ok := m.messages[msg.TransactionSource]
+	if !ok {
+		partition = map[string]database.Message{}
+		m.messages[msg.TransactionSource] = partition
+	}
+
+	if msg.ProcessedAt != nil {
+		msg.ProcessedAt = lo.ToPtr(*msg.ProcessedAt)
+	}
+
+	if msg.Decisions != nil {
+		msg.Decisions = lo.MapValues(msg.Decisions, func(decision *database.Decision, _ string) *database.Decision {
+			return lo.ToPtr(*decision)
+		})
+	}
+
+	if msg.Parsed != nil {
+		msg.Parsed = lo.ToPtr(*msg.Parsed)
+	}
+
+	partition[msg.ID] = msg
+}
+
+func (m *Memory) GetLatestMessages(
+	_ context.Context,
+	transactionSource database.TransactionSource,
+) ([]*database.Message, error) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	var items []*database.Message
+
+	for _, msg := range m.messages[transactionSource] {
+		if msg.IsProcessed {
+			continue
+		}
+
+		msgCopy := msg
+		items = append(items, &msgCopy)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+
+	return items, nil
+}
+
+func (m *Memory) Clear(_ context.Context, transactionSource database.TransactionSource) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for id, msg := range m.messages[transactionSource] {
+		if !msg.IsProcessed {
+			delete(m.messages[transactionSource], id)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) UpdateMessages(_ context.Context, messages []*database.Message) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for _, msg := range messages {
+		m.putMessage(*msg)
+	}
+
+	return nil
+}
+
+func (m *Memory) UpdateParsed(
+	_ context.Context,
+	source database.TransactionSource,
+	parsed map[string]*database.ParsedMessage,
+) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for id, result := range parsed {
+		msg, ok := m.messages[source][id]
+		if !ok || msg.IsProcessed {
+			continue
+		}
+
+		msg.Parsed = result
+		m.putMessage(msg)
+	}
+
+	return nil
+}
+
+func (m *Memory) UpdateDecision(
+	_ context.Context,
+	source database.TransactionSource,
+	messageID string,
+	key string,
+	decision *database.Decision,
+) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	msg, ok := m.messages[source][messageID]
+	if !ok || msg.IsProcessed {
+		return nil
+	}
+
+	msg.Decisions = mergeDecision(lo.Assign(msg.Decisions), key, decision) // returned copies share the map
+	m.putMessage(msg)
+
+	return nil
+}
+
+func (m *Memory) AddDuplicateKey(
+	_ context.Context,
+	key string,
+	source database.TransactionSource,
+) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	partition, ok := m.duplicates[source]
+	if !ok {
+		partition = map[string]time.Time{}
+		m.duplicates[source] = partition
+	}
+
+	if _, exists := partition[key]; !exists {
+		partition[key] = time.Now().UTC()
+	}
+
+	return nil
+}
+
+func (m *Memory) GetDuplicates(
+	_ context.Context,
+	keys []string,
+	source database.TransactionSource,
+) ([]string, error) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	var existing []string
+
+	for _, key := range lo.Uniq(keys) {
+		if _, ok := m.duplicates[source][key]; ok {
+			existing = append(existing, key)
+		}
+	}
+
+	return existing, nil
+}
+
+func (m *Memory) DeleteDuplicateKeys(
+	_ context.Context,
+	keys []string,
+	source database.TransactionSource,
+) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for _, key := range keys {
+		delete(m.duplicates[source], key)
+	}
+
+	return nil
+}
+
+func (m *Memory) GetMessages(
+	_ context.Context,
+	source database.TransactionSource,
+	ids []string,
+) ([]*database.Message, error) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	var items []*database.Message
+
+	for _, id := range lo.Uniq(ids) {
+		msg, ok := m.messages[source][id]
+		if !ok {
+			continue
+		}
+
+		items = append(items, &msg)
+	}
+
+	return items, nil
+}
+
+func (m *Memory) SaveCommitBatch(_ context.Context, batch *database.CommitBatch) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	copied, err := m.copyBatch(batch)
+	if err != nil {
+		return err
+	}
+
+	partition, ok := m.batches[batch.TransactionSource]
+	if !ok {
+		partition = map[string]database.CommitBatch{}
+		m.batches[batch.TransactionSource] = partition
+	}
+
+	partition[batch.ID] = *copied
+
+	return nil
+}
+
+func (m *Memory) GetCommitBatches(
+	_ context.Context,
+	source database.TransactionSource,
+	limit int,
+) ([]*database.CommitBatch, error) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	var items []*database.CommitBatch
+
+	for _, batch := range m.batches[source] {
+		copied, err := m.copyBatch(&batch)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, copied)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	return items, nil
+}
+
+func (m *Memory) SaveAccountMapping(_ context.Context, mapping *database.AccountMapping) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.mappings[mapping.Pattern] = *mapping
+
+	return nil
+}
+
+func (m *Memory) GetAccountMappings(_ context.Context) ([]*database.AccountMapping, error) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	var items []*database.AccountMapping
+
+	for _, mapping := range m.mappings {
+		mappingCopy := mapping
+		items = append(items, &mappingCopy)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+
+	return items, nil
+}
+
+func (m *Memory) DeleteAccountMapping(_ context.Context, pattern string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	delete(m.mappings, pattern)
+
+	return nil
+}
+
+// copyBatch detaches the stored batch from the caller, same as a real database would.
+func (m *Memory) copyBatch(batch *database.CommitBatch) (*database.CommitBatch, error) {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied database.CommitBatch
+	if err = json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+
+	return &copied, nil
+}
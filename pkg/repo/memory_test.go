@@ -0,0 +1,14 @@
+package repo_test
+
+import (
+	"testing"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo/repotest"
+)
+
+func TestMemory(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repo {
+		return repo.NewMemory()
+	})
+}
@@ -0,0 +1,15 @@
+package repo
+
+import (
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func NewPostgres(connectionString string) (*Gorm, error) {
+	db, err := gorm.Open(postgres.Open(connectionString), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGorm(db)
+}
@@ -0,0 +1,25 @@
+package repo_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo/repotest"
+)
+
+func TestPostgres(t *testing.T) {
+	connectionString, ok := os.LookupEnv("POSTGRES_CONNECTION_STRING")
+	if !ok {
+		t.Skip("POSTGRES_CONNECTION_STRING is not set")
+	}
+
+	repotest.Run(t, func(t *testing.T) repotest.Repo {
+		local, err := repo.NewPostgres(connectionString)
+		assert.NoError(t, err)
+
+		return local
+	})
+}
@@ -0,0 +1,541 @@
+// Package repotest contains the conformance suite every storage backend has to pass.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/accountmap"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/duplicatecleaner"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/processor"
+)
+
+type Repo interface {
+	processor.Repo
+	duplicatecleaner.Repo
+	accountmap.Repo
+}
+
+// Run executes the suite against the repo returned by factory. Every case uses its own
+// transaction sources, so the suite is safe to run against a shared live database.
+func Run(t *testing.T, factory func(t *testing.T) Repo) {
+	t.Run("latest messages are ordered by creation date desc", func(t *testing.T) {
+		testLatestMessagesOrder(t, factory(t))
+	})
+
+	t.Run("latest messages skip processed", func(t *testing.T) {
+		testProcessedFilter(t, factory(t))
+	})
+
+	t.Run("clear removes only unprocessed messages of one source", func(t *testing.T) {
+		testClear(t, factory(t))
+	})
+
+	t.Run("duplicate keys are partitioned by source", func(t *testing.T) {
+		testDuplicates(t, factory(t))
+	})
+
+	t.Run("duplicate keys can be deleted", func(t *testing.T) {
+		testDeleteDuplicates(t, factory(t))
+	})
+
+	t.Run("messages are fetched by id including processed", func(t *testing.T) {
+		testGetMessages(t, factory(t))
+	})
+
+	t.Run("commit batches are ordered and updatable", func(t *testing.T) {
+		testCommitBatches(t, factory(t))
+	})
+
+	t.Run("message decisions are persisted", func(t *testing.T) {
+		testDecisions(t, factory(t))
+	})
+
+	t.Run("decision updates are merged into pending messages", func(t *testing.T) {
+		testUpdateDecision(t, factory(t))
+	})
+
+	t.Run("message parse results are persisted", func(t *testing.T) {
+		testParsed(t, factory(t))
+	})
+
+	t.Run("parse results update only pending messages", func(t *testing.T) {
+		testUpdateParsed(t, factory(t))
+	})
+
+	t.Run("account mappings are upserted and deleted", func(t *testing.T) {
+		testAccountMappings(t, factory(t))
+	})
+}
+
+func newSource() database.TransactionSource {
+	return database.TransactionSource("repotest_" + uuid.NewString())
+}
+
+func newMessage(source database.TransactionSource, createdAt time.Time) database.Message {
+	return database.Message{
+		ID:                uuid.NewString(),
+		CreatedAt:         createdAt,
+		Content:           "content " + uuid.NewString(),
+		FileID:            "file-id",
+		ChatID:            1234,
+		MessageID:         5678,
+		TransactionSource: source,
+	}
+}
+
+func ids(messages []*database.Message) []string {
+	return lo.Map(messages, func(item *database.Message, _ int) string {
+		return item.ID
+	})
+}
+
+func testLatestMessagesOrder(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	oldest := newMessage(source, now.Add(-2*time.Hour))
+	newest := newMessage(source, now)
+	middle := newMessage(source, now.Add(-time.Hour))
+
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{oldest, newest, middle}))
+
+	messages, err := repo.GetLatestMessages(ctx, source)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{newest.ID, middle.ID, oldest.ID}, ids(messages))
+
+	if assert.Len(t, messages, 3) {
+		assert.Equal(t, newest.Content, messages[0].Content)
+		assert.Equal(t, newest.FileID, messages[0].FileID)
+		assert.Equal(t, newest.ChatID, messages[0].ChatID)
+		assert.Equal(t, newest.MessageID, messages[0].MessageID)
+		assert.Equal(t, source, messages[0].TransactionSource)
+		assert.True(t, newest.CreatedAt.Equal(messages[0].CreatedAt))
+	}
+}
+
+func testProcessedFilter(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	first := newMessage(source, now.Add(-time.Hour))
+	second := newMessage(source, now)
+
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{first, second}))
+
+	messages, err := repo.GetLatestMessages(ctx, source)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	processed := messages[0]
+	processed.IsProcessed = true
+	processed.ProcessedAt = lo.ToPtr(now)
+
+	assert.NoError(t, repo.UpdateMessages(ctx, []*database.Message{processed}))
+
+	messages, err = repo.GetLatestMessages(ctx, source)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{first.ID}, ids(messages))
+}
+
+func testClear(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	otherSource := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	pending := newMessage(source, now)
+	processed := newMessage(source, now.Add(-time.Hour))
+	other := newMessage(otherSource, now)
+
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{pending, processed, other}))
+
+	processed.IsProcessed = true
+	processed.ProcessedAt = lo.ToPtr(now)
+	assert.NoError(t, repo.UpdateMessages(ctx, []*database.Message{&processed}))
+
+	assert.NoError(t, repo.Clear(ctx, source))
+
+	messages, err := repo.GetLatestMessages(ctx, source)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+
+	messages, err = repo.GetLatestMessages(ctx, otherSource)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{other.ID}, ids(messages))
+
+	// the processed message must survive clear, flip it back to verify it is still stored
+	processed.IsProcessed = false
+	processed.ProcessedAt = nil
+	assert.NoError(t, repo.UpdateMessages(ctx, []*database.Message{&processed}))
+
+	messages, err = repo.GetLatestMessages(ctx, source)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{processed.ID}, ids(messages))
+}
+
+func testDuplicates(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	otherSource := newSource()
+
+	key := uuid.NewString()
+	otherKey := uuid.NewString()
+
+	existing, err := repo.GetDuplicates(ctx, []string{key}, source)
+	assert.NoError(t, err)
+	assert.Empty(t, existing)
+
+	assert.NoError(t, repo.AddDuplicateKey(ctx, key, source))
+	assert.NoError(t, repo.AddDuplicateKey(ctx, key, source)) // adding a stored key again is not an error
+	assert.NoError(t, repo.AddDuplicateKey(ctx, otherKey, otherSource))
+
+	existing, err = repo.GetDuplicates(ctx, []string{key, otherKey, uuid.NewString()}, source)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, existing)
+
+	existing, err = repo.GetDuplicates(ctx, []string{key, otherKey}, otherSource)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{otherKey}, existing)
+}
+
+func testDeleteDuplicates(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+
+	key := uuid.NewString()
+	kept := uuid.NewString()
+
+	assert.NoError(t, repo.AddDuplicateKey(ctx, key, source))
+	assert.NoError(t, repo.AddDuplicateKey(ctx, kept, source))
+
+	assert.NoError(t, repo.DeleteDuplicateKeys(ctx, []string{key, uuid.NewString()}, source))
+
+	existing, err := repo.GetDuplicates(ctx, []string{key, kept}, source)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{kept}, existing)
+}
+
+func testGetMessages(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	pending := newMessage(source, now)
+	processed := newMessage(source, now.Add(-time.Hour))
+	other := newMessage(source, now.Add(-2*time.Hour))
+
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{pending, processed, other}))
+
+	processed.IsProcessed = true
+	processed.ProcessedAt = lo.ToPtr(now)
+	assert.NoError(t, repo.UpdateMessages(ctx, []*database.Message{&processed}))
+
+	messages, err := repo.GetMessages(ctx, source, []string{pending.ID, processed.ID, uuid.NewString()})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{pending.ID, processed.ID}, ids(messages))
+
+	for _, msg := range messages {
+		if msg.ID == processed.ID {
+			assert.True(t, msg.IsProcessed)
+		}
+	}
+
+	messages, err = repo.GetMessages(ctx, newSource(), []string{pending.ID})
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func testDecisions(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	msg := newMessage(source, now)
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{msg}))
+
+	msg.Decisions = map[string]*database.Decision{
+		"abc": {Action: database.DecisionActionSkip, UpdatedAt: now},
+		"def": {CategoryName: "Groceries", UpdatedAt: now},
+	}
+	assert.NoError(t, repo.UpdateMessages(ctx, []*database.Message{&msg}))
+
+	msg.Decisions["abc"].Action = database.DecisionActionCommit // stored copy is detached
+
+	messages, err := repo.GetLatestMessages(ctx, source)
+	assert.NoError(t, err)
+
+	if assert.Len(t, messages, 1) {
+		assertJSONEqual(t, map[string]*database.Decision{
+			"abc": {Action: database.DecisionActionSkip, UpdatedAt: now},
+			"def": {CategoryName: "Groceries", UpdatedAt: now},
+		}, messages[0].Decisions)
+	}
+}
+
+func testUpdateDecision(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	pending := newMessage(source, now)
+	processed := newMessage(source, now)
+	processed.IsProcessed = true
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{pending, processed}))
+
+	// two buttons of one transaction pressed right after each other
+	assert.NoError(t, repo.UpdateDecision(ctx, source, pending.ID, "abc",
+		&database.Decision{CategoryName: "Groceries", UpdatedAt: now}))
+	assert.NoError(t, repo.UpdateDecision(ctx, source, pending.ID, "abc",
+		&database.Decision{Action: database.DecisionActionCommit, UpdatedAt: now.Add(time.Second)}))
+	assert.NoError(t, repo.UpdateDecision(ctx, source, pending.ID, "def",
+		&database.Decision{Action: database.DecisionActionSkip, UpdatedAt: now}))
+
+	assert.NoError(t, repo.UpdateDecision(ctx, source, processed.ID, "abc",
+		&database.Decision{Action: database.DecisionActionSkip, UpdatedAt: now}))
+	assert.NoError(t, repo.UpdateDecision(ctx, source, "missing", "abc",
+		&database.Decision{Action: database.DecisionActionSkip, UpdatedAt: now}))
+
+	messages, err := repo.GetMessages(ctx, source, []string{pending.ID, processed.ID, "missing"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{pending.ID, processed.ID}, ids(messages))
+
+	for _, msg := range messages {
+		if msg.ID == pending.ID {
+			assertJSONEqual(t, map[string]*database.Decision{
+				"abc": {
+					Action:       database.DecisionActionCommit,
+					CategoryName: "Groceries",
+					UpdatedAt:    now.Add(time.Second),
+				},
+				"def": {Action: database.DecisionActionSkip, UpdatedAt: now},
+			}, msg.Decisions)
+			assert.Equal(t, pending.Content, msg.Content)
+		}
+
+		if msg.ID == processed.ID {
+			assert.Empty(t, msg.Decisions)
+		}
+	}
+}
+
+func testParsed(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	msg := newMessage(source, now)
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{msg}))
+
+	parsed := &database.ParsedMessage{
+		Fingerprint: "abc",
+		ParsedAt:    now,
+		Errors:      []string{"failed to decode hex"},
+		Transactions: []*database.ParsedTransaction{
+			{
+				Position: 2,
+				Transaction: &database.Transaction{
+					ID:          "tx-1",
+					Description: "Groceries",
+					Date:        now,
+				},
+				DuplicateMessageIDs: []string{"msg-2"},
+				FireflyPayload:      json.RawMessage(`{"amount":"10.00"}`),
+				Error:               "not supported",
+				ErrorKinds:          []string{"notSupported"},
+				MatchedRules:        []string{"groceries"},
+			},
+		},
+	}
+
+	msg.Parsed = parsed
+	assert.NoError(t, repo.UpdateMessages(ctx, []*database.Message{&msg}))
+
+	messages, err := repo.GetLatestMessages(ctx, source)
+	assert.NoError(t, err)
+
+	if assert.Len(t, messages, 1) {
+		assertJSONEqual(t, parsed, messages[0].Parsed)
+	}
+}
+
+func testUpdateParsed(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	cleared := newMessage(source, now)
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{cleared}))
+	assert.NoError(t, repo.Clear(ctx, source)) // cleared while being parsed
+
+	pending := newMessage(source, now)
+	processed := newMessage(source, now)
+	assert.NoError(t, repo.AddMessage(ctx, []database.Message{pending, processed}))
+
+	// decisions and the commit are made while the messages are being parsed
+	decisions := map[string]*database.Decision{
+		"abc": {Action: database.DecisionActionSkip, UpdatedAt: now},
+	}
+	pending.Decisions = decisions
+	processed.IsProcessed = true
+	processed.ProcessedAt = lo.ToPtr(now)
+	assert.NoError(t, repo.UpdateMessages(ctx, []*database.Message{&pending, &processed}))
+
+	parsed := &database.ParsedMessage{Fingerprint: "abc", ParsedAt: now}
+	assert.NoError(t, repo.UpdateParsed(ctx, source, map[string]*database.ParsedMessage{
+		pending.ID:   parsed,
+		processed.ID: parsed,
+		cleared.ID:   parsed,
+	}))
+
+	messages, err := repo.GetMessages(ctx, source, []string{pending.ID, processed.ID, cleared.ID})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{pending.ID, processed.ID}, ids(messages))
+
+	for _, msg := range messages {
+		if msg.ID == pending.ID {
+			assertJSONEqual(t, parsed, msg.Parsed)
+			assertJSONEqual(t, decisions, msg.Decisions)
+			assert.False(t, msg.IsProcessed)
+		}
+
+		if msg.ID == processed.ID {
+			assert.Nil(t, msg.Parsed)
+			assert.True(t, msg.IsProcessed)
+		}
+	}
+}
+
+func testAccountMappings(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// mappings are not partitioned, patterns are unique to keep the case safe on a shared database
+	exact := &database.AccountMapping{
+		Pattern:          "repotest_" + uuid.NewString(),
+		FireflyAccountID: "1",
+		CreatedAt:        now.Add(-time.Hour),
+	}
+	masked := &database.AccountMapping{
+		Pattern:          "repotest_5168**?" + uuid.NewString(),
+		FireflyAccountID: "2",
+		CreatedAt:        now,
+	}
+
+	own := func() []*database.AccountMapping {
+		mappings, err := repo.GetAccountMappings(ctx)
+		assert.NoError(t, err)
+
+		return lo.Filter(mappings, func(item *database.AccountMapping, _ int) bool {
+			return item.Pattern == exact.Pattern || item.Pattern == masked.Pattern
+		})
+	}
+
+	assert.NoError(t, repo.SaveAccountMapping(ctx, exact))
+	assert.NoError(t, repo.SaveAccountMapping(ctx, masked))
+
+	exact.FireflyAccountID = "3"
+	assert.NoError(t, repo.SaveAccountMapping(ctx, exact))
+
+	mappings := own()
+	if assert.Len(t, mappings, 2) {
+		assert.Equal(t, masked.Pattern, mappings[0].Pattern)
+		assert.Equal(t, "2", mappings[0].FireflyAccountID)
+		assert.True(t, now.Equal(mappings[0].CreatedAt))
+		assert.Equal(t, exact.Pattern, mappings[1].Pattern)
+		assert.Equal(t, "3", mappings[1].FireflyAccountID)
+	}
+
+	assert.NoError(t, repo.DeleteAccountMapping(ctx, masked.Pattern))
+	assert.NoError(t, repo.DeleteAccountMapping(ctx, "repotest_"+uuid.NewString()))
+
+	assert.Equal(t, []string{exact.Pattern}, lo.Map(own(), func(item *database.AccountMapping, _ int) string {
+		return item.Pattern
+	}))
+
+	assert.NoError(t, repo.DeleteAccountMapping(ctx, exact.Pattern))
+}
+
+func testCommitBatches(t *testing.T, repo Repo) {
+	ctx := context.TODO()
+	source := newSource()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	newBatch := func(createdAt time.Time) *database.CommitBatch {
+		return &database.CommitBatch{
+			ID:                uuid.NewString(),
+			TransactionSource: source,
+			ChatID:            1234,
+			CreatedAt:         createdAt,
+			Transactions: []*database.CommitBatchTransaction{
+				{
+					FireflyID:      "42",
+					MessageIDs:     []string{uuid.NewString()},
+					DuplicateKeys:  []string{uuid.NewString()},
+					Status:         database.CommitStatusCommitted,
+					FireflyPayload: []byte(`{"amount":"12.50"}`),
+					Transaction: &database.Transaction{
+						ID:          uuid.NewString(),
+						Description: "Coffee",
+						Date:        createdAt,
+					},
+				},
+				{
+					MessageIDs: []string{uuid.NewString()},
+					Status:     database.CommitStatusFailed,
+					Error:      "failed to commit transaction",
+				},
+			},
+		}
+	}
+
+	older := newBatch(now.Add(-time.Hour))
+	newer := newBatch(now)
+
+	assert.NoError(t, repo.SaveCommitBatch(ctx, older))
+	assert.NoError(t, repo.SaveCommitBatch(ctx, newer))
+
+	batches, err := repo.GetCommitBatches(ctx, source, 0)
+	assert.NoError(t, err)
+
+	if assert.Len(t, batches, 2) {
+		assert.Equal(t, newer.ID, batches[0].ID)
+		assert.Equal(t, older.ID, batches[1].ID)
+		assertJSONEqual(t, newer.Transactions, batches[0].Transactions)
+		assert.Equal(t, newer.ChatID, batches[0].ChatID)
+		assert.Nil(t, batches[0].UndoneAt)
+	}
+
+	newer.UndoneAt = lo.ToPtr(now)
+	assert.NoError(t, repo.SaveCommitBatch(ctx, newer))
+
+	batches, err = repo.GetCommitBatches(ctx, source, 1)
+	assert.NoError(t, err)
+
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, newer.ID, batches[0].ID)
+		if assert.NotNil(t, batches[0].UndoneAt) {
+			assert.True(t, now.Equal(*batches[0].UndoneAt))
+		}
+	}
+}
+
+// assertJSONEqual compares values by their stored form, decimals and times do not survive a round trip bit for bit.
+func assertJSONEqual(t *testing.T, expected any, actual any) {
+	expectedJSON, err := json.Marshal(expected)
+	assert.NoError(t, err)
+
+	actualJSON, err := json.Marshal(actual)
+	assert.NoError(t, err)
+
+	assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
@@ -0,0 +1,20 @@
+package repo_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/repo/repotest"
+)
+
+func TestSqlite(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repo {
+		local, err := repo.NewSqlite(filepath.Join(t.TempDir(), "importer.db"))
+		assert.NoError(t, err)
+
+		return local
+	})
+}
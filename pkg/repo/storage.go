@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+const (
+	StorageCosmo    = "cosmo"
+	StoragePostgres = "postgres"
+	StorageSqlite   = "sqlite"
+	StorageMemory   = "memory"
+)
+
+type Storage interface {
+	AddMessage(ctx context.Context, messages []database.Message) error
+	GetLatestMessages(ctx context.Context, source database.TransactionSource) ([]*database.Message, error)
+	Clear(ctx context.Context, transactionSource database.TransactionSource) error
+	UpdateMessages(ctx context.Context, message []*database.Message) error
+	UpdateParsed(ctx context.Context, source database.TransactionSource, parsed map[string]*database.ParsedMessage) error
+	UpdateDecision(
+		ctx context.Context,
+		source database.TransactionSource,
+		messageID string,
+		key string,
+		decision *database.Decision,
+	) error
+	GetDuplicates(ctx context.Context, key []string, source database.TransactionSource) ([]string, error)
+	AddDuplicateKey(ctx context.Context, key string, source database.TransactionSource) error
+	DeleteDuplicateKeys(ctx context.Context, keys []string, source database.TransactionSource) error
+	GetMessages(ctx context.Context, source database.TransactionSource, ids []string) ([]*database.Message, error)
+	SaveCommitBatch(ctx context.Context, batch *database.CommitBatch) error
+	GetCommitBatches(ctx context.Context, source database.TransactionSource, limit int) ([]*database.CommitBatch, error)
+	SaveAccountMapping(ctx context.Context, mapping *database.AccountMapping) error
+	GetAccountMappings(ctx context.Context) ([]*database.AccountMapping, error)
+	DeleteAccountMapping(ctx context.Context, pattern string) error
+}
+
+// NewStorageFromEnv picks the backend from STORAGE_TYPE, falling back to defaultType when it is not set.
+func NewStorageFromEnv(defaultType string) (Storage, error) {
+	storageType := defaultType
+	if v, ok := os.LookupEnv("STORAGE_TYPE"); ok && v != "" {
+		storageType = v
+	}
+
+	switch storageType {
+	case StorageCosmo:
+		client, err := azcosmos.NewClientFromConnectionString(os.Getenv("COSMO_DB_CONNECTION_STRING"), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewCosmo(client, os.Getenv("COSMO_DB_NAME"))
+	case StoragePostgres:
+		return NewPostgres(os.Getenv("POSTGRES_CONNECTION_STRING"))
+	case StorageSqlite:
+		path := "importer.db"
+		if v, ok := os.LookupEnv("SQLITE_PATH"); ok && v != "" {
+			path = v
+		}
+
+		return NewSqlite(path)
+	case StorageMemory:
+		return NewMemory(), nil
+	default:
+		return nil, errors.Newf("unknown storage type %s", storageType)
+	}
+}
+
+// mergeDecision applies the non-empty fields of the update to the decision stored under the key, so presses of
+// different buttons of one transaction do not overwrite each other.
+func mergeDecision(
+	decisions map[string]*database.Decision,
+	key string,
+	update *database.Decision,
+) map[string]*database.Decision {
+	if decisions == nil {
+		decisions = map[string]*database.Decision{}
+	}
+
+	decision := lo.FromPtr(decisions[key])
+
+	if update.Action != database.DecisionActionNone {
+		decision.Action = update.Action
+	}
+
+	if update.CategoryName != "" {
+		decision.CategoryName = update.CategoryName
+	}
+
+	decision.UpdatedAt = update.UpdatedAt
+	decisions[key] = &decision
+
+	return decisions
+}
@@ -0,0 +1,148 @@
+package rules
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/common"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+)
+
+type Engine struct {
+	rules []*Rule
+}
+
+func NewEngine(rules []*Rule) (*Engine, error) {
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, errors.New("rule name is required")
+		}
+
+		var err error
+
+		if rule.Match.Description != "" {
+			if rule.descriptionRegex, err = regexp.Compile(rule.Match.Description); err != nil {
+				return nil, errors.Wrapf(err, "invalid description regex in rule %s", rule.Name)
+			}
+		}
+
+		if rule.Match.Account != "" {
+			if rule.accountRegex, err = regexp.Compile(rule.Match.Account); err != nil {
+				return nil, errors.Wrapf(err, "invalid account regex in rule %s", rule.Name)
+			}
+		}
+	}
+
+	return &Engine{
+		rules: rules,
+	}, nil
+}
+
+// LoadFile reads rules from a json file, any other extension is parsed as yaml.
+func LoadFile(path string) (*Engine, error) {
+	var file File
+
+	if err := common.UnmarshalFile(path, "rules", &file); err != nil {
+		return nil, err
+	}
+
+	return NewEngine(file.Rules)
+}
+
+func (e *Engine) Apply(
+	_ context.Context,
+	mappedTx []*firefly.MappedTransaction,
+) {
+	for _, tx := range mappedTx {
+		if tx.Transaction == nil || tx.Original == nil {
+			continue
+		}
+
+		for _, rule := range e.rules {
+			if !rule.matches(tx) {
+				continue
+			}
+
+			rule.apply(tx)
+
+			if rule.Stop {
+				break
+			}
+		}
+	}
+}
+
+func (r *Rule) matches(tx *firefly.MappedTransaction) bool {
+	original := tx.Original
+
+	if r.descriptionRegex != nil && !r.descriptionRegex.MatchString(original.Description) {
+		return false
+	}
+
+	if r.accountRegex != nil &&
+		!r.accountRegex.MatchString(original.SourceAccount) &&
+		!r.accountRegex.MatchString(original.DestinationAccount) {
+		return false
+	}
+
+	if len(r.Match.MCC) > 0 && !lo.Contains(r.Match.MCC, original.MCC) {
+		return false
+	}
+
+	if len(r.Match.Source) > 0 && !lo.Contains(r.Match.Source, original.TransactionSource) {
+		return false
+	}
+
+	if len(r.Match.Type) > 0 && !lo.Contains(r.Match.Type, tx.Transaction.Type) {
+		return false
+	}
+
+	amount := r.amount(original)
+
+	if r.Match.AmountMin != nil && amount.LessThan(*r.Match.AmountMin) {
+		return false
+	}
+
+	if r.Match.AmountMax != nil && amount.GreaterThan(*r.Match.AmountMax) {
+		return false
+	}
+
+	return true
+}
+
+func (r *Rule) amount(tx *database.Transaction) decimal.Decimal {
+	if !tx.SourceAmount.IsZero() {
+		return tx.SourceAmount.Abs()
+	}
+
+	return tx.DestinationAmount.Abs()
+}
+
+func (r *Rule) apply(tx *firefly.MappedTransaction) {
+	target := tx.Transaction
+
+	if r.Set.Category != "" {
+		target.CategoryName = r.Set.Category
+	}
+
+	if r.Set.Budget != "" {
+		target.BudgetName = r.Set.Budget
+	}
+
+	for _, tag := range r.Set.Tags {
+		if !lo.Contains(target.Tags, tag) {
+			target.Tags = append(target.Tags, tag)
+		}
+	}
+
+	if r.Set.DestinationName != "" && target.DestinationID == "" { // own accounts are never renamed
+		target.DestinationName = r.Set.DestinationName
+	}
+
+	tx.MatchedRules = append(tx.MatchedRules, r.Name)
+}
@@ -0,0 +1,120 @@
+package rules_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/firefly"
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/rules"
+)
+
+func TestApplyYaml(t *testing.T) {
+	engine, err := rules.LoadFile("testdata/rules.yaml")
+	assert.NoError(t, err)
+
+	groceries := &firefly.MappedTransaction{
+		Original: &database.Transaction{
+			Description:  "SILPO 123",
+			MCC:          "5411",
+			SourceAmount: decimal.NewFromInt(2000),
+		},
+		Transaction: &firefly.Transaction{
+			Type: "withdrawal",
+		},
+	}
+
+	bigExpense := &firefly.MappedTransaction{
+		Original: &database.Transaction{
+			Description:  "Rent",
+			SourceAmount: decimal.NewFromInt(-1500),
+		},
+		Transaction: &firefly.Transaction{
+			Type:          "withdrawal",
+			DestinationID: "10",
+		},
+	}
+
+	refund := &firefly.MappedTransaction{
+		Original: &database.Transaction{
+			TransactionSource:  database.Revolut,
+			Description:        "CARD_REFUND.Amazon",
+			DestinationAccount: "revolut_EUR",
+			DestinationAmount:  decimal.NewFromInt(20),
+		},
+		Transaction: &firefly.Transaction{
+			Type: "deposit",
+		},
+	}
+
+	failed := &firefly.MappedTransaction{
+		Original: &database.Transaction{
+			Description: "SILPO",
+			MCC:         "5411",
+		},
+	}
+
+	engine.Apply(context.TODO(), []*firefly.MappedTransaction{groceries, bigExpense, refund, failed})
+
+	assert.Equal(t, []string{"groceries"}, groceries.MatchedRules) // stop prevents big-expense
+	assert.Equal(t, "Groceries", groceries.Transaction.CategoryName)
+	assert.Equal(t, "Food", groceries.Transaction.BudgetName)
+	assert.Equal(t, []string{"food"}, groceries.Transaction.Tags)
+	assert.Equal(t, "Silpo", groceries.Transaction.DestinationName)
+
+	assert.Equal(t, []string{"big-expense"}, bigExpense.MatchedRules)
+	assert.Equal(t, []string{"big"}, bigExpense.Transaction.Tags)
+	assert.Empty(t, bigExpense.Transaction.CategoryName)
+
+	assert.Equal(t, []string{"revolut-income"}, refund.MatchedRules)
+	assert.Equal(t, "Refunds", refund.Transaction.CategoryName)
+
+	assert.Empty(t, failed.MatchedRules)
+}
+
+func TestApplyJson(t *testing.T) {
+	engine, err := rules.LoadFile("testdata/rules.json")
+	assert.NoError(t, err)
+
+	matched := &firefly.MappedTransaction{
+		Original: &database.Transaction{
+			Description:  "Netflix",
+			SourceAmount: decimal.NewFromFloat(15.49),
+		},
+		Transaction: &firefly.Transaction{},
+	}
+
+	tooExpensive := &firefly.MappedTransaction{
+		Original: &database.Transaction{
+			Description:  "Netflix",
+			SourceAmount: decimal.NewFromInt(60),
+		},
+		Transaction: &firefly.Transaction{},
+	}
+
+	engine.Apply(context.TODO(), []*firefly.MappedTransaction{matched, tooExpensive})
+
+	assert.Equal(t, []string{"subscriptions"}, matched.MatchedRules)
+	assert.Equal(t, "Subscriptions", matched.Transaction.CategoryName)
+	assert.Equal(t, []string{"monthly"}, matched.Transaction.Tags)
+
+	assert.Empty(t, tooExpensive.MatchedRules)
+}
+
+func TestInvalidRule(t *testing.T) {
+	_, err := rules.NewEngine([]*rules.Rule{
+		{
+			Name: "broken",
+			Match: rules.Match{
+				Description: "(",
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "broken")
+
+	_, err = rules.NewEngine([]*rules.Rule{{}})
+	assert.Error(t, err)
+}
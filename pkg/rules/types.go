@@ -0,0 +1,41 @@
+package rules
+
+import (
+	"regexp"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/skynet2/firefly-iii-privatbank-importer/pkg/database"
+)
+
+type File struct {
+	Rules []*Rule `json:"rules" yaml:"rules"`
+}
+
+type Rule struct {
+	Name  string  `json:"name" yaml:"name"`
+	Match Match   `json:"match" yaml:"match"`
+	Set   Actions `json:"set" yaml:"set"`
+	// Stop prevents the next rules from being applied to a matched transaction.
+	Stop bool `json:"stop" yaml:"stop"`
+
+	descriptionRegex *regexp.Regexp
+	accountRegex     *regexp.Regexp
+}
+
+type Match struct {
+	Description string                       `json:"description" yaml:"description"`
+	MCC         []string                     `json:"mcc" yaml:"mcc"`
+	AmountMin   *decimal.Decimal             `json:"amountMin" yaml:"amountMin"`
+	AmountMax   *decimal.Decimal             `json:"amountMax" yaml:"amountMax"`
+	Account     string                       `json:"account" yaml:"account"`
+	Source      []database.TransactionSource `json:"source" yaml:"source"`
+	Type        []string                     `json:"type" yaml:"type"`
+}
+
+type Actions struct {
+	Category        string   `json:"category" yaml:"category"`
+	Tags            []string `json:"tags" yaml:"tags"`
+	Budget          string   `json:"budget" yaml:"budget"`
+	DestinationName string   `json:"destinationName" yaml:"destinationName"`
+}